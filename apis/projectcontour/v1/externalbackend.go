@@ -0,0 +1,85 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExternalBackendSpec defines the spec of the CRD
+type ExternalBackendSpec struct {
+	// Endpoints are the addresses of the backend.
+	// +kubebuilder:validation:MinItems=1
+	Endpoints []ExternalBackendEndpoint `json:"endpoints"`
+	// Locality is the locality of the endpoints, which zone aware routing prefers
+	// the endpoints of when it's the locality of Envoy.
+	// +optional
+	Locality *Locality `json:"locality,omitempty"`
+	// HealthCheckPolicy defines HTTP health checks on the endpoints. The health
+	// check policy of a route referring to the backend takes precedence over it.
+	// +optional
+	HealthCheckPolicy *HTTPHealthCheckPolicy `json:"healthCheckPolicy,omitempty"`
+	// Protocol may be used to specify the protocol used to reach the endpoints.
+	// Values may be tls, h2, h2c. The protocol of an HTTPProxy service referring
+	// to the backend takes precedence over it.
+	// +kubebuilder:validation:Enum=h2;h2c;tls
+	// +optional
+	Protocol *string `json:"protocol,omitempty"`
+}
+
+// ExternalBackendEndpoint is the address of an endpoint of an ExternalBackend.
+type ExternalBackendEndpoint struct {
+	// Address is the IP address of the endpoint.
+	Address string `json:"address"`
+	// Port of the endpoint. An HTTPProxy service referring to the backend
+	// proxies traffic to the endpoints on its port.
+	//
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port"`
+}
+
+// Locality is the region and zone of a set of endpoints.
+type Locality struct {
+	// Region of the endpoints.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// Zone of the endpoints, within their region.
+	// +optional
+	Zone string `json:"zone,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ExternalBackend is a set of endpoints outside the cluster, such as
+// virtual machines or managed services, that HTTPProxy services can
+// refer to in place of a Kubernetes Service.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:scope=Namespaced,path=externalbackends,shortName=extbackend;extbackends,singular=externalbackend
+type ExternalBackend struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec ExternalBackendSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ExternalBackendList is a list of ExternalBackends.
+type ExternalBackendList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ExternalBackend `json:"items"`
+}
@@ -0,0 +1,66 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FQDNPolicySpec defines the spec of the CRD
+type FQDNPolicySpec struct {
+	// Rules map fully qualified domain names to the namespaces whose
+	// root HTTPProxies may use them.
+	// +kubebuilder:validation:MinItems=1
+	Rules []FQDNRule `json:"rules"`
+}
+
+// FQDNRule restricts the fully qualified domain names matching it to the
+// root HTTPProxies of a set of namespaces.
+type FQDNRule struct {
+	// Fqdn is a fully qualified domain name, or a pattern with a leading
+	// "*." label, such as *.team-a.example.com, which matches the names
+	// under its domain. The most specific rules matching a name apply.
+	// +kubebuilder:validation:MinLength=1
+	Fqdn string `json:"fqdn"`
+	// Namespaces are the namespaces whose root HTTPProxies may use the
+	// names matching the rule.
+	// +kubebuilder:validation:MinItems=1
+	Namespaces []string `json:"namespaces"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FQDNPolicy restricts which namespaces may hold the root HTTPProxies of
+// fully qualified domain names. A root HTTPProxy that uses a name that its
+// namespace isn't permitted to is invalid, and doesn't conflict with the
+// root HTTPProxies that are permitted to use it.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:scope=Cluster,path=fqdnpolicies,singular=fqdnpolicy
+type FQDNPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec FQDNPolicySpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FQDNPolicyList is a list of FQDNPolicies.
+type FQDNPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []FQDNPolicy `json:"items"`
+}
@@ -33,6 +33,12 @@ type HTTPProxySpec struct {
 	// Includes allow for specific routing configuration to be appended to another HTTPProxy in another namespace.
 	// +optional
 	Includes []Include `json:"includes,omitempty"`
+	// IncludeAdmissionPolicy restricts the HTTPProxies that may include this
+	// HTTPProxy. If not supplied, the include admission policy of the defaults
+	// of its namespace, or of the cluster defaults, applies, and if there is
+	// none, any HTTPProxy may include it.
+	// +optional
+	IncludeAdmissionPolicy *IncludeAdmissionPolicy `json:"includeAdmissionPolicy,omitempty"`
 }
 
 // Include describes a set of policies that can be applied to an HTTPProxy in a namespace.
@@ -45,6 +51,26 @@ type Include struct {
 	// Conditions are a set of routing properties that is applied to an HTTPProxy in a namespace.
 	// +optional
 	Conditions []Condition `json:"conditions,omitempty"`
+	// Weight defines the relative share of traffic sent to the routes of
+	// this include. Includes that set a weight may have identical
+	// conditions, and their routes with identical conditions split
+	// traffic according to the weights.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int64 `json:"weight,omitempty"`
+}
+
+// IncludeAdmissionPolicy lists the parents that may include an HTTPProxy.
+// HTTPProxies in the namespace of the HTTPProxy may always include it.
+type IncludeAdmissionPolicy struct {
+	// Namespaces are the namespaces of the HTTPProxies that may include it.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Fqdns are the fully qualified domain names of the root HTTPProxies
+	// that may include it, directly or through other includes.
+	// +optional
+	Fqdns []string `json:"fqdns,omitempty"`
 }
 
 // Condition are policies that are applied on top of HTTPProxies.
@@ -98,13 +124,190 @@ type HeaderCondition struct {
 // to be a "root".
 type VirtualHost struct {
 	// The fully qualified domain name of the root of the ingress tree
-	// all leaves of the DAG rooted at this object relate to the fqdn
+	// all leaves of the DAG rooted at this object relate to the fqdn.
+	// A wildcard fqdn such as "*.example.com" matches the subdomains
+	// of example.com that are not claimed by another HTTPProxy.
 	Fqdn string `json:"fqdn"`
+	// Aliases are additional domain names that share the routes and
+	// tls properties of the fqdn. Aliases may also be wildcards. When
+	// tls is enabled, the certificate must be valid for the fqdn and
+	// every alias.
+	// +optional
+	Aliases []string `json:"aliases,omitempty"`
 	// If present describes tls properties. The SNI names that will be matched on
 	// are described in fqdn, the tls.secretName secret must contain a
 	// matching certificate
 	// +optional
 	TLS *TLS `json:"tls,omitempty"`
+	// The policy for tracing requests to this virtual host. It applies
+	// to all routes of the virtual host that do not set their own.
+	// +optional
+	TracingPolicy *TracingPolicy `json:"tracingPolicy,omitempty"`
+	// IPAllowFilterPolicy is a list of address ranges that requests to
+	// this virtual host are allowed from. Requests from other addresses
+	// are denied. It applies to all routes of the virtual host that do
+	// not set an IP filter policy of their own.
+	// Cannot be combined with IPDenyFilterPolicy.
+	// +optional
+	IPAllowFilterPolicy []IPFilterPolicy `json:"ipAllowPolicy,omitempty"`
+	// IPDenyFilterPolicy is a list of address ranges that requests to
+	// this virtual host are denied from. It applies to all routes of
+	// the virtual host that do not set an IP filter policy of their own.
+	// Cannot be combined with IPAllowFilterPolicy.
+	// +optional
+	IPDenyFilterPolicy []IPFilterPolicy `json:"ipDenyPolicy,omitempty"`
+	// BasicAuth requires clients to authenticate with the credentials
+	// of an htpasswd Secret. It applies to all routes of the virtual
+	// host that do not set their own, except those listed in
+	// basicAuth.skipPrefixes.
+	// +optional
+	BasicAuth *BasicAuthPolicy `json:"basicAuth,omitempty"`
+	// JWTProviders are the providers the JWTs of requests to this
+	// virtual host are verified with. Routes choose a provider with
+	// their jwtVerificationPolicy.
+	// +optional
+	JWTProviders []JWTProvider `json:"jwtProviders,omitempty"`
+}
+
+// JWTProvider defines how JWTs issued by a single issuer are verified.
+type JWTProvider struct {
+	// Name is the unique name of the provider.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Issuer is the value the "iss" claim must have. If not
+	// supplied, the issuer is not verified.
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// Audiences are the values the "aud" claim may have. If not
+	// supplied, the audience is not verified.
+	// +optional
+	Audiences []string `json:"audiences,omitempty"`
+	// Default makes this provider verify the requests to routes
+	// that do not set a jwtVerificationPolicy. Only one provider
+	// may be the default.
+	// +optional
+	Default bool `json:"default,omitempty"`
+	// LocalJWKS reads the keys that verify JWTs from a Secret.
+	// One of LocalJWKS or RemoteJWKS must be supplied.
+	// +optional
+	LocalJWKS *LocalJWKS `json:"localJWKS,omitempty"`
+	// RemoteJWKS fetches the keys that verify JWTs from a Service.
+	// One of LocalJWKS or RemoteJWKS must be supplied.
+	// +optional
+	RemoteJWKS *RemoteJWKS `json:"remoteJWKS,omitempty"`
+	// ForwardJWT keeps the JWT in the requests forwarded to
+	// backends. By default, it is removed once it is verified.
+	// +optional
+	ForwardJWT bool `json:"forwardJWT,omitempty"`
+	// ClaimsToHeaders are the claims of verified JWTs that are
+	// forwarded to backends as request headers.
+	// +optional
+	ClaimsToHeaders []ClaimToHeader `json:"claimsToHeaders,omitempty"`
+}
+
+// LocalJWKS is a JSON Web Key Set held in a Secret.
+type LocalJWKS struct {
+	// SecretName is the name of a Secret in the current namespace.
+	// The "jwks" key of the Secret holds the JSON Web Key Set.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+// RemoteJWKS is a JSON Web Key Set fetched over HTTP from a Service.
+type RemoteJWKS struct {
+	// ServiceName is the name of a Service in the current namespace.
+	// +kubebuilder:validation:MinLength=1
+	ServiceName string `json:"serviceName"`
+	// ServicePort is the port of the Service.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	ServicePort int `json:"servicePort"`
+	// Path is the path the JSON Web Key Set is fetched from.
+	// Defaults to "/".
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Timeout is how long Envoy waits for the JSON Web Key Set
+	// to be fetched. Defaults to "1s".
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+	// CacheDuration is how long the fetched JSON Web Key Set is
+	// used before it is fetched again. Defaults to "5m".
+	// +optional
+	CacheDuration string `json:"cacheDuration,omitempty"`
+}
+
+// ClaimToHeader forwards a claim of a verified JWT as a request header.
+type ClaimToHeader struct {
+	// Claim is the name of a top level claim of the JWT.
+	// +kubebuilder:validation:MinLength=1
+	Claim string `json:"claim"`
+	// Header is the name of the request header the claim is
+	// forwarded in. Any value clients send is replaced.
+	// +kubebuilder:validation:MinLength=1
+	Header string `json:"header"`
+}
+
+// JWTVerificationPolicy defines how the JWTs of requests to a route are verified.
+type JWTVerificationPolicy struct {
+	// Require is the name of the provider that verifies the JWTs of
+	// requests to the route. Defaults to the default provider of the
+	// virtual host.
+	// +optional
+	Require string `json:"require,omitempty"`
+	// AllowMissing allows requests without a JWT. JWTs that are
+	// present must still be valid.
+	// +optional
+	AllowMissing bool `json:"allowMissing,omitempty"`
+	// Disabled turns off JWT verification for the route.
+	// Cannot be combined with Require or AllowMissing.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// BasicAuthPolicy requires HTTP Basic authentication with the
+// credentials held in an htpasswd Secret.
+type BasicAuthPolicy struct {
+	// SecretName is the name of a Secret in the current namespace.
+	// The "auth" key of the Secret holds the htpasswd data. Password
+	// hashes must be Apache MD5 ("$apr1$") or SHA1 ("{SHA}").
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+	// Realm is the protection space reported to clients that
+	// have not authenticated. Defaults to "Restricted".
+	// +optional
+	Realm string `json:"realm,omitempty"`
+	// SkipPrefixes are the path prefixes of the routes that do not
+	// require authentication. Only valid on a virtual host.
+	// +optional
+	SkipPrefixes []string `json:"skipPrefixes,omitempty"`
+}
+
+// IPFilterSource indicates which address of a request an
+// IPFilterPolicy is matched against.
+type IPFilterSource string
+
+const (
+	// IPFilterSourcePeer matches the address of the network
+	// peer. When the PROXY protocol is enabled, this is the
+	// client address the PROXY protocol header carries.
+	IPFilterSourcePeer IPFilterSource = "Peer"
+	// IPFilterSourceRemote matches the address of the client
+	// as derived from the X-Forwarded-For header, trusting the
+	// proxies Contour is configured to trust.
+	IPFilterSourceRemote IPFilterSource = "Remote"
+)
+
+// IPFilterPolicy defines an address range a request's
+// address is matched against.
+type IPFilterPolicy struct {
+	// Source is the address of the request to match, either "Peer"
+	// or "Remote". Defaults to "Peer".
+	// +optional
+	// +kubebuilder:validation:Enum=Peer;Remote
+	Source IPFilterSource `json:"source,omitempty"`
+	// CIDR is the IPv4 or IPv6 address range to match, such as
+	// "10.0.0.0/8". A bare address matches only that address.
+	CIDR string `json:"cidr"`
 }
 
 // TLS describes tls properties. The SNI names that will be matched on
@@ -135,6 +338,29 @@ type TLS struct {
 	// EnableFallbackCertificate defines if the vhost should allow a default certificate to
 	// be applied which handles all requests which don't match the SNI defined in this vhost.
 	EnableFallbackCertificate bool `json:"enableFallbackCertificate,omitempty"`
+
+	// HSTS adds a Strict-Transport-Security header to the responses
+	// of this vhost. The header is not added to responses served
+	// over plain HTTP.
+	// +optional
+	HSTS *HSTSPolicy `json:"hsts,omitempty"`
+}
+
+// HSTSPolicy defines the Strict-Transport-Security header sent
+// in responses from a TLS enabled vhost.
+type HSTSPolicy struct {
+	// MaxAge is how long browsers should only access the vhost
+	// over HTTPS, e.g. "8760h". It is sent in the header
+	// rounded down to whole seconds.
+	MaxAge string `json:"maxAge"`
+	// IncludeSubdomains applies the policy to all subdomains
+	// of the vhost.
+	// +optional
+	IncludeSubdomains bool `json:"includeSubdomains,omitempty"`
+	// Preload requests the vhost be included in browser HSTS
+	// preload lists. It requires IncludeSubdomains.
+	// +optional
+	Preload bool `json:"preload,omitempty"`
 }
 
 // Route contains the set of routes for a virtual host.
@@ -176,6 +402,35 @@ type Route struct {
 	// The policy for managing response headers during proxying
 	// +optional
 	ResponseHeadersPolicy *HeadersPolicy `json:"responseHeadersPolicy,omitempty"`
+	// The policy for tracing requests to this route.
+	// +optional
+	TracingPolicy *TracingPolicy `json:"tracingPolicy,omitempty"`
+	// The policy for assigning clients of this route to canary services.
+	// +optional
+	CanaryPolicy *CanaryPolicy `json:"canaryPolicy,omitempty"`
+	// The policy for injecting faults into requests to this route.
+	// +optional
+	FaultInjectionPolicy *FaultInjectionPolicy `json:"faultInjectionPolicy,omitempty"`
+	// IPAllowFilterPolicy is a list of address ranges that requests to
+	// this route are allowed from. Requests from other addresses are
+	// denied. It replaces the IP filter policy of the virtual host.
+	// Cannot be combined with IPDenyFilterPolicy.
+	// +optional
+	IPAllowFilterPolicy []IPFilterPolicy `json:"ipAllowPolicy,omitempty"`
+	// IPDenyFilterPolicy is a list of address ranges that requests to
+	// this route are denied from. It replaces the IP filter policy of
+	// the virtual host.
+	// Cannot be combined with IPAllowFilterPolicy.
+	// +optional
+	IPDenyFilterPolicy []IPFilterPolicy `json:"ipDenyPolicy,omitempty"`
+	// BasicAuth requires clients to authenticate with the credentials
+	// of an htpasswd Secret. It replaces the basic authentication
+	// policy of the virtual host.
+	// +optional
+	BasicAuth *BasicAuthPolicy `json:"basicAuth,omitempty"`
+	// The policy for verifying the JWTs of requests to this route.
+	// +optional
+	JWTVerificationPolicy *JWTVerificationPolicy `json:"jwtVerificationPolicy,omitempty"`
 }
 
 func (r *Route) GetPrefixReplacements() []ReplacePrefix {
@@ -185,6 +440,13 @@ func (r *Route) GetPrefixReplacements() []ReplacePrefix {
 	return nil
 }
 
+func (r *Route) GetRegexRewrite() *RegexRewrite {
+	if r.PathRewritePolicy != nil {
+		return r.PathRewritePolicy.RegexRewrite
+	}
+	return nil
+}
+
 // TCPProxy contains the set of services to proxy TCP connections.
 type TCPProxy struct {
 	// The load balancing policy for the backend services.
@@ -206,6 +468,13 @@ type TCPProxy struct {
 	// The health check policy for this tcp proxy
 	// +optional
 	HealthCheckPolicy *TCPHealthCheckPolicy `json:"healthCheckPolicy,omitempty"`
+	// Port claims one of the plain TCP listener ports Contour is configured
+	// to serve for an HTTPProxy without a virtual host. Connections to the
+	// port are proxied to the services without TLS.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port,omitempty"`
 }
 
 // TCPProxyInclude describes a target HTTPProxy document which contains the TCPProxy details.
@@ -222,6 +491,11 @@ type Service struct {
 	// Name is the name of Kubernetes service to proxy traffic.
 	// Names defined here will be used to look up corresponding endpoints which contain the ips to route.
 	Name string `json:"name"`
+	// Kind is the kind of the object that Name refers to; either Service, the default,
+	// or ExternalBackend.
+	// +kubebuilder:validation:Enum=Service;ExternalBackend
+	// +optional
+	Kind string `json:"kind,omitempty"`
 	// Port (defined as Integer) to proxy traffic to since a service can have multiple defined.
 	//
 	// +required
@@ -230,8 +504,9 @@ type Service struct {
 	// +kubebuilder:validation:ExclusiveMinimum=false
 	// +kubebuilder:validation:ExclusiveMaximum=true
 	Port int `json:"port"`
-	// Protocol may be used to specify (or override) the protocol used to reach this Service.
-	// Values may be tls, h2, h2c. If omitted, protocol-selection falls back on Service annotations.
+	// Protocol may be used to specify the protocol used to reach this Service.
+	// Values may be tls, h2, h2c. The upstream-protocol annotations of the Service take
+	// precedence over it, and it takes precedence over the appProtocol of the Service port.
 	// +kubebuilder:validation:Enum=h2;h2c;tls
 	// +optional
 	Protocol *string `json:"protocol,omitempty"`
@@ -312,6 +587,117 @@ type TimeoutPolicy struct {
 	// stream_idle_timeout default of 5m still applies.
 	// +optional
 	Idle string `json:"idle,omitempty"`
+
+	// Timeout after which, if there is no activity on a websocket connection for this route,
+	// the connection will be closed. Only applies when websockets are enabled on the route.
+	// If not specified, the Contour-wide websocket idle timeout (if configured) applies,
+	// otherwise the connection manager-wide stream_idle_timeout default of 5m applies.
+	// +optional
+	WebsocketIdle string `json:"websocketIdle,omitempty"`
+
+	// Maximum duration of a stream to the backend services of this route,
+	// after which the stream is reset regardless of activity.
+	// If not specified, there is no maximum stream duration.
+	// +optional
+	MaxStreamDuration string `json:"maxStreamDuration,omitempty"`
+
+	// Upper bound on the timeout that a gRPC client may request via the grpc-timeout
+	// header. When set, Envoy honours the grpc-timeout header up to this value,
+	// and "infinity" allows any requested timeout. If not specified, the grpc-timeout
+	// header is ignored and the response timeout applies.
+	// +optional
+	MaxGrpcTimeout string `json:"maxGrpcTimeout,omitempty"`
+}
+
+// TracingPolicy defines the tracing attributes of a virtual host or route.
+type TracingPolicy struct {
+	// Sampling is the percentage of requests that will be traced,
+	// from "0" to "100", overriding the sampling percentage
+	// configured for Contour. Fractional percentages such as "0.5"
+	// are permitted.
+	// +kubebuilder:validation:Pattern=`^(100(\.0+)?|[0-9]{1,2}(\.[0-9]+)?)$`
+	Sampling string `json:"sampling"`
+}
+
+// CanaryPolicy assigns a percentage of the clients of a route to canary
+// services. The assignment is kept in a cookie, so clients stay with the
+// services they were first assigned to.
+type CanaryPolicy struct {
+	// Services are the canary services to proxy traffic.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:Required
+	Services []Service `json:"services"`
+	// Percentage of new clients assigned to the canary services.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percentage int64 `json:"percentage,omitempty"`
+	// CookieName is the name of the assignment cookie.
+	// Defaults to "contour-canary".
+	// +optional
+	CookieName string `json:"cookieName,omitempty"`
+	// CookieMaxAge is how long the assignment cookie is kept by clients.
+	// If not supplied, the cookie lasts until the client's session ends.
+	// +optional
+	CookieMaxAge string `json:"cookieMaxAge,omitempty"`
+	// Header is the name of the request header that overrides the
+	// assignment. A value of "always" routes the request to the
+	// canary services, and a value of "never" to the route's services.
+	// Defaults to "X-Canary".
+	// +optional
+	Header string `json:"header,omitempty"`
+}
+
+// FaultInjectionPolicy defines the faults injected into requests to a
+// route, for testing how clients and services cope with failures.
+type FaultInjectionPolicy struct {
+	// Delay delays requests before they are forwarded.
+	// +optional
+	Delay *FaultDelay `json:"delay,omitempty"`
+	// Abort responds to requests with an error status
+	// instead of forwarding them.
+	// +optional
+	Abort *FaultAbort `json:"abort,omitempty"`
+	// Headers limit the faults to requests that match all
+	// of the header conditions.
+	// +optional
+	Headers []HeaderCondition `json:"headers,omitempty"`
+}
+
+// FaultDelay defines a fixed delay injected into requests.
+type FaultDelay struct {
+	// Duration is the length of the delay, for example "500ms".
+	Duration string `json:"duration"`
+	// Percentage is the percentage of requests that will be
+	// delayed, from "0" to "100". Fractional percentages such
+	// as "0.5" are permitted. Defaults to "100".
+	// +optional
+	// +kubebuilder:validation:Pattern=`^(100(\.0+)?|[0-9]{1,2}(\.[0-9]+)?)$`
+	Percentage string `json:"percentage,omitempty"`
+}
+
+// FaultAbort defines the error status returned to aborted requests.
+// Exactly one of HTTPStatus or GRPCStatus must be provided.
+type FaultAbort struct {
+	// HTTPStatus is the HTTP status code of the response.
+	// +optional
+	// +kubebuilder:validation:Minimum=200
+	// +kubebuilder:validation:Maximum=599
+	HTTPStatus int64 `json:"httpStatus,omitempty"`
+	// GRPCStatus is the gRPC status code of the response to
+	// gRPC requests. Envoy derives the gRPC status from the
+	// HTTP status of the response, so only the codes 2
+	// (UNKNOWN), 7 (PERMISSION_DENIED), 12 (UNIMPLEMENTED),
+	// 13 (INTERNAL), 14 (UNAVAILABLE) and 16 (UNAUTHENTICATED)
+	// are supported.
+	// +optional
+	GRPCStatus int64 `json:"grpcStatus,omitempty"`
+	// Percentage is the percentage of requests that will be
+	// aborted, from "0" to "100". Fractional percentages such
+	// as "0.5" are permitted. Defaults to "100".
+	// +optional
+	// +kubebuilder:validation:Pattern=`^(100(\.0+)?|[0-9]{1,2}(\.[0-9]+)?)$`
+	Percentage string `json:"percentage,omitempty"`
 }
 
 // RetryPolicy defines the attributes associated with retrying policy.
@@ -362,6 +748,29 @@ type PathRewritePolicy struct {
 	// ReplacePrefix describes how the path prefix should be replaced.
 	// +optional
 	ReplacePrefix []ReplacePrefix `json:"replacePrefix,omitempty"`
+
+	// RegexRewrite describes how the path should be rewritten
+	// using a regular expression.
+	// +optional
+	RegexRewrite *RegexRewrite `json:"regexRewrite,omitempty"`
+}
+
+// RegexRewrite describes a regular expression path rewrite.
+type RegexRewrite struct {
+	// Pattern is the regular expression, in RE2 syntax, that is
+	// matched against the request path. Every portion of the path
+	// that matches Pattern is replaced by Substitution.
+	//
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Pattern string `json:"pattern"`
+
+	// Substitution is the string that matching portions of the
+	// path are replaced with. Capture groups from Pattern may be
+	// referenced using the \1 (etc) syntax.
+	//
+	// +kubebuilder:validation:Required
+	Substitution string `json:"substitution"`
 }
 
 // LoadBalancerPolicy defines the load balancing policy.
@@ -395,7 +804,11 @@ type HeaderValue struct {
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MinLength=1
 	Name string `json:"name"`
-	// Value represents the value of a header specified by a key
+	// Value represents the value of a header specified by a key.
+	// The value may contain a limited set of Envoy request and connection
+	// variables, such as %DOWNSTREAM_REMOTE_ADDRESS% or %REQ(X-Foo)%,
+	// which are expanded when the request is proxied. Any other '%'
+	// characters are treated literally.
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MinLength=1
 	Value string `json:"value"`
@@ -0,0 +1,94 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProxyDefaultsSpec defines the spec of the CRD
+type ProxyDefaultsSpec struct {
+	// TimeoutPolicy is the default timeout policy of routes. The timeouts
+	// that the timeout policy of a route doesn't set are taken from it.
+	// +optional
+	TimeoutPolicy *TimeoutPolicy `json:"timeoutPolicy,omitempty"`
+	// RetryPolicy is the default retry policy of routes without one.
+	// +optional
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+	// RequestHeadersPolicy is the default policy for managing the request
+	// headers of routes. The headers that it sets or removes are added to
+	// those of the policy of a route, unless the route sets or removes them.
+	// +optional
+	RequestHeadersPolicy *HeadersPolicy `json:"requestHeadersPolicy,omitempty"`
+	// ResponseHeadersPolicy is the default policy for managing the response
+	// headers of routes. The headers that it sets or removes are added to
+	// those of the policy of a route, unless the route sets or removes them.
+	// +optional
+	ResponseHeadersPolicy *HeadersPolicy `json:"responseHeadersPolicy,omitempty"`
+	// LoadBalancerPolicy is the default load balancing policy of routes without one.
+	// +optional
+	LoadBalancerPolicy *LoadBalancerPolicy `json:"loadBalancerPolicy,omitempty"`
+	// IncludeAdmissionPolicy is the default include admission policy of
+	// HTTPProxies without one.
+	// +optional
+	IncludeAdmissionPolicy *IncludeAdmissionPolicy `json:"includeAdmissionPolicy,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ProxyDefaults holds the default policies of the routes of the HTTPProxies
+// in its namespace. If a namespace has more than one, the first by name applies.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:scope=Namespaced,path=proxydefaults,singular=proxydefault
+type ProxyDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec ProxyDefaultsSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ProxyDefaultsList is a list of ProxyDefaults.
+type ProxyDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ProxyDefaults `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterProxyDefaults holds the default policies of the routes of all
+// HTTPProxies. The policies of the ProxyDefaults of a namespace take
+// precedence over it. If there is more than one, the first by name applies.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:scope=Cluster,path=clusterproxydefaults,singular=clusterproxydefault
+type ClusterProxyDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec ProxyDefaultsSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterProxyDefaultsList is a list of ClusterProxyDefaults.
+type ClusterProxyDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ClusterProxyDefaults `json:"items"`
+}
@@ -29,6 +29,10 @@ var SchemeGroupVersion = GroupVersion
 
 var HTTPProxyGVR = GroupVersion.WithResource("httpproxies")
 var TLSCertificateDelegationGVR = GroupVersion.WithResource("tlscertificatedelegations")
+var ExternalBackendGVR = GroupVersion.WithResource("externalbackends")
+var ProxyDefaultsGVR = GroupVersion.WithResource("proxydefaults")
+var ClusterProxyDefaultsGVR = GroupVersion.WithResource("clusterproxydefaults")
+var FQDNPolicyGVR = GroupVersion.WithResource("fqdnpolicies")
 
 // Resource gets an Contour GroupResource for a specified resource
 func Resource(resource string) schema.GroupResource {
@@ -45,6 +49,14 @@ func AddKnownTypes(scheme *runtime.Scheme) error {
 		&HTTPProxyList{},
 		&TLSCertificateDelegation{},
 		&TLSCertificateDelegationList{},
+		&ExternalBackend{},
+		&ExternalBackendList{},
+		&ProxyDefaults{},
+		&ProxyDefaultsList{},
+		&ClusterProxyDefaults{},
+		&ClusterProxyDefaultsList{},
+		&FQDNPolicy{},
+		&FQDNPolicyList{},
 	)
 	metav1.AddToGroupVersion(scheme, GroupVersion)
 	return nil
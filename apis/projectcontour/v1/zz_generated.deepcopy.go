@@ -24,6 +24,48 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BasicAuthPolicy) DeepCopyInto(out *BasicAuthPolicy) {
+	*out = *in
+	if in.SkipPrefixes != nil {
+		in, out := &in.SkipPrefixes, &out.SkipPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BasicAuthPolicy.
+func (in *BasicAuthPolicy) DeepCopy() *BasicAuthPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(BasicAuthPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryPolicy) DeepCopyInto(out *CanaryPolicy) {
+	*out = *in
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]Service, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryPolicy.
+func (in *CanaryPolicy) DeepCopy() *CanaryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CertificateDelegation) DeepCopyInto(out *CertificateDelegation) {
 	*out = *in
@@ -34,47 +76,403 @@ func (in *CertificateDelegation) DeepCopyInto(out *CertificateDelegation) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateDelegation.
-func (in *CertificateDelegation) DeepCopy() *CertificateDelegation {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateDelegation.
+func (in *CertificateDelegation) DeepCopy() *CertificateDelegation {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateDelegation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClaimToHeader) DeepCopyInto(out *ClaimToHeader) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClaimToHeader.
+func (in *ClaimToHeader) DeepCopy() *ClaimToHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(ClaimToHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterProxyDefaults) DeepCopyInto(out *ClusterProxyDefaults) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterProxyDefaults.
+func (in *ClusterProxyDefaults) DeepCopy() *ClusterProxyDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterProxyDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterProxyDefaults) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterProxyDefaultsList) DeepCopyInto(out *ClusterProxyDefaultsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterProxyDefaults, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterProxyDefaultsList.
+func (in *ClusterProxyDefaultsList) DeepCopy() *ClusterProxyDefaultsList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterProxyDefaultsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterProxyDefaultsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	if in.Header != nil {
+		in, out := &in.Header, &out.Header
+		*out = new(HeaderCondition)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
+	if in == nil {
+		return nil
+	}
+	out := new(Condition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DownstreamValidation) DeepCopyInto(out *DownstreamValidation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownstreamValidation.
+func (in *DownstreamValidation) DeepCopy() *DownstreamValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(DownstreamValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalBackend) DeepCopyInto(out *ExternalBackend) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalBackend.
+func (in *ExternalBackend) DeepCopy() *ExternalBackend {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalBackend)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExternalBackend) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalBackendEndpoint) DeepCopyInto(out *ExternalBackendEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalBackendEndpoint.
+func (in *ExternalBackendEndpoint) DeepCopy() *ExternalBackendEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalBackendEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalBackendList) DeepCopyInto(out *ExternalBackendList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ExternalBackend, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalBackendList.
+func (in *ExternalBackendList) DeepCopy() *ExternalBackendList {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalBackendList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExternalBackendList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalBackendSpec) DeepCopyInto(out *ExternalBackendSpec) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]ExternalBackendEndpoint, len(*in))
+		copy(*out, *in)
+	}
+	if in.Locality != nil {
+		in, out := &in.Locality, &out.Locality
+		*out = new(Locality)
+		**out = **in
+	}
+	if in.HealthCheckPolicy != nil {
+		in, out := &in.HealthCheckPolicy, &out.HealthCheckPolicy
+		*out = new(HTTPHealthCheckPolicy)
+		**out = **in
+	}
+	if in.Protocol != nil {
+		in, out := &in.Protocol, &out.Protocol
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalBackendSpec.
+func (in *ExternalBackendSpec) DeepCopy() *ExternalBackendSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalBackendSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FQDNPolicy) DeepCopyInto(out *FQDNPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FQDNPolicy.
+func (in *FQDNPolicy) DeepCopy() *FQDNPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(FQDNPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FQDNPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FQDNPolicyList) DeepCopyInto(out *FQDNPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FQDNPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FQDNPolicyList.
+func (in *FQDNPolicyList) DeepCopy() *FQDNPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(FQDNPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FQDNPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FQDNPolicySpec) DeepCopyInto(out *FQDNPolicySpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]FQDNRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FQDNPolicySpec.
+func (in *FQDNPolicySpec) DeepCopy() *FQDNPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FQDNPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FQDNRule) DeepCopyInto(out *FQDNRule) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FQDNRule.
+func (in *FQDNRule) DeepCopy() *FQDNRule {
+	if in == nil {
+		return nil
+	}
+	out := new(FQDNRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FaultAbort) DeepCopyInto(out *FaultAbort) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FaultAbort.
+func (in *FaultAbort) DeepCopy() *FaultAbort {
+	if in == nil {
+		return nil
+	}
+	out := new(FaultAbort)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FaultDelay) DeepCopyInto(out *FaultDelay) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FaultDelay.
+func (in *FaultDelay) DeepCopy() *FaultDelay {
 	if in == nil {
 		return nil
 	}
-	out := new(CertificateDelegation)
+	out := new(FaultDelay)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Condition) DeepCopyInto(out *Condition) {
+func (in *FaultInjectionPolicy) DeepCopyInto(out *FaultInjectionPolicy) {
 	*out = *in
-	if in.Header != nil {
-		in, out := &in.Header, &out.Header
-		*out = new(HeaderCondition)
+	if in.Delay != nil {
+		in, out := &in.Delay, &out.Delay
+		*out = new(FaultDelay)
+		**out = **in
+	}
+	if in.Abort != nil {
+		in, out := &in.Abort, &out.Abort
+		*out = new(FaultAbort)
 		**out = **in
 	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]HeaderCondition, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
-func (in *Condition) DeepCopy() *Condition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FaultInjectionPolicy.
+func (in *FaultInjectionPolicy) DeepCopy() *FaultInjectionPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(Condition)
+	out := new(FaultInjectionPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DownstreamValidation) DeepCopyInto(out *DownstreamValidation) {
+func (in *HSTSPolicy) DeepCopyInto(out *HSTSPolicy) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownstreamValidation.
-func (in *DownstreamValidation) DeepCopy() *DownstreamValidation {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HSTSPolicy.
+func (in *HSTSPolicy) DeepCopy() *HSTSPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(DownstreamValidation)
+	out := new(HSTSPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -180,6 +578,11 @@ func (in *HTTPProxySpec) DeepCopyInto(out *HTTPProxySpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.IncludeAdmissionPolicy != nil {
+		in, out := &in.IncludeAdmissionPolicy, &out.IncludeAdmissionPolicy
+		*out = new(IncludeAdmissionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPProxySpec.
@@ -247,6 +650,21 @@ func (in *HeadersPolicy) DeepCopy() *HeadersPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPFilterPolicy) DeepCopyInto(out *IPFilterPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPFilterPolicy.
+func (in *IPFilterPolicy) DeepCopy() *IPFilterPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(IPFilterPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Include) DeepCopyInto(out *Include) {
 	*out = *in
@@ -269,6 +687,81 @@ func (in *Include) DeepCopy() *Include {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IncludeAdmissionPolicy) DeepCopyInto(out *IncludeAdmissionPolicy) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Fqdns != nil {
+		in, out := &in.Fqdns, &out.Fqdns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IncludeAdmissionPolicy.
+func (in *IncludeAdmissionPolicy) DeepCopy() *IncludeAdmissionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(IncludeAdmissionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTProvider) DeepCopyInto(out *JWTProvider) {
+	*out = *in
+	if in.Audiences != nil {
+		in, out := &in.Audiences, &out.Audiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LocalJWKS != nil {
+		in, out := &in.LocalJWKS, &out.LocalJWKS
+		*out = new(LocalJWKS)
+		**out = **in
+	}
+	if in.RemoteJWKS != nil {
+		in, out := &in.RemoteJWKS, &out.RemoteJWKS
+		*out = new(RemoteJWKS)
+		**out = **in
+	}
+	if in.ClaimsToHeaders != nil {
+		in, out := &in.ClaimsToHeaders, &out.ClaimsToHeaders
+		*out = make([]ClaimToHeader, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTProvider.
+func (in *JWTProvider) DeepCopy() *JWTProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTVerificationPolicy) DeepCopyInto(out *JWTVerificationPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTVerificationPolicy.
+func (in *JWTVerificationPolicy) DeepCopy() *JWTVerificationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTVerificationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LoadBalancerPolicy) DeepCopyInto(out *LoadBalancerPolicy) {
 	*out = *in
@@ -284,6 +777,36 @@ func (in *LoadBalancerPolicy) DeepCopy() *LoadBalancerPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalJWKS) DeepCopyInto(out *LocalJWKS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalJWKS.
+func (in *LocalJWKS) DeepCopy() *LocalJWKS {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalJWKS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Locality) DeepCopyInto(out *Locality) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Locality.
+func (in *Locality) DeepCopy() *Locality {
+	if in == nil {
+		return nil
+	}
+	out := new(Locality)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PathRewritePolicy) DeepCopyInto(out *PathRewritePolicy) {
 	*out = *in
@@ -292,6 +815,11 @@ func (in *PathRewritePolicy) DeepCopyInto(out *PathRewritePolicy) {
 		*out = make([]ReplacePrefix, len(*in))
 		copy(*out, *in)
 	}
+	if in.RegexRewrite != nil {
+		in, out := &in.RegexRewrite, &out.RegexRewrite
+		*out = new(RegexRewrite)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PathRewritePolicy.
@@ -304,6 +832,139 @@ func (in *PathRewritePolicy) DeepCopy() *PathRewritePolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyDefaults) DeepCopyInto(out *ProxyDefaults) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyDefaults.
+func (in *ProxyDefaults) DeepCopy() *ProxyDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxyDefaults) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyDefaultsList) DeepCopyInto(out *ProxyDefaultsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProxyDefaults, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyDefaultsList.
+func (in *ProxyDefaultsList) DeepCopy() *ProxyDefaultsList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyDefaultsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxyDefaultsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyDefaultsSpec) DeepCopyInto(out *ProxyDefaultsSpec) {
+	*out = *in
+	if in.TimeoutPolicy != nil {
+		in, out := &in.TimeoutPolicy, &out.TimeoutPolicy
+		*out = new(TimeoutPolicy)
+		**out = **in
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(RetryPolicy)
+		**out = **in
+	}
+	if in.RequestHeadersPolicy != nil {
+		in, out := &in.RequestHeadersPolicy, &out.RequestHeadersPolicy
+		*out = new(HeadersPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResponseHeadersPolicy != nil {
+		in, out := &in.ResponseHeadersPolicy, &out.ResponseHeadersPolicy
+		*out = new(HeadersPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LoadBalancerPolicy != nil {
+		in, out := &in.LoadBalancerPolicy, &out.LoadBalancerPolicy
+		*out = new(LoadBalancerPolicy)
+		**out = **in
+	}
+	if in.IncludeAdmissionPolicy != nil {
+		in, out := &in.IncludeAdmissionPolicy, &out.IncludeAdmissionPolicy
+		*out = new(IncludeAdmissionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyDefaultsSpec.
+func (in *ProxyDefaultsSpec) DeepCopy() *ProxyDefaultsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyDefaultsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegexRewrite) DeepCopyInto(out *RegexRewrite) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegexRewrite.
+func (in *RegexRewrite) DeepCopy() *RegexRewrite {
+	if in == nil {
+		return nil
+	}
+	out := new(RegexRewrite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteJWKS) DeepCopyInto(out *RemoteJWKS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteJWKS.
+func (in *RemoteJWKS) DeepCopy() *RemoteJWKS {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteJWKS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReplacePrefix) DeepCopyInto(out *ReplacePrefix) {
 	*out = *in
@@ -386,6 +1047,41 @@ func (in *Route) DeepCopyInto(out *Route) {
 		*out = new(HeadersPolicy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TracingPolicy != nil {
+		in, out := &in.TracingPolicy, &out.TracingPolicy
+		*out = new(TracingPolicy)
+		**out = **in
+	}
+	if in.CanaryPolicy != nil {
+		in, out := &in.CanaryPolicy, &out.CanaryPolicy
+		*out = new(CanaryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FaultInjectionPolicy != nil {
+		in, out := &in.FaultInjectionPolicy, &out.FaultInjectionPolicy
+		*out = new(FaultInjectionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IPAllowFilterPolicy != nil {
+		in, out := &in.IPAllowFilterPolicy, &out.IPAllowFilterPolicy
+		*out = make([]IPFilterPolicy, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPDenyFilterPolicy != nil {
+		in, out := &in.IPDenyFilterPolicy, &out.IPDenyFilterPolicy
+		*out = make([]IPFilterPolicy, len(*in))
+		copy(*out, *in)
+	}
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(BasicAuthPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JWTVerificationPolicy != nil {
+		in, out := &in.JWTVerificationPolicy, &out.JWTVerificationPolicy
+		*out = new(JWTVerificationPolicy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route.
@@ -529,6 +1225,11 @@ func (in *TLS) DeepCopyInto(out *TLS) {
 		*out = new(DownstreamValidation)
 		**out = **in
 	}
+	if in.HSTS != nil {
+		in, out := &in.HSTS, &out.HSTS
+		*out = new(HSTSPolicy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLS.
@@ -636,6 +1337,21 @@ func (in *TimeoutPolicy) DeepCopy() *TimeoutPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracingPolicy) DeepCopyInto(out *TracingPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingPolicy.
+func (in *TracingPolicy) DeepCopy() *TracingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TracingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpstreamValidation) DeepCopyInto(out *UpstreamValidation) {
 	*out = *in
@@ -654,11 +1370,43 @@ func (in *UpstreamValidation) DeepCopy() *UpstreamValidation {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualHost) DeepCopyInto(out *VirtualHost) {
 	*out = *in
+	if in.Aliases != nil {
+		in, out := &in.Aliases, &out.Aliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.TLS != nil {
 		in, out := &in.TLS, &out.TLS
 		*out = new(TLS)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TracingPolicy != nil {
+		in, out := &in.TracingPolicy, &out.TracingPolicy
+		*out = new(TracingPolicy)
+		**out = **in
+	}
+	if in.IPAllowFilterPolicy != nil {
+		in, out := &in.IPAllowFilterPolicy, &out.IPAllowFilterPolicy
+		*out = make([]IPFilterPolicy, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPDenyFilterPolicy != nil {
+		in, out := &in.IPDenyFilterPolicy, &out.IPDenyFilterPolicy
+		*out = make([]IPFilterPolicy, len(*in))
+		copy(*out, *in)
+	}
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(BasicAuthPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JWTProviders != nil {
+		in, out := &in.JWTProviders, &out.JWTProviders
+		*out = make([]JWTProvider, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualHost.
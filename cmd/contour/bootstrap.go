@@ -14,14 +14,33 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/projectcontour/contour/internal/envoy"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/yaml.v2"
 )
 
 // registerBootstrap registers the bootstrap subcommand and flags
 // with the Application provided.
 func registerBootstrap(app *kingpin.Application) (*kingpin.CmdClause, *envoy.BootstrapConfig) {
-	var config envoy.BootstrapConfig
+	var (
+		config     envoy.BootstrapConfig
+		configFile string
+	)
+
+	// parseConfig reads the tracing configuration from the
+	// Contour configuration file, so that the bootstrap and
+	// contour serve share the same trace collector.
+	parseConfig := func(_ *kingpin.ParseContext) error {
+		tracing, err := readTracingConfig(configFile)
+		if err != nil {
+			return err
+		}
+		config.Tracing = tracing
+		return nil
+	}
 
 	bootstrap := app.Command("bootstrap", "Generate bootstrap configuration.")
 	bootstrap.Arg("path", "Configuration file ('-' for standard output).").Required().StringVar(&config.Path)
@@ -34,5 +53,31 @@ func registerBootstrap(app *kingpin.Application) (*kingpin.CmdClause, *envoy.Boo
 	bootstrap.Flag("envoy-cert-file", "gRPC Client cert filename for Envoy to load.").Envar("ENVOY_CERT_FILE").StringVar(&config.GrpcClientCert)
 	bootstrap.Flag("envoy-key-file", "gRPC Client key filename for Envoy to load.").Envar("ENVOY_KEY_FILE").StringVar(&config.GrpcClientKey)
 	bootstrap.Flag("namespace", "The namespace the Envoy container will run in.").Envar("CONTOUR_NAMESPACE").Default("projectcontour").StringVar(&config.Namespace)
+	bootstrap.Flag("service-cluster", "The service cluster name Envoy reports, unless a tracing service-name is configured.").StringVar(&config.ServiceCluster)
+	bootstrap.Flag("region", "The region Envoy runs in.").StringVar(&config.Region)
+	bootstrap.Flag("zone", "The zone Envoy runs in, which enables zone aware routing.").StringVar(&config.Zone)
+	bootstrap.Flag("local-service", "The name/port of the Service that selects the Envoy pods, for zone aware routing.").Default("envoy/http").StringVar(&config.LocalService)
+	bootstrap.Flag("config-path", "Path to the Contour configuration file.").Action(parseConfig).ExistingFileVar(&configFile)
 	return bootstrap, &config
 }
+
+// readTracingConfig returns the tracing configuration from
+// the supplied Contour configuration file.
+func readTracingConfig(path string) (*envoy.TracingConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ctx serveContext
+	if err := yaml.NewDecoder(f).Decode(&ctx); err != nil {
+		return nil, err
+	}
+
+	tracing, err := ctx.Tracing.tracingConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracing configuration: %w", err)
+	}
+	return tracing, nil
+}
@@ -38,6 +38,7 @@ import (
 	"gopkg.in/alecthomas/kingpin.v2"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 // Add RBAC policy to support leader election.
@@ -119,6 +120,7 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 
 	serve.Flag("debug", "Enable debug logging.").Short('d').BoolVar(&ctx.Debug)
 	serve.Flag("experimental-service-apis", "Subscribe to the new service-apis types.").BoolVar(&ctx.UseExperimentalServiceAPITypes)
+	serve.Flag("use-endpoint-slices", "Discover service endpoints from EndpointSlices.").BoolVar(&ctx.UseEndpointSlices)
 	return serve, ctx
 }
 
@@ -145,6 +147,26 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		log.WithField("context", "fallback-certificate").Fatalf("invalid fallback certificate configuration: %q", err)
 	}
 
+	tracing, err := ctx.Tracing.tracingConfig()
+	if err != nil {
+		log.WithField("context", "tracing").Fatalf("invalid tracing configuration: %q", err)
+	}
+
+	securityHeaders, err := ctx.TLSConfig.SecurityHeaders.securityHeadersPolicy()
+	if err != nil {
+		log.WithField("context", "security-headers").Fatalf("invalid security headers configuration: %q", err)
+	}
+
+	clientAddress, err := ctx.ClientAddress.clientAddressPolicy()
+	if err != nil {
+		log.WithField("context", "client-address").Fatalf("invalid client address configuration: %q", err)
+	}
+
+	tcpPorts, err := ctx.tcpPorts()
+	if err != nil {
+		log.WithField("context", "tcp-ports").Fatalf("invalid TCP listener configuration: %q", err)
+	}
+
 	if rootNamespaces := ctx.proxyRootNamespaces(); len(rootNamespaces) > 0 {
 		// Add the FallbackCertificateNamespace to the root-namespaces if not already
 		if !contains(rootNamespaces, ctx.TLSConfig.FallbackCertificate.Namespace) && fallbackCert != nil {
@@ -187,6 +209,8 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		ConnectionIdleTimeout: ctx.ConnectionIdleTimeout,
 		StreamIdleTimeout:     ctx.StreamIdleTimeout,
 		MaxConnectionDuration: ctx.MaxConnectionDuration,
+		Tracing:               tracing,
+		ClientAddress:         clientAddress,
 	}
 
 	defaultHTTPVersions, err := parseDefaultHTTPVersions(ctx.DefaultHTTPVersions)
@@ -213,6 +237,11 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 				FieldLogger:    log.WithField("context", "KubernetesCache"),
 			},
 			DisablePermitInsecure: ctx.DisablePermitInsecure,
+			WebsocketIdleTimeout:  ctx.WebsocketIdleTimeout,
+			SecurityHeaders:       securityHeaders,
+			ClientAddress:         clientAddress,
+			TCPPorts:              tcpPorts,
+			ZoneAwareRouting:      ctx.ZoneAwareRouting.zoneAwareRoutingPolicy(),
 		},
 		FieldLogger: log.WithField("context", "contourEventHandler"),
 	}
@@ -256,21 +285,52 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		informerSyncList.InformOnResources(clusterInformerFactory, dynamicHandler, k8s.SecretsResources()...)
 	}
 
-	// step 5. endpoints updates are handled directly by the EndpointsTranslator
-	// due to their high update rate and their orthogonal nature.
-	et := &contour.EndpointsTranslator{
-		FieldLogger: log.WithField("context", "endpointstranslator"),
+	// step 5. endpoints updates are handled directly by the EndpointsTranslator,
+	// or the EndpointSliceTranslator, due to their high update rate and their
+	// orthogonal nature.
+	var et interface {
+		cgrpc.Resource
+		cache.ResourceEventHandler
+	}
+	if ctx.UseEndpointSlices {
+		et = &contour.EndpointSliceTranslator{
+			FieldLogger: log.WithField("context", "endpointslicetranslator"),
+		}
+	} else {
+		et = &contour.EndpointsTranslator{
+			FieldLogger: log.WithField("context", "endpointstranslator"),
+		}
+	}
+
+	endpointsHandler := &k8s.DynamicClientHandler{
+		Next: &contour.EventRecorder{
+			Next:    et,
+			Counter: eventHandler.Metrics.EventHandlerOperations,
+		},
+		Converter: converter,
+		Logger:    log.WithField("context", "endpointstranslator"),
 	}
 
-	informerSyncList.InformOnResources(clusterInformerFactory,
-		&k8s.DynamicClientHandler{
-			Next: &contour.EventRecorder{
-				Next:    et,
-				Counter: eventHandler.Metrics.EventHandlerOperations,
-			},
-			Converter: converter,
-			Logger:    log.WithField("context", "endpointstranslator"),
-		}, k8s.EndpointsResources()...)
+	if ctx.UseEndpointSlices {
+		// EndpointSlices carry the locality of each endpoint in its topology.
+		informerSyncList.InformOnResources(clusterInformerFactory, endpointsHandler, k8s.EndpointSliceResources()...)
+	} else {
+		informerSyncList.InformOnResources(clusterInformerFactory, endpointsHandler, k8s.EndpointsResources()...)
+
+		// Zone aware routing needs the locality of each endpoint,
+		// which is taken from the topology labels of its node.
+		if ctx.ZoneAwareRouting != nil {
+			informerSyncList.InformOnResources(clusterInformerFactory, endpointsHandler, k8s.NodesResources()...)
+		}
+	}
+
+	// The annotations of each Service control whether the not
+	// ready endpoints of the Service are published.
+	informerSyncList.InformOnResources(clusterInformerFactory, endpointsHandler, k8s.ServicesResources()...)
+
+	// The endpoints of ExternalBackends are published alongside
+	// those of Services.
+	informerSyncList.InformOnResources(clusterInformerFactory, endpointsHandler, k8s.ExternalBackendResources()...)
 
 	// step 6. setup workgroup runner and register informers.
 	var g workgroup.Group
@@ -20,13 +20,17 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
 	"github.com/projectcontour/contour/internal/k8s"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/projectcontour/contour/internal/contour"
 	"google.golang.org/grpc"
@@ -115,6 +119,21 @@ type serveContext struct {
 	// LeaderElectionConfig can be set in the config file.
 	LeaderElectionConfig `yaml:"leaderelection,omitempty"`
 
+	// Tracing holds the distributed tracing configuration.
+	Tracing *TracingConfig `yaml:"tracing,omitempty"`
+
+	// ClientAddress defines how Envoy derives the client
+	// address from the X-Forwarded-For header.
+	ClientAddress *ClientAddressConfig `yaml:"client-address,omitempty"`
+
+	// ZoneAwareRouting makes Envoy prefer the endpoints of
+	// services that are in its own zone.
+	ZoneAwareRouting *ZoneAwareRoutingConfig `yaml:"zone-aware-routing,omitempty"`
+
+	// TCPPorts are the ports of Envoy's plain TCP listeners,
+	// which HTTPProxies without a virtual host may claim.
+	TCPPorts []int `yaml:"tcp-ports,omitempty"`
+
 	// TimeoutConfig holds various configurable timeouts that can
 	// be set in the config file.
 	TimeoutConfig `yaml:"timeouts,omitempty"`
@@ -129,6 +148,10 @@ type serveContext struct {
 	// (GatewayClass, Gateway, HTTPRoute, TCPRoute, and any more as they are added)
 	UseExperimentalServiceAPITypes bool `yaml:"-"`
 
+	// UseEndpointSlices makes Contour discover the endpoints of services
+	// from their discovery.k8s.io EndpointSlices instead of their Endpoints.
+	UseEndpointSlices bool `yaml:"-"`
+
 	// envoy service details
 
 	// Namespace of the envoy service to inspect for Ingress status details.
@@ -199,6 +222,164 @@ type TLSConfig struct {
 	// FallbackCertificate defines the namespace/name of the Kubernetes secret to
 	// use as fallback when a non-SNI request is received.
 	FallbackCertificate FallbackCertificate `yaml:"fallback-certificate,omitempty"`
+
+	// SecurityHeaders defines the response headers added to
+	// the routes of every TLS enabled virtual host.
+	SecurityHeaders *SecurityHeadersConfig `yaml:"security-headers,omitempty"`
+}
+
+// SecurityHeadersConfig defines the response headers added to
+// the routes of every TLS enabled virtual host.
+type SecurityHeadersConfig struct {
+	// HSTS defines the Strict-Transport-Security header. A
+	// HTTPProxy's tls.hsts setting replaces it for its vhost.
+	HSTS *HSTSConfig `yaml:"hsts,omitempty"`
+
+	// Headers holds additional headers, such as X-Frame-Options.
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// HSTSConfig defines the Strict-Transport-Security header.
+type HSTSConfig struct {
+	MaxAge            time.Duration `yaml:"max-age"`
+	IncludeSubdomains bool          `yaml:"include-subdomains,omitempty"`
+	Preload           bool          `yaml:"preload,omitempty"`
+}
+
+// securityHeadersPolicy validates the security headers configuration
+// and returns its DAG equivalent, or nil if it is not configured.
+func (s *SecurityHeadersConfig) securityHeadersPolicy() (*dag.SecurityHeadersPolicy, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	policy := &dag.SecurityHeadersPolicy{}
+
+	if s.HSTS != nil {
+		if s.HSTS.MaxAge < 0 {
+			return nil, fmt.Errorf("invalid hsts max-age %v", s.HSTS.MaxAge)
+		}
+		if s.HSTS.Preload && !s.HSTS.IncludeSubdomains {
+			return nil, errors.New("hsts preload requires include-subdomains")
+		}
+		policy.HSTS = &dag.HSTSPolicy{
+			MaxAge:            s.HSTS.MaxAge,
+			IncludeSubdomains: s.HSTS.IncludeSubdomains,
+			Preload:           s.HSTS.Preload,
+		}
+	}
+
+	for name, value := range s.Headers {
+		key := http.CanonicalHeaderKey(name)
+		if msgs := validation.IsHTTPHeaderName(key); len(msgs) != 0 {
+			return nil, fmt.Errorf("invalid header %q: %v", name, msgs)
+		}
+		if key == "Strict-Transport-Security" {
+			return nil, fmt.Errorf("header %q must be configured with hsts", key)
+		}
+		if _, ok := policy.Set[key]; ok {
+			return nil, fmt.Errorf("duplicate header %q", key)
+		}
+		if policy.Set == nil {
+			policy.Set = make(map[string]string)
+		}
+		policy.Set[key] = value
+	}
+
+	return policy, nil
+}
+
+// ClientAddressConfig defines how Envoy derives the client address
+// from the X-Forwarded-For header.
+type ClientAddressConfig struct {
+	// NumTrustedHops is the number of proxies in front of Envoy,
+	// such as cloud load balancers, that append to X-Forwarded-For.
+	NumTrustedHops uint32 `yaml:"num-trusted-hops,omitempty"`
+
+	// TrustedCIDRs restricts the peers whose X-Forwarded-For
+	// headers are trusted. If empty, every peer is trusted.
+	TrustedCIDRs []string `yaml:"trusted-cidrs,omitempty"`
+
+	// SkipXFFAppend stops Envoy appending the peer address
+	// to X-Forwarded-For.
+	SkipXFFAppend bool `yaml:"skip-xff-append,omitempty"`
+}
+
+// maxTrustedHops bounds num-trusted-hops. IP filters match the
+// client's X-Forwarded-For entry with a regular expression that
+// repeats once per hop, and Envoy rejects regular expressions
+// that repeat more than 1000 times.
+const maxTrustedHops = 64
+
+// clientAddressPolicy validates the client address configuration
+// and returns its DAG equivalent, or nil if it is not configured.
+func (c *ClientAddressConfig) clientAddressPolicy() (*dag.ClientAddressPolicy, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	if c.NumTrustedHops > maxTrustedHops {
+		return nil, fmt.Errorf("num-trusted-hops must be at most %d", maxTrustedHops)
+	}
+
+	if len(c.TrustedCIDRs) > 0 && c.NumTrustedHops == 0 {
+		return nil, errors.New("trusted-cidrs requires num-trusted-hops")
+	}
+
+	policy := &dag.ClientAddressPolicy{
+		NumTrustedHops: c.NumTrustedHops,
+		SkipXFFAppend:  c.SkipXFFAppend,
+	}
+
+	for _, cidr := range c.TrustedCIDRs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted CIDR %q", cidr)
+		}
+		policy.TrustedCIDRs = append(policy.TrustedCIDRs, *ipnet)
+	}
+
+	return policy, nil
+}
+
+// ZoneAwareRoutingConfig defines how Envoy prefers the endpoints
+// of services that are in its own zone.
+type ZoneAwareRoutingConfig struct {
+	// MinClusterSize is the number of endpoints a service needs
+	// before Envoy prefers those in its own zone. Defaults to
+	// Envoy's default of 6.
+	MinClusterSize uint64 `yaml:"min-cluster-size,omitempty"`
+}
+
+// zoneAwareRoutingPolicy returns the DAG equivalent of the zone
+// aware routing configuration, or nil if it is not configured.
+func (z *ZoneAwareRoutingConfig) zoneAwareRoutingPolicy() *dag.ZoneAwareRoutingPolicy {
+	if z == nil {
+		return nil
+	}
+	return &dag.ZoneAwareRoutingPolicy{
+		MinClusterSize: z.MinClusterSize,
+	}
+}
+
+// tcpPorts validates the plain TCP listener ports, which may
+// not be shared with Envoy's other listeners.
+func (ctx *serveContext) tcpPorts() ([]int, error) {
+	reserved := map[int]string{
+		ctx.httpPort:  "http",
+		ctx.httpsPort: "https",
+		ctx.statsPort: "stats",
+	}
+	for _, port := range ctx.TCPPorts {
+		if port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %d", port)
+		}
+		if listener, ok := reserved[port]; ok {
+			return nil, fmt.Errorf("port %d is used by the %s listener", port, listener)
+		}
+		reserved[port] = "tcp"
+	}
+	return ctx.TCPPorts, nil
 }
 
 // FallbackCertificate defines the namespace/name of the Kubernetes secret to
@@ -255,6 +436,110 @@ type TimeoutConfig struct {
 	// has been established from the client to the proxy before it is closed by the proxy,
 	// regardless of whether there has been activity or not. Set to 0 for no max duration.
 	MaxConnectionDuration time.Duration `yaml:"max-connection-duration,omitempty"`
+
+	// WebsocketIdleTimeout defines how long the proxy should wait while there
+	// is no activity on a websocket connection before terminating it. It
+	// applies to websocket routes that do not set their own timeout. Set to
+	// 0 to fall back to the stream idle timeout.
+	WebsocketIdleTimeout time.Duration `yaml:"websocket-idle-timeout,omitempty"`
+}
+
+// TracingConfig holds the distributed tracing configuration.
+type TracingConfig struct {
+	// Collector is the Service that Envoy sends spans to.
+	Collector TracingCollector `yaml:"collector"`
+
+	// Protocol is the protocol used to send spans to the collector.
+	// Valid values: 'zipkin', 'zipkin-proto'. Defaults to 'zipkin'.
+	Protocol string `yaml:"protocol,omitempty"`
+
+	// Sampling is the percentage of requests to trace, from 0 to 100.
+	// Defaults to 100.
+	Sampling *float64 `yaml:"sampling,omitempty"`
+
+	// ServiceName is the name of the service reported in spans.
+	ServiceName string `yaml:"service-name,omitempty"`
+
+	// CustomTags are added to every span.
+	CustomTags []TracingCustomTag `yaml:"custom-tags,omitempty"`
+}
+
+// TracingCollector defines the namespace/name and port of the
+// Kubernetes Service that Envoy sends spans to.
+type TracingCollector struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+	Port      int    `yaml:"port"`
+}
+
+// TracingCustomTag defines a tag added to every span. Exactly one
+// of literal, request-header or environment must be set.
+type TracingCustomTag struct {
+	Name          string `yaml:"name"`
+	Literal       string `yaml:"literal,omitempty"`
+	RequestHeader string `yaml:"request-header,omitempty"`
+	Environment   string `yaml:"environment,omitempty"`
+}
+
+// tracingConfig validates the tracing configuration and returns
+// its Envoy equivalent, or nil if tracing is not configured.
+func (t *TracingConfig) tracingConfig() (*envoy.TracingConfig, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	if len(strings.TrimSpace(t.Collector.Name)) == 0 || len(strings.TrimSpace(t.Collector.Namespace)) == 0 {
+		return nil, errors.New("collector name and namespace must be defined")
+	}
+
+	if t.Collector.Port <= 0 || t.Collector.Port > 65535 {
+		return nil, fmt.Errorf("invalid collector port %d", t.Collector.Port)
+	}
+
+	if !envoy.ValidTracingProtocol(t.Protocol) {
+		return nil, fmt.Errorf("invalid protocol %q", t.Protocol)
+	}
+
+	sampling := 100.0
+	if t.Sampling != nil {
+		sampling = *t.Sampling
+	}
+	if sampling < 0 || sampling > 100 {
+		return nil, fmt.Errorf("invalid sampling percentage %v", sampling)
+	}
+
+	var tags []envoy.TracingCustomTag
+	for _, tag := range t.CustomTags {
+		if len(tag.Name) == 0 {
+			return nil, errors.New("custom tag name must be defined")
+		}
+
+		sources := 0
+		for _, s := range []string{tag.Literal, tag.RequestHeader, tag.Environment} {
+			if len(s) > 0 {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return nil, fmt.Errorf("custom tag %q must set exactly one of literal, request-header or environment", tag.Name)
+		}
+
+		tags = append(tags, envoy.TracingCustomTag{
+			Name:          tag.Name,
+			Literal:       tag.Literal,
+			RequestHeader: tag.RequestHeader,
+			Environment:   tag.Environment,
+		})
+	}
+
+	return &envoy.TracingConfig{
+		CollectorAddress: t.Collector.Name + "." + t.Collector.Namespace,
+		CollectorPort:    t.Collector.Port,
+		Protocol:         t.Protocol,
+		ServiceName:      t.ServiceName,
+		Sampling:         sampling,
+		CustomTags:       tags,
+	}, nil
 }
 
 // grpcOptions returns a slice of grpc.ServerOptions.
@@ -27,6 +27,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
 	"github.com/projectcontour/contour/internal/k8s"
 
@@ -204,6 +205,64 @@ default-http-versions:
 				return ctx
 			},
 		},
+		"security headers": {
+			yamlIn: `
+tls:
+  security-headers:
+    hsts:
+      max-age: 8760h
+      include-subdomains: true
+    headers:
+      X-Content-Type-Options: nosniff
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				ctx.TLSConfig.SecurityHeaders = &SecurityHeadersConfig{
+					HSTS: &HSTSConfig{
+						MaxAge:            8760 * time.Hour,
+						IncludeSubdomains: true,
+					},
+					Headers: map[string]string{
+						"X-Content-Type-Options": "nosniff",
+					},
+				}
+				return ctx
+			},
+		},
+		"tracing": {
+			yamlIn: `
+tracing:
+  collector:
+    name: otel-collector
+    namespace: observability
+    port: 9411
+  protocol: zipkin-proto
+  sampling: 12.5
+  service-name: ingress
+  custom-tags:
+  - name: cluster
+    literal: prod
+`,
+			want: func() *serveContext {
+				ctx := newServeContext()
+				sampling := 12.5
+				ctx.Tracing = &TracingConfig{
+					Collector: TracingCollector{
+						Name:      "otel-collector",
+						Namespace: "observability",
+						Port:      9411,
+					},
+					Protocol:    "zipkin-proto",
+					Sampling:    &sampling,
+					ServiceName: "ingress",
+					CustomTags: []TracingCustomTag{{
+						Name:    "cluster",
+						Literal: "prod",
+					}},
+				}
+				return ctx
+			},
+		},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -285,6 +344,340 @@ func TestFallbackCertificateParams(t *testing.T) {
 	}
 }
 
+func TestTracingConfig(t *testing.T) {
+	collector := TracingCollector{
+		Name:      "otel-collector",
+		Namespace: "observability",
+		Port:      9411,
+	}
+	sampling := func(f float64) *float64 { return &f }
+
+	tests := map[string]struct {
+		tracing     *TracingConfig
+		want        *envoy.TracingConfig
+		expecterror bool
+	}{
+		"tracing not defined": {
+			tracing: nil,
+			want:    nil,
+		},
+		"defaults": {
+			tracing: &TracingConfig{
+				Collector: collector,
+			},
+			want: &envoy.TracingConfig{
+				CollectorAddress: "otel-collector.observability",
+				CollectorPort:    9411,
+				Sampling:         100,
+			},
+		},
+		"all fields set": {
+			tracing: &TracingConfig{
+				Collector:   collector,
+				Protocol:    "zipkin-proto",
+				Sampling:    sampling(0),
+				ServiceName: "ingress",
+				CustomTags: []TracingCustomTag{{
+					Name:          "request-id",
+					RequestHeader: "x-request-id",
+				}},
+			},
+			want: &envoy.TracingConfig{
+				CollectorAddress: "otel-collector.observability",
+				CollectorPort:    9411,
+				Protocol:         "zipkin-proto",
+				ServiceName:      "ingress",
+				Sampling:         0,
+				CustomTags: []envoy.TracingCustomTag{{
+					Name:          "request-id",
+					RequestHeader: "x-request-id",
+				}},
+			},
+		},
+		"missing collector namespace": {
+			tracing: &TracingConfig{
+				Collector: TracingCollector{Name: "otel-collector", Port: 9411},
+			},
+			expecterror: true,
+		},
+		"missing collector port": {
+			tracing: &TracingConfig{
+				Collector: TracingCollector{Name: "otel-collector", Namespace: "observability"},
+			},
+			expecterror: true,
+		},
+		"unknown protocol": {
+			tracing: &TracingConfig{
+				Collector: collector,
+				Protocol:  "jaeger",
+			},
+			expecterror: true,
+		},
+		"sampling out of range": {
+			tracing: &TracingConfig{
+				Collector: collector,
+				Sampling:  sampling(101),
+			},
+			expecterror: true,
+		},
+		"custom tag without a source": {
+			tracing: &TracingConfig{
+				Collector:  collector,
+				CustomTags: []TracingCustomTag{{Name: "cluster"}},
+			},
+			expecterror: true,
+		},
+		"custom tag with two sources": {
+			tracing: &TracingConfig{
+				Collector: collector,
+				CustomTags: []TracingCustomTag{{
+					Name:        "cluster",
+					Literal:     "prod",
+					Environment: "CLUSTER",
+				}},
+			},
+			expecterror: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.tracing.tracingConfig()
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatal(diff)
+			}
+
+			goterror := err != nil
+			if goterror != tc.expecterror {
+				t.Errorf("Expected tracing configuration error: %s", err)
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersPolicy(t *testing.T) {
+	tests := map[string]struct {
+		headers     *SecurityHeadersConfig
+		want        *dag.SecurityHeadersPolicy
+		expecterror bool
+	}{
+		"security headers not defined": {
+			headers: nil,
+			want:    nil,
+		},
+		"hsts and headers": {
+			headers: &SecurityHeadersConfig{
+				HSTS: &HSTSConfig{
+					MaxAge:            time.Hour,
+					IncludeSubdomains: true,
+				},
+				Headers: map[string]string{
+					"x-frame-options": "DENY",
+				},
+			},
+			want: &dag.SecurityHeadersPolicy{
+				HSTS: &dag.HSTSPolicy{
+					MaxAge:            time.Hour,
+					IncludeSubdomains: true,
+				},
+				Set: map[string]string{
+					"X-Frame-Options": "DENY",
+				},
+			},
+		},
+		"negative hsts max-age": {
+			headers: &SecurityHeadersConfig{
+				HSTS: &HSTSConfig{MaxAge: -time.Second},
+			},
+			expecterror: true,
+		},
+		"hsts preload without include-subdomains": {
+			headers: &SecurityHeadersConfig{
+				HSTS: &HSTSConfig{MaxAge: time.Hour, Preload: true},
+			},
+			expecterror: true,
+		},
+		"hsts set as a header": {
+			headers: &SecurityHeadersConfig{
+				Headers: map[string]string{
+					"strict-transport-security": "max-age=3600",
+				},
+			},
+			expecterror: true,
+		},
+		"invalid header name": {
+			headers: &SecurityHeadersConfig{
+				Headers: map[string]string{
+					"x frame options": "DENY",
+				},
+			},
+			expecterror: true,
+		},
+		"duplicate header": {
+			headers: &SecurityHeadersConfig{
+				Headers: map[string]string{
+					"x-frame-options": "DENY",
+					"X-Frame-Options": "SAMEORIGIN",
+				},
+			},
+			expecterror: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.headers.securityHeadersPolicy()
+
+			if !tc.expecterror {
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+
+			goterror := err != nil
+			if goterror != tc.expecterror {
+				t.Errorf("Expected security headers configuration error: %s", err)
+			}
+		})
+	}
+}
+
+func TestClientAddressPolicy(t *testing.T) {
+	tests := map[string]struct {
+		config      *ClientAddressConfig
+		want        *dag.ClientAddressPolicy
+		expecterror bool
+	}{
+		"client address not defined": {
+			config: nil,
+			want:   nil,
+		},
+		"trusted hops": {
+			config: &ClientAddressConfig{
+				NumTrustedHops: 2,
+				SkipXFFAppend:  true,
+			},
+			want: &dag.ClientAddressPolicy{
+				NumTrustedHops: 2,
+				SkipXFFAppend:  true,
+			},
+		},
+		"trusted cidrs": {
+			config: &ClientAddressConfig{
+				NumTrustedHops: 1,
+				TrustedCIDRs:   []string{"10.0.0.0/8", "2001:db8::/32"},
+			},
+			want: &dag.ClientAddressPolicy{
+				NumTrustedHops: 1,
+				TrustedCIDRs: []net.IPNet{{
+					IP:   net.IP{10, 0, 0, 0},
+					Mask: net.CIDRMask(8, 32),
+				}, {
+					IP:   net.ParseIP("2001:db8::"),
+					Mask: net.CIDRMask(32, 128),
+				}},
+			},
+		},
+		"most trusted hops": {
+			config: &ClientAddressConfig{
+				NumTrustedHops: 64,
+			},
+			want: &dag.ClientAddressPolicy{
+				NumTrustedHops: 64,
+			},
+		},
+		"too many trusted hops": {
+			config: &ClientAddressConfig{
+				NumTrustedHops: 65,
+			},
+			expecterror: true,
+		},
+		"trusted cidrs without hops": {
+			config: &ClientAddressConfig{
+				TrustedCIDRs: []string{"10.0.0.0/8"},
+			},
+			expecterror: true,
+		},
+		"invalid cidr": {
+			config: &ClientAddressConfig{
+				NumTrustedHops: 1,
+				TrustedCIDRs:   []string{"10.0.0.1"},
+			},
+			expecterror: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.config.clientAddressPolicy()
+
+			if !tc.expecterror {
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+
+			goterror := err != nil
+			if goterror != tc.expecterror {
+				t.Errorf("Expected client address configuration error: %s", err)
+			}
+		})
+	}
+}
+
+func TestTCPPorts(t *testing.T) {
+	tests := map[string]struct {
+		ports       []int
+		want        []int
+		expecterror bool
+	}{
+		"no tcp ports": {
+			ports: nil,
+			want:  nil,
+		},
+		"tcp ports": {
+			ports: []int{6379, 2525},
+			want:  []int{6379, 2525},
+		},
+		"invalid port": {
+			ports:       []int{65536},
+			expecterror: true,
+		},
+		"duplicate port": {
+			ports:       []int{6379, 6379},
+			expecterror: true,
+		},
+		"http listener port": {
+			ports:       []int{8080},
+			expecterror: true,
+		},
+		"stats listener port": {
+			ports:       []int{8002},
+			expecterror: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := newServeContext()
+			ctx.TCPPorts = tc.ports
+			got, err := ctx.tcpPorts()
+
+			if !tc.expecterror {
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+
+			goterror := err != nil
+			if goterror != tc.expecterror {
+				t.Errorf("Expected TCP ports configuration error: %s", err)
+			}
+		})
+	}
+}
+
 // Testdata for this test case can be re-generated by running:
 // make gencerts
 // cp certs/*.pem cmd/contour/testdata/X/
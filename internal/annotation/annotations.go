@@ -20,6 +20,7 @@ import (
 	"time"
 
 	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -64,10 +65,15 @@ var annotationsByKind = map[string]map[string]struct{}{
 		"projectcontour.io/websocket-routes":             {},
 	},
 	"Service": {
+		"projectcontour.io/dns-discovery-type":    {},
+		"projectcontour.io/dns-lookup-family":     {},
+		"projectcontour.io/dns-refresh-rate":      {},
+		"projectcontour.io/dns-respect-ttl":       {},
 		"projectcontour.io/max-connections":       {},
 		"projectcontour.io/max-pending-requests":  {},
 		"projectcontour.io/max-requests":          {},
 		"projectcontour.io/max-retries":           {},
+		"projectcontour.io/not-ready-endpoints":   {},
 		"projectcontour.io/upstream-protocol.h2":  {},
 		"projectcontour.io/upstream-protocol.h2c": {},
 		"projectcontour.io/upstream-protocol.tls": {},
@@ -277,3 +283,60 @@ func MaxRequests(o metav1.ObjectMetaAccessor) uint32 {
 func MaxRetries(o metav1.ObjectMetaAccessor) uint32 {
 	return parseUInt32(CompatAnnotation(o, "max-retries"))
 }
+
+// NotReadyEndpoints returns the health status that the not ready endpoints
+// of a Service are published with, from the projectcontour.io/not-ready-endpoints
+// annotation. Valid values are "degraded" and "draining".
+//
+// false is returned if the annotation is absent or invalid, in which
+// case not ready endpoints are not published.
+func NotReadyEndpoints(o metav1.ObjectMetaAccessor) (envoy_api_v2_core.HealthStatus, bool) {
+	switch CompatAnnotation(o, "not-ready-endpoints") {
+	case "degraded":
+		return envoy_api_v2_core.HealthStatus_DEGRADED, true
+	case "draining":
+		return envoy_api_v2_core.HealthStatus_DRAINING, true
+	default:
+		return envoy_api_v2_core.HealthStatus_UNKNOWN, false
+	}
+}
+
+// DNSRefreshRate returns the interval at which Envoy resolves the
+// external name of a Service, from the projectcontour.io/dns-refresh-rate
+// annotation.
+//
+// '0' is returned if the annotation is absent, unparseable, or less than
+// a millisecond.
+func DNSRefreshRate(o metav1.ObjectMetaAccessor) time.Duration {
+	d, err := time.ParseDuration(CompatAnnotation(o, "dns-refresh-rate"))
+	if err != nil || d < time.Millisecond {
+		return 0
+	}
+	return d
+}
+
+// DNSRespectTTL returns true if the projectcontour.io/dns-respect-ttl
+// annotation is present and set to true.
+func DNSRespectTTL(o metav1.ObjectMetaAccessor) bool {
+	return CompatAnnotation(o, "dns-respect-ttl") == "true"
+}
+
+// DNSLookupFamily returns the IP address family Envoy resolves the external
+// name of a Service to, from the projectcontour.io/dns-lookup-family annotation.
+// Valid values are "auto", "v4" and "v6".
+//
+// "" is returned if the annotation is absent or invalid.
+func DNSLookupFamily(o metav1.ObjectMetaAccessor) string {
+	switch family := CompatAnnotation(o, "dns-lookup-family"); family {
+	case "auto", "v4", "v6":
+		return family
+	default:
+		return ""
+	}
+}
+
+// LogicalDNS returns true if the projectcontour.io/dns-discovery-type
+// annotation is present and set to logical.
+func LogicalDNS(o metav1.ObjectMetaAccessor) bool {
+	return CompatAnnotation(o, "dns-discovery-type") == "logical"
+}
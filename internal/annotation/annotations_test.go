@@ -16,7 +16,9 @@ package annotation
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/assert"
 	v1 "k8s.io/api/core/v1"
@@ -573,6 +575,50 @@ func backend(name string, port intstr.IntOrString) *v1beta1.IngressBackend {
 	}
 }
 
+func TestNotReadyEndpoints(t *testing.T) {
+	tests := map[string]struct {
+		value  string
+		status envoy_api_v2_core.HealthStatus
+		ok     bool
+	}{
+		"absent": {
+			value:  "",
+			status: envoy_api_v2_core.HealthStatus_UNKNOWN,
+			ok:     false,
+		},
+		"degraded": {
+			value:  "degraded",
+			status: envoy_api_v2_core.HealthStatus_DEGRADED,
+			ok:     true,
+		},
+		"draining": {
+			value:  "draining",
+			status: envoy_api_v2_core.HealthStatus_DRAINING,
+			ok:     true,
+		},
+		"invalid": {
+			value:  "healthy",
+			status: envoy_api_v2_core.HealthStatus_UNKNOWN,
+			ok:     false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"projectcontour.io/not-ready-endpoints": tc.value,
+					},
+				},
+			}
+			status, ok := NotReadyEndpoints(svc)
+			assert.Equal(t, tc.status, status)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
 // kindOf returns the kind string for the given Kubernetes object.
 //
 // The API machinery doesn't populate the metav1.TypeMeta field for
@@ -596,3 +642,49 @@ func kindOf(obj interface{}) string {
 		return ""
 	}
 }
+
+func TestDNSPolicy(t *testing.T) {
+	tests := map[string]struct {
+		annotations  map[string]string
+		refreshRate  time.Duration
+		respectTTL   bool
+		lookupFamily string
+		logicalDNS   bool
+	}{
+		"absent": {},
+		"valid": {
+			annotations: map[string]string{
+				"projectcontour.io/dns-refresh-rate":   "30s",
+				"projectcontour.io/dns-respect-ttl":    "true",
+				"projectcontour.io/dns-lookup-family":  "v6",
+				"projectcontour.io/dns-discovery-type": "logical",
+			},
+			refreshRate:  30 * time.Second,
+			respectTTL:   true,
+			lookupFamily: "v6",
+			logicalDNS:   true,
+		},
+		"invalid": {
+			annotations: map[string]string{
+				"projectcontour.io/dns-refresh-rate":   "1us",
+				"projectcontour.io/dns-respect-ttl":    "yes",
+				"projectcontour.io/dns-lookup-family":  "ipv6",
+				"projectcontour.io/dns-discovery-type": "strict",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tc.annotations,
+				},
+			}
+			assert.Equal(t, tc.refreshRate, DNSRefreshRate(svc))
+			assert.Equal(t, tc.respectTTL, DNSRespectTTL(svc))
+			assert.Equal(t, tc.lookupFamily, DNSLookupFamily(svc))
+			assert.Equal(t, tc.logicalDNS, LogicalDNS(svc))
+		})
+	}
+}
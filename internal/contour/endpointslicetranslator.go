@@ -0,0 +1,335 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contour
+
+import (
+	"sort"
+	"sync"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	"github.com/golang/protobuf/proto"
+	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/annotation"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/projectcontour/contour/internal/protobuf"
+	"github.com/projectcontour/contour/internal/sorter"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8scache "k8s.io/client-go/tools/cache"
+)
+
+// A EndpointSliceTranslator translates Kubernetes EndpointSlice objects
+// into Envoy ClusterLoadAssignment objects. The endpoints of all the
+// slices of a service are merged per service port, and grouped by the
+// locality in their topology. If it is also informed of Services, the
+// not ready endpoints of the Services that publish them are included.
+// If it is also informed of ExternalBackends, their endpoints are included.
+type EndpointSliceTranslator struct {
+	logrus.FieldLogger
+	clusterLoadAssignmentCache
+
+	// mu guards slices, notReady and clusters.
+	mu sync.Mutex
+
+	// notReady holds the health status that the not ready
+	// endpoints of each Service are published with. Services
+	// that don't publish their not ready endpoints are absent.
+	notReady map[k8s.FullName]envoy_api_v2_core.HealthStatus
+
+	// slices holds the EndpointSlices of each service, by slice name.
+	slices map[k8s.FullName]map[string]*discoveryv1beta1.EndpointSlice
+
+	// clusters holds the names of the ClusterLoadAssignments
+	// last computed for each service.
+	clusters map[k8s.FullName][]string
+}
+
+func (e *EndpointSliceTranslator) OnAdd(obj interface{}) {
+	switch obj := obj.(type) {
+	case *discoveryv1beta1.EndpointSlice:
+		e.setSlice(obj, obj)
+	case *v1.Service:
+		status, ok := annotation.NotReadyEndpoints(obj)
+		e.setNotReadyStatus(obj.ObjectMeta, status, ok)
+	case *projectcontour.ExternalBackend:
+		e.setExternalBackend(nil, obj)
+	default:
+		e.Errorf("OnAdd unexpected type %T: %#v", obj, obj)
+	}
+}
+
+func (e *EndpointSliceTranslator) OnUpdate(oldObj, newObj interface{}) {
+	switch newObj := newObj.(type) {
+	case *discoveryv1beta1.EndpointSlice:
+		oldObj, ok := oldObj.(*discoveryv1beta1.EndpointSlice)
+		if !ok {
+			e.Errorf("OnUpdate endpointslice %#v received invalid oldObj %T; %#v", newObj, oldObj, oldObj)
+			return
+		}
+		if sliceService(oldObj) != sliceService(newObj) {
+			// the slice has moved to another service.
+			e.setSlice(oldObj, nil)
+		}
+		e.setSlice(newObj, newObj)
+	case *v1.Service:
+		status, ok := annotation.NotReadyEndpoints(newObj)
+		e.setNotReadyStatus(newObj.ObjectMeta, status, ok)
+	case *projectcontour.ExternalBackend:
+		oldObj, ok := oldObj.(*projectcontour.ExternalBackend)
+		if !ok {
+			e.Errorf("OnUpdate externalbackend %#v received invalid oldObj %T; %#v", newObj, oldObj, oldObj)
+			return
+		}
+		e.setExternalBackend(oldObj, newObj)
+	default:
+		e.Errorf("OnUpdate unexpected type %T: %#v", newObj, newObj)
+	}
+}
+
+func (e *EndpointSliceTranslator) OnDelete(obj interface{}) {
+	switch obj := obj.(type) {
+	case *discoveryv1beta1.EndpointSlice:
+		e.setSlice(obj, nil)
+	case *v1.Service:
+		e.setNotReadyStatus(obj.ObjectMeta, envoy_api_v2_core.HealthStatus_UNKNOWN, false)
+	case *projectcontour.ExternalBackend:
+		e.setExternalBackend(obj, nil)
+	case k8scache.DeletedFinalStateUnknown:
+		e.OnDelete(obj.Obj) // recurse into ourselves with the tombstoned value
+	default:
+		e.Errorf("OnDelete unexpected type %T: %#v", obj, obj)
+	}
+}
+
+func (e *EndpointSliceTranslator) Contents() []proto.Message {
+	values := e.clusterLoadAssignmentCache.Contents()
+	sort.Stable(sorter.For(values))
+	return protobuf.AsMessages(values)
+}
+
+func (e *EndpointSliceTranslator) Query(names []string) []proto.Message {
+	e.clusterLoadAssignmentCache.mu.Lock()
+	defer e.clusterLoadAssignmentCache.mu.Unlock()
+	values := make([]*v2.ClusterLoadAssignment, 0, len(names))
+	for _, n := range names {
+		v, ok := e.entries[n]
+		if !ok {
+			v = &v2.ClusterLoadAssignment{
+				ClusterName: n,
+			}
+		}
+		values = append(values, v)
+	}
+
+	sort.Stable(sorter.For(values))
+	return protobuf.AsMessages(values)
+}
+
+func (*EndpointSliceTranslator) TypeURL() string { return resource.EndpointType }
+
+// sliceService returns the name of the service the supplied
+// EndpointSlice belongs to. The name is empty if the slice
+// does not belong to a service.
+func sliceService(slice *discoveryv1beta1.EndpointSlice) k8s.FullName {
+	name, ok := slice.Labels[discoveryv1beta1.LabelServiceName]
+	if !ok {
+		return k8s.FullName{}
+	}
+	return k8s.FullName{Name: name, Namespace: slice.Namespace}
+}
+
+// setSlice records the current value of the supplied slice, or
+// forgets it if current is nil, then recomputes the
+// ClusterLoadAssignments of the slice's service.
+func (e *EndpointSliceTranslator) setSlice(slice, current *discoveryv1beta1.EndpointSlice) {
+	service := sliceService(slice)
+	if service.Name == "" {
+		// slices not managed for a service are ignored.
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if current == nil {
+		delete(e.slices[service], slice.Name)
+		if len(e.slices[service]) == 0 {
+			delete(e.slices, service)
+		}
+	} else {
+		if e.slices == nil {
+			e.slices = make(map[k8s.FullName]map[string]*discoveryv1beta1.EndpointSlice)
+		}
+		if e.slices[service] == nil {
+			e.slices[service] = make(map[string]*discoveryv1beta1.EndpointSlice)
+		}
+		e.slices[service][slice.Name] = current
+	}
+
+	e.recomputeClusterLoadAssignments(service)
+}
+
+// setNotReadyStatus records the health status that the not ready
+// endpoints of the Service with the supplied meta are published with,
+// or forgets it if publish is false, and recomputes the Service's
+// ClusterLoadAssignments if it has changed.
+func (e *EndpointSliceTranslator) setNotReadyStatus(meta metav1.ObjectMeta, status envoy_api_v2_core.HealthStatus, publish bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	service := k8s.FullName{Name: meta.Name, Namespace: meta.Namespace}
+	current, ok := e.notReady[service]
+	if ok == publish && current == status {
+		return
+	}
+	if !publish {
+		delete(e.notReady, service)
+	} else {
+		if e.notReady == nil {
+			e.notReady = make(map[k8s.FullName]envoy_api_v2_core.HealthStatus)
+		}
+		e.notReady[service] = status
+	}
+
+	if _, ok := e.slices[service]; ok {
+		e.recomputeClusterLoadAssignments(service)
+	}
+}
+
+// recomputeClusterLoadAssignments recomputes the EDS cache entries of
+// the supplied service from the endpoints of all of its slices.
+func (e *EndpointSliceTranslator) recomputeClusterLoadAssignments(service k8s.FullName) {
+	slices := make([]*discoveryv1beta1.EndpointSlice, 0, len(e.slices[service]))
+	for _, slice := range e.slices[service] {
+		slices = append(slices, slice)
+	}
+	sort.Slice(slices, func(i, j int) bool { return slices[i].Name < slices[j].Name })
+
+	type endpoint struct {
+		ip       string
+		port     int
+		locality *envoy_api_v2_core.Locality
+		status   envoy_api_v2_core.HealthStatus
+	}
+
+	notReadyStatus, publish := e.notReady[service]
+
+	// endpoints holds the ready endpoints of each port, by port name.
+	endpoints := make(map[string][]endpoint)
+	seen := make(map[string]map[string]bool)
+	for _, slice := range slices {
+		switch slice.AddressType {
+		case discoveryv1beta1.AddressTypeIPv4, discoveryv1beta1.AddressTypeIPv6, discoveryv1beta1.AddressTypeIP:
+		default:
+			// skip slices of addresses Envoy can't connect to directly.
+			continue
+		}
+
+		for _, p := range slice.Ports {
+			if p.Protocol != nil && *p.Protocol != v1.ProtocolTCP {
+				// skip non TCP ports
+				continue
+			}
+			if p.Port == nil {
+				// skip ports that are not yet known
+				continue
+			}
+
+			var portname string
+			if p.Name != nil {
+				portname = *p.Name
+			}
+			for _, ep := range slice.Endpoints {
+				status := envoy_api_v2_core.HealthStatus_UNKNOWN
+				if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+					if !publish {
+						// skip endpoints that are not ready.
+						continue
+					}
+					status = notReadyStatus
+				}
+				locality := topologyLocality(ep.Topology)
+				for _, addr := range ep.Addresses {
+					if seen[portname][addr] {
+						// an endpoint may briefly be in more than one slice.
+						continue
+					}
+					if seen[portname] == nil {
+						seen[portname] = make(map[string]bool)
+					}
+					seen[portname][addr] = true
+					endpoints[portname] = append(endpoints[portname], endpoint{
+						ip:       addr,
+						port:     int(*p.Port),
+						locality: locality,
+						status:   status,
+					})
+				}
+			}
+		}
+	}
+
+	meta := metav1.ObjectMeta{Name: service.Name, Namespace: service.Namespace}
+	current := make(map[string]bool)
+	for portname, eps := range endpoints {
+		sort.SliceStable(eps, func(i, j int) bool {
+			if eps[i].status != eps[j].status {
+				// ready endpoints come first.
+				return eps[i].status == envoy_api_v2_core.HealthStatus_UNKNOWN
+			}
+			return eps[i].ip < eps[j].ip
+		})
+
+		lbendpoints := make([]localityEndpoint, 0, len(eps))
+		for _, ep := range eps {
+			lbendpoints = append(lbendpoints, localityEndpoint{
+				locality: ep.locality,
+				address:  envoy.SocketAddress(ep.ip, ep.port),
+				status:   ep.status,
+			})
+		}
+
+		cla := &v2.ClusterLoadAssignment{
+			ClusterName: servicename(meta, portname),
+			Endpoints:   groupByLocality(lbendpoints),
+		}
+		current[cla.ClusterName] = true
+		e.Add(cla)
+	}
+
+	// remove the entries of ports that are no longer present.
+	for _, name := range e.clusters[service] {
+		if !current[name] {
+			e.Remove(name)
+		}
+	}
+
+	if len(current) == 0 {
+		delete(e.clusters, service)
+		return
+	}
+	if e.clusters == nil {
+		e.clusters = make(map[k8s.FullName][]string)
+	}
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	e.clusters[service] = names
+}
@@ -0,0 +1,345 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contour
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+	"github.com/golang/protobuf/proto"
+	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/assert"
+	"github.com/projectcontour/contour/internal/envoy"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEndpointSliceTranslatorAddEndpointSlices(t *testing.T) {
+	tests := map[string]struct {
+		slices []*discoveryv1beta1.EndpointSlice
+		want   []proto.Message
+	}{
+		"simple": {
+			slices: []*discoveryv1beta1.EndpointSlice{
+				endpointSlice("default", "simple-abc", "simple", discoveryv1beta1.AddressTypeIPv4,
+					sliceEndpoints("192.168.183.24"),
+					slicePort("", 8080),
+				),
+			},
+			want: []proto.Message{
+				envoy.ClusterLoadAssignment("default/simple", envoy.SocketAddress("192.168.183.24", 8080)),
+			},
+		},
+		"multiple slices are merged": {
+			slices: []*discoveryv1beta1.EndpointSlice{
+				endpointSlice("default", "httpbin-org-abc", "httpbin-org", discoveryv1beta1.AddressTypeIPv4,
+					sliceEndpoints("50.17.192.147", "50.19.99.160"),
+					slicePort("", 80),
+				),
+				endpointSlice("default", "httpbin-org-def", "httpbin-org", discoveryv1beta1.AddressTypeIPv4,
+					sliceEndpoints("23.23.247.89", "50.17.206.192", "50.17.192.147"),
+					slicePort("", 80),
+				),
+			},
+			want: []proto.Message{
+				envoy.ClusterLoadAssignment("default/httpbin-org",
+					envoy.SocketAddress("23.23.247.89", 80), // addresses should be sorted and unique
+					envoy.SocketAddress("50.17.192.147", 80),
+					envoy.SocketAddress("50.17.206.192", 80),
+					envoy.SocketAddress("50.19.99.160", 80),
+				),
+			},
+		},
+		"multiple ports": {
+			slices: []*discoveryv1beta1.EndpointSlice{
+				endpointSlice("default", "httpbin-org-abc", "httpbin-org", discoveryv1beta1.AddressTypeIPv4,
+					sliceEndpoints("10.10.1.1"),
+					slicePort("b", 309),
+					slicePort("a", 8675),
+				),
+			},
+			want: []proto.Message{
+				envoy.ClusterLoadAssignment("default/httpbin-org/a", envoy.SocketAddress("10.10.1.1", 8675)),
+				envoy.ClusterLoadAssignment("default/httpbin-org/b", envoy.SocketAddress("10.10.1.1", 309)),
+			},
+		},
+		"dual stack": {
+			slices: []*discoveryv1beta1.EndpointSlice{
+				endpointSlice("default", "simple-abc", "simple", discoveryv1beta1.AddressTypeIPv4,
+					sliceEndpoints("192.168.183.24"),
+					slicePort("", 8080),
+				),
+				endpointSlice("default", "simple-def", "simple", discoveryv1beta1.AddressTypeIPv6,
+					sliceEndpoints("2001:db8::1"),
+					slicePort("", 8080),
+				),
+			},
+			want: []proto.Message{
+				envoy.ClusterLoadAssignment("default/simple",
+					envoy.SocketAddress("192.168.183.24", 8080),
+					envoy.SocketAddress("2001:db8::1", 8080),
+				),
+			},
+		},
+		"fqdn slices are ignored": {
+			slices: []*discoveryv1beta1.EndpointSlice{
+				endpointSlice("default", "simple-abc", "simple", discoveryv1beta1.AddressTypeFQDN,
+					sliceEndpoints("www.example.com"),
+					slicePort("", 8080),
+				),
+			},
+			want: nil,
+		},
+		"slices without a service are ignored": {
+			slices: []*discoveryv1beta1.EndpointSlice{
+				endpointSlice("default", "simple-abc", "", discoveryv1beta1.AddressTypeIPv4,
+					sliceEndpoints("192.168.183.24"),
+					slicePort("", 8080),
+				),
+			},
+			want: nil,
+		},
+		"endpoints that are not ready are skipped": {
+			slices: []*discoveryv1beta1.EndpointSlice{
+				endpointSlice("default", "simple-abc", "simple", discoveryv1beta1.AddressTypeIPv4,
+					append(sliceEndpoints("192.168.183.24"), discoveryv1beta1.Endpoint{
+						Addresses: []string{"192.168.183.25"},
+						Conditions: discoveryv1beta1.EndpointConditions{
+							Ready: new(bool),
+						},
+					}),
+					slicePort("", 8080),
+				),
+			},
+			want: []proto.Message{
+				envoy.ClusterLoadAssignment("default/simple", envoy.SocketAddress("192.168.183.24", 8080)),
+			},
+		},
+		"endpoints are grouped by topology": {
+			slices: []*discoveryv1beta1.EndpointSlice{
+				endpointSlice("default", "simple-abc", "simple", discoveryv1beta1.AddressTypeIPv4,
+					[]discoveryv1beta1.Endpoint{{
+						Addresses: []string{"192.168.183.24"},
+						Topology: map[string]string{
+							v1.LabelZoneRegionStable:        "us-east-1",
+							v1.LabelZoneFailureDomainStable: "us-east-1a",
+						},
+					}, {
+						Addresses: []string{"192.168.183.25"},
+					}},
+					slicePort("", 8080),
+				),
+			},
+			want: []proto.Message{
+				&v2.ClusterLoadAssignment{
+					ClusterName: "default/simple",
+					Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
+						LbEndpoints: lbendpoints(envoy.SocketAddress("192.168.183.25", 8080)),
+					}, {
+						Locality: &envoy_api_v2_core.Locality{
+							Region: "us-east-1",
+							Zone:   "us-east-1a",
+						},
+						LbEndpoints: lbendpoints(envoy.SocketAddress("192.168.183.24", 8080)),
+					}},
+				},
+			},
+		},
+	}
+
+	log := testLogger(t)
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			et := &EndpointSliceTranslator{
+				FieldLogger: log,
+			}
+			for _, slice := range tc.slices {
+				et.OnAdd(slice)
+			}
+			got := et.Contents()
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestEndpointSliceTranslatorRemoveEndpointSlices(t *testing.T) {
+	et := &EndpointSliceTranslator{
+		FieldLogger: testLogger(t),
+	}
+
+	s1 := endpointSlice("default", "simple-abc", "simple", discoveryv1beta1.AddressTypeIPv4,
+		sliceEndpoints("192.168.183.24"),
+		slicePort("a", 8080),
+	)
+	s2 := endpointSlice("default", "simple-def", "simple", discoveryv1beta1.AddressTypeIPv4,
+		sliceEndpoints("192.168.183.25"),
+		slicePort("a", 8080),
+		slicePort("b", 8081),
+	)
+	et.OnAdd(s1)
+	et.OnAdd(s2)
+
+	want := []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple/a",
+			envoy.SocketAddress("192.168.183.24", 8080),
+			envoy.SocketAddress("192.168.183.25", 8080),
+		),
+		envoy.ClusterLoadAssignment("default/simple/b", envoy.SocketAddress("192.168.183.25", 8081)),
+	}
+	assert.Equal(t, want, et.Contents())
+
+	// Assert that removing a slice removes its endpoints, and
+	// the ports that no other slice of the service has.
+	et.OnDelete(s2)
+	want = []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple/a", envoy.SocketAddress("192.168.183.24", 8080)),
+	}
+	assert.Equal(t, want, et.Contents())
+
+	// Assert that updating a slice to have no endpoints
+	// removes the ports of the service.
+	s3 := endpointSlice("default", "simple-abc", "simple", discoveryv1beta1.AddressTypeIPv4,
+		nil,
+		slicePort("a", 8080),
+	)
+	et.OnUpdate(s1, s3)
+	want = nil
+	assert.Equal(t, want, et.Contents())
+}
+
+func TestEndpointSliceTranslatorNotReadyEndpoints(t *testing.T) {
+	et := &EndpointSliceTranslator{
+		FieldLogger: testLogger(t),
+	}
+
+	et.OnAdd(endpointSlice("default", "simple-abc", "simple", discoveryv1beta1.AddressTypeIPv4,
+		append(sliceEndpoints("10.0.0.2"), discoveryv1beta1.Endpoint{
+			Addresses: []string{"10.0.0.1"},
+			Conditions: discoveryv1beta1.EndpointConditions{
+				Ready: new(bool),
+			},
+		}),
+		slicePort("", 8080),
+	))
+
+	// Assert that not ready endpoints are not published by default.
+	want := []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple", envoy.SocketAddress("10.0.0.2", 8080)),
+	}
+	assert.Equal(t, want, et.Contents())
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"projectcontour.io/not-ready-endpoints": "degraded",
+			},
+		},
+	}
+	et.OnAdd(svc)
+
+	// Assert that not ready endpoints are published after the
+	// ready endpoints, with the health status of the annotation.
+	degraded := envoy.LBEndpoint(envoy.SocketAddress("10.0.0.1", 8080))
+	degraded.HealthStatus = envoy_api_v2_core.HealthStatus_DEGRADED
+	want = []proto.Message{
+		&v2.ClusterLoadAssignment{
+			ClusterName: "default/simple",
+			Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
+				LbEndpoints: []*envoy_api_v2_endpoint.LbEndpoint{
+					envoy.LBEndpoint(envoy.SocketAddress("10.0.0.2", 8080)),
+					degraded,
+				},
+			}},
+		},
+	}
+	assert.Equal(t, want, et.Contents())
+
+	et.OnDelete(svc)
+
+	want = []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple", envoy.SocketAddress("10.0.0.2", 8080)),
+	}
+	assert.Equal(t, want, et.Contents())
+}
+
+func TestEndpointSliceTranslatorExternalBackend(t *testing.T) {
+	et := &EndpointSliceTranslator{
+		FieldLogger: testLogger(t),
+	}
+
+	et.OnAdd(endpointSlice("default", "simple-abc", "simple", discoveryv1beta1.AddressTypeIPv4,
+		sliceEndpoints("192.168.183.24"),
+		slicePort("", 8080),
+	))
+	eb := externalBackend("default", "vms",
+		projectcontour.ExternalBackendEndpoint{Address: "172.16.0.1", Port: 8080},
+	)
+	et.OnAdd(eb)
+
+	// Assert that the endpoints of the backend are published
+	// alongside those of the services.
+	want := []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple", envoy.SocketAddress("192.168.183.24", 8080)),
+		envoy.ClusterLoadAssignment("default/vms/8080", envoy.SocketAddress("172.16.0.1", 8080)),
+	}
+	assert.Equal(t, want, et.Contents())
+
+	et.OnDelete(eb)
+	want = []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple", envoy.SocketAddress("192.168.183.24", 8080)),
+	}
+	assert.Equal(t, want, et.Contents())
+}
+
+func endpointSlice(ns, name, service string, addressType discoveryv1beta1.AddressType, endpoints []discoveryv1beta1.Endpoint, ports ...discoveryv1beta1.EndpointPort) *discoveryv1beta1.EndpointSlice {
+	slice := &discoveryv1beta1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		AddressType: addressType,
+		Endpoints:   endpoints,
+		Ports:       ports,
+	}
+	if service != "" {
+		slice.Labels = map[string]string{
+			discoveryv1beta1.LabelServiceName: service,
+		}
+	}
+	return slice
+}
+
+func sliceEndpoints(addrs ...string) []discoveryv1beta1.Endpoint {
+	var endpoints []discoveryv1beta1.Endpoint
+	for _, addr := range addrs {
+		endpoints = append(endpoints, discoveryv1beta1.Endpoint{
+			Addresses: []string{addr},
+		})
+	}
+	return endpoints
+}
+
+func slicePort(name string, port int32) discoveryv1beta1.EndpointPort {
+	protocol := v1.ProtocolTCP
+	return discoveryv1beta1.EndpointPort{
+		Name:     &name,
+		Port:     &port,
+		Protocol: &protocol,
+	}
+}
@@ -14,15 +14,21 @@
 package contour
 
 import (
+	"net"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_api_v2_endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
 	"github.com/golang/protobuf/proto"
+	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/annotation"
 	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/k8s"
 	"github.com/projectcontour/contour/internal/protobuf"
 	"github.com/projectcontour/contour/internal/sorter"
 	"github.com/sirupsen/logrus"
@@ -32,16 +38,45 @@ import (
 )
 
 // A EndpointsTranslator translates Kubernetes Endpoints objects into Envoy
-// ClusterLoadAssignment objects.
+// ClusterLoadAssignment objects. If it is also informed of Nodes, the
+// endpoints of each ClusterLoadAssignment are grouped by the locality
+// of their node. If it is also informed of Services, the not ready
+// addresses of the Services that publish them are included. If it is
+// also informed of ExternalBackends, their endpoints are included.
 type EndpointsTranslator struct {
 	logrus.FieldLogger
 	clusterLoadAssignmentCache
+
+	// endpointsMu guards localities, notReady and endpoints, as
+	// Nodes, Services and Endpoints are informed concurrently.
+	endpointsMu sync.Mutex
+
+	// localities holds the locality of each node, by name.
+	localities map[string]*envoy_api_v2_core.Locality
+
+	// notReady holds the health status that the not ready
+	// addresses of each Service are published with. Services
+	// that don't publish their not ready addresses are absent.
+	notReady map[k8s.FullName]envoy_api_v2_core.HealthStatus
+
+	// endpoints holds the Endpoints the cache was computed
+	// from, so that their ClusterLoadAssignments can be
+	// recomputed when the locality of a node, or the not
+	// ready status of a Service, changes.
+	endpoints map[k8s.FullName]*v1.Endpoints
 }
 
 func (e *EndpointsTranslator) OnAdd(obj interface{}) {
 	switch obj := obj.(type) {
 	case *v1.Endpoints:
 		e.addEndpoints(obj)
+	case *v1.Node:
+		e.setNodeLocality(obj.Name, nodeLocality(obj))
+	case *v1.Service:
+		status, ok := annotation.NotReadyEndpoints(obj)
+		e.setNotReadyStatus(obj.ObjectMeta, status, ok)
+	case *projectcontour.ExternalBackend:
+		e.setExternalBackend(nil, obj)
 	default:
 		e.Errorf("OnAdd unexpected type %T: %#v", obj, obj)
 	}
@@ -56,6 +91,18 @@ func (e *EndpointsTranslator) OnUpdate(oldObj, newObj interface{}) {
 			return
 		}
 		e.updateEndpoints(oldObj, newObj)
+	case *v1.Node:
+		e.setNodeLocality(newObj.Name, nodeLocality(newObj))
+	case *v1.Service:
+		status, ok := annotation.NotReadyEndpoints(newObj)
+		e.setNotReadyStatus(newObj.ObjectMeta, status, ok)
+	case *projectcontour.ExternalBackend:
+		oldObj, ok := oldObj.(*projectcontour.ExternalBackend)
+		if !ok {
+			e.Errorf("OnUpdate externalbackend %#v received invalid oldObj %T; %#v", newObj, oldObj, oldObj)
+			return
+		}
+		e.setExternalBackend(oldObj, newObj)
 	default:
 		e.Errorf("OnUpdate unexpected type %T: %#v", newObj, newObj)
 	}
@@ -65,6 +112,12 @@ func (e *EndpointsTranslator) OnDelete(obj interface{}) {
 	switch obj := obj.(type) {
 	case *v1.Endpoints:
 		e.removeEndpoints(obj)
+	case *v1.Node:
+		e.setNodeLocality(obj.Name, nil)
+	case *v1.Service:
+		e.setNotReadyStatus(obj.ObjectMeta, envoy_api_v2_core.HealthStatus_UNKNOWN, false)
+	case *projectcontour.ExternalBackend:
+		e.setExternalBackend(obj, nil)
 	case k8scache.DeletedFinalStateUnknown:
 		e.OnDelete(obj.Obj) // recurse into ourselves with the tombstoned value
 	default:
@@ -99,10 +152,16 @@ func (e *EndpointsTranslator) Query(names []string) []proto.Message {
 func (*EndpointsTranslator) TypeURL() string { return resource.EndpointType }
 
 func (e *EndpointsTranslator) addEndpoints(ep *v1.Endpoints) {
+	e.endpointsMu.Lock()
+	defer e.endpointsMu.Unlock()
+	e.setEndpoints(ep.ObjectMeta, ep)
 	e.recomputeClusterLoadAssignment(nil, ep)
 }
 
 func (e *EndpointsTranslator) updateEndpoints(oldep, newep *v1.Endpoints) {
+	e.endpointsMu.Lock()
+	defer e.endpointsMu.Unlock()
+	e.setEndpoints(newep.ObjectMeta, newep)
 	if len(newep.Subsets) == 0 && len(oldep.Subsets) == 0 {
 		// if there are no endpoints in this object, and the old
 		// object also had zero endpoints, ignore this update
@@ -113,9 +172,190 @@ func (e *EndpointsTranslator) updateEndpoints(oldep, newep *v1.Endpoints) {
 }
 
 func (e *EndpointsTranslator) removeEndpoints(ep *v1.Endpoints) {
+	e.endpointsMu.Lock()
+	defer e.endpointsMu.Unlock()
+	e.setEndpoints(ep.ObjectMeta, nil)
 	e.recomputeClusterLoadAssignment(ep, nil)
 }
 
+// setEndpoints records the current Endpoints with the supplied
+// meta, or forgets them if ep is nil.
+func (e *EndpointsTranslator) setEndpoints(meta metav1.ObjectMeta, ep *v1.Endpoints) {
+	name := k8s.FullName{Name: meta.Name, Namespace: meta.Namespace}
+	if ep == nil {
+		delete(e.endpoints, name)
+		return
+	}
+	if e.endpoints == nil {
+		e.endpoints = make(map[k8s.FullName]*v1.Endpoints)
+	}
+	e.endpoints[name] = ep
+}
+
+// setNodeLocality records the locality of the named node, or
+// forgets it if locality is nil, and recomputes the
+// ClusterLoadAssignments of the Endpoints on the node if
+// its locality has changed.
+func (e *EndpointsTranslator) setNodeLocality(name string, locality *envoy_api_v2_core.Locality) {
+	e.endpointsMu.Lock()
+	defer e.endpointsMu.Unlock()
+
+	if localityKey(e.localities[name]) == localityKey(locality) {
+		return
+	}
+	if locality == nil {
+		delete(e.localities, name)
+	} else {
+		if e.localities == nil {
+			e.localities = make(map[string]*envoy_api_v2_core.Locality)
+		}
+		e.localities[name] = locality
+	}
+
+	for _, ep := range e.endpoints {
+		if onNode(ep, name) {
+			e.recomputeClusterLoadAssignment(nil, ep)
+		}
+	}
+}
+
+// onNode returns true if any address of ep is on the named node.
+func onNode(ep *v1.Endpoints, name string) bool {
+	on := func(addresses []v1.EndpointAddress) bool {
+		for _, a := range addresses {
+			if a.NodeName != nil && *a.NodeName == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, s := range ep.Subsets {
+		if on(s.Addresses) || on(s.NotReadyAddresses) {
+			return true
+		}
+	}
+	return false
+}
+
+// setNotReadyStatus records the health status that the not ready
+// addresses of the Service with the supplied meta are published with,
+// or forgets it if publish is false, and recomputes the Service's
+// ClusterLoadAssignments if it has changed.
+func (e *EndpointsTranslator) setNotReadyStatus(meta metav1.ObjectMeta, status envoy_api_v2_core.HealthStatus, publish bool) {
+	e.endpointsMu.Lock()
+	defer e.endpointsMu.Unlock()
+
+	name := k8s.FullName{Name: meta.Name, Namespace: meta.Namespace}
+	current, ok := e.notReady[name]
+	if ok == publish && current == status {
+		return
+	}
+	if !publish {
+		delete(e.notReady, name)
+	} else {
+		if e.notReady == nil {
+			e.notReady = make(map[k8s.FullName]envoy_api_v2_core.HealthStatus)
+		}
+		e.notReady[name] = status
+	}
+
+	if ep, ok := e.endpoints[name]; ok {
+		// recompute from a copy of the Endpoints, so that the
+		// ports of only not ready addresses are removed if they
+		// are no longer published.
+		e.recomputeClusterLoadAssignment(ep.DeepCopy(), ep)
+	}
+}
+
+// nodeLocality returns the locality of the supplied node from its
+// topology labels, or nil if it has none.
+func nodeLocality(node *v1.Node) *envoy_api_v2_core.Locality {
+	return topologyLocality(node.Labels)
+}
+
+// topologyLocality returns the locality described by the supplied
+// topology labels, or nil if they describe none.
+func topologyLocality(labels map[string]string) *envoy_api_v2_core.Locality {
+	label := func(name, deprecated string) string {
+		if v, ok := labels[name]; ok {
+			return v
+		}
+		return labels[deprecated]
+	}
+
+	locality := &envoy_api_v2_core.Locality{
+		Region: label(v1.LabelZoneRegionStable, v1.LabelZoneRegion),
+		Zone:   label(v1.LabelZoneFailureDomainStable, v1.LabelZoneFailureDomain),
+	}
+	if locality.Region == "" && locality.Zone == "" {
+		return nil
+	}
+	return locality
+}
+
+// localityKey returns a key that orders localities by region
+// then zone, with the nil locality first.
+func localityKey(locality *envoy_api_v2_core.Locality) string {
+	if locality == nil {
+		return ""
+	}
+	return locality.Region + "/" + locality.Zone
+}
+
+// localityEndpoints returns the supplied addresses, with the supplied
+// health status, and the locality of their node. Addresses on nodes of
+// unknown locality have no locality.
+func (e *EndpointsTranslator) localityEndpoints(addresses []v1.EndpointAddress, status envoy_api_v2_core.HealthStatus, port int) []localityEndpoint {
+	endpoints := make([]localityEndpoint, 0, len(addresses))
+	for _, a := range addresses {
+		var locality *envoy_api_v2_core.Locality
+		if a.NodeName != nil {
+			locality = e.localities[*a.NodeName]
+		}
+		endpoints = append(endpoints, localityEndpoint{
+			locality: locality,
+			address:  envoy.SocketAddress(a.IP, port),
+			status:   status,
+		})
+	}
+	return endpoints
+}
+
+// localityEndpoint is the address of an endpoint, its locality,
+// and its health status.
+type localityEndpoint struct {
+	locality *envoy_api_v2_core.Locality
+	address  *envoy_api_v2_core.Address
+	status   envoy_api_v2_core.HealthStatus
+}
+
+// groupByLocality groups the supplied endpoints by locality, keeping
+// their order within each group. The groups are ordered by locality
+// with the nil locality first.
+func groupByLocality(endpoints []localityEndpoint) []*envoy_api_v2_endpoint.LocalityLbEndpoints {
+	var groups []*envoy_api_v2_endpoint.LocalityLbEndpoints
+	seen := make(map[string]*envoy_api_v2_endpoint.LocalityLbEndpoints)
+	for _, ep := range endpoints {
+		key := localityKey(ep.locality)
+		group, ok := seen[key]
+		if !ok {
+			group = &envoy_api_v2_endpoint.LocalityLbEndpoints{
+				Locality: ep.locality,
+			}
+			seen[key] = group
+			groups = append(groups, group)
+		}
+		lbendpoint := envoy.LBEndpoint(ep.address)
+		lbendpoint.HealthStatus = ep.status
+		group.LbEndpoints = append(group.LbEndpoints, lbendpoint)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return localityKey(groups[i].Locality) < localityKey(groups[j].Locality)
+	})
+	return groups
+}
+
 // recomputeClusterLoadAssignment recomputes the EDS cache taking into account old and new endpoints.
 func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.Endpoints) {
 	// skip computation if either old and new services or endpoints are equal (thus also handling nil)
@@ -135,11 +375,18 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 		}
 	}
 
+	status, publish := e.notReady[k8s.FullName{Name: newep.Name, Namespace: newep.Namespace}]
+
 	seen := make(map[string]bool)
 	// add or update endpoints
 	for _, s := range newep.Subsets {
-		if len(s.Addresses) < 1 {
-			// skip subset without ready addresses.
+		var notReady []v1.EndpointAddress
+		if publish {
+			notReady = append([]v1.EndpointAddress{}, s.NotReadyAddresses...) // shallow copy
+			sort.Slice(notReady, func(i, j int) bool { return notReady[i].IP < notReady[j].IP })
+		}
+		if len(s.Addresses) < 1 && len(notReady) < 1 {
+			// skip subset without published addresses.
 			continue
 		}
 		for _, p := range s.Ports {
@@ -151,17 +398,12 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 			addresses := append([]v1.EndpointAddress{}, s.Addresses...) // shallow copy
 			sort.Slice(addresses, func(i, j int) bool { return addresses[i].IP < addresses[j].IP })
 
-			lbendpoints := make([]*envoy_api_v2_endpoint.LbEndpoint, 0, len(addresses))
-			for _, a := range addresses {
-				addr := envoy.SocketAddress(a.IP, int(p.Port))
-				lbendpoints = append(lbendpoints, envoy.LBEndpoint(addr))
-			}
+			endpoints := e.localityEndpoints(addresses, envoy_api_v2_core.HealthStatus_UNKNOWN, int(p.Port))
+			endpoints = append(endpoints, e.localityEndpoints(notReady, status, int(p.Port))...)
 
 			cla := &v2.ClusterLoadAssignment{
 				ClusterName: servicename(newep.ObjectMeta, p.Name),
-				Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
-					LbEndpoints: lbendpoints,
-				}},
+				Endpoints:   groupByLocality(endpoints),
 			}
 			seen[cla.ClusterName] = true
 			e.Add(cla)
@@ -170,9 +412,6 @@ func (e *EndpointsTranslator) recomputeClusterLoadAssignment(oldep, newep *v1.En
 
 	// iterate over the ports in the old spec, remove any were not seen.
 	for _, s := range oldep.Subsets {
-		if len(s.Addresses) == 0 {
-			continue
-		}
 		for _, p := range s.Ports {
 			name := servicename(oldep.ObjectMeta, p.Name)
 			if _, ok := seen[name]; !ok {
@@ -211,6 +450,67 @@ func (c *clusterLoadAssignmentCache) Remove(name string) {
 	c.Notify(name)
 }
 
+// setExternalBackend adds the ClusterLoadAssignments of the newer
+// ExternalBackend to the cache, and removes those of the older that
+// the newer no longer has. Either may be nil.
+func (c *clusterLoadAssignmentCache) setExternalBackend(oldeb, neweb *projectcontour.ExternalBackend) {
+	seen := make(map[string]bool)
+	if neweb != nil {
+		for _, cla := range externalBackendClusterLoadAssignments(neweb) {
+			seen[cla.ClusterName] = true
+			c.Add(cla)
+		}
+	}
+	if oldeb != nil {
+		for _, cla := range externalBackendClusterLoadAssignments(oldeb) {
+			if !seen[cla.ClusterName] {
+				// port is no longer present, remove it.
+				c.Remove(cla.ClusterName)
+			}
+		}
+	}
+}
+
+// externalBackendClusterLoadAssignments returns the ClusterLoadAssignments
+// of the supplied ExternalBackend, one for its endpoints on each port, named
+// by the port number.
+func externalBackendClusterLoadAssignments(eb *projectcontour.ExternalBackend) []*v2.ClusterLoadAssignment {
+	var locality *envoy_api_v2_core.Locality
+	if l := eb.Spec.Locality; l != nil && (l.Region != "" || l.Zone != "") {
+		locality = &envoy_api_v2_core.Locality{
+			Region: l.Region,
+			Zone:   l.Zone,
+		}
+	}
+
+	var ports []int
+	endpoints := make(map[int][]localityEndpoint)
+	for _, ep := range eb.Spec.Endpoints {
+		if net.ParseIP(ep.Address) == nil {
+			// skip addresses that EDS can't publish; the DAG
+			// rejects HTTPProxies that refer to them.
+			continue
+		}
+		if _, ok := endpoints[ep.Port]; !ok {
+			ports = append(ports, ep.Port)
+		}
+		endpoints[ep.Port] = append(endpoints[ep.Port], localityEndpoint{
+			locality: locality,
+			address:  envoy.SocketAddress(ep.Address, ep.Port),
+			status:   envoy_api_v2_core.HealthStatus_UNKNOWN,
+		})
+	}
+
+	clas := make([]*v2.ClusterLoadAssignment, 0, len(ports))
+	for _, port := range ports {
+		clas = append(clas, &v2.ClusterLoadAssignment{
+			ClusterName: servicename(eb.ObjectMeta, strconv.Itoa(port)),
+			Endpoints:   groupByLocality(endpoints[port]),
+		})
+	}
+	return clas
+}
+
 // Contents returns a copy of the contents of the cache.
 func (c *clusterLoadAssignmentCache) Contents() []*v2.ClusterLoadAssignment {
 	c.mu.Lock()
@@ -17,10 +17,14 @@ import (
 	"testing"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	"github.com/golang/protobuf/proto"
+	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/assert"
 	"github.com/projectcontour/contour/internal/envoy"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestEndpointsTranslatorContents(t *testing.T) {
@@ -436,6 +440,251 @@ func TestEndpointsTranslatorScaleToZeroEndpoints(t *testing.T) {
 	assert.Equal(t, want, got)
 }
 
+func TestEndpointsTranslatorNodeLocality(t *testing.T) {
+	et := &EndpointsTranslator{
+		FieldLogger: testLogger(t),
+	}
+
+	nodeName := func(name string) *string { return &name }
+	et.OnAdd(endpoints("default", "simple", v1.EndpointSubset{
+		Addresses: []v1.EndpointAddress{
+			{IP: "10.0.0.1", NodeName: nodeName("node-a")},
+			{IP: "10.0.0.2", NodeName: nodeName("node-b")},
+			{IP: "10.0.0.3", NodeName: nodeName("node-c")},
+		},
+		Ports: ports(
+			port("", 8080),
+		),
+	}))
+
+	// Assert that endpoints on nodes of unknown locality are grouped together.
+	want := []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple",
+			envoy.SocketAddress("10.0.0.1", 8080),
+			envoy.SocketAddress("10.0.0.2", 8080),
+			envoy.SocketAddress("10.0.0.3", 8080),
+		),
+	}
+	assert.Equal(t, want, et.Contents())
+
+	nodeA := node("node-a", "us-east-1", "us-east-1a")
+	et.OnAdd(nodeA)
+	et.OnAdd(node("node-b", "us-east-1", "us-east-1b"))
+
+	// Assert that endpoints are grouped by the locality of their node.
+	want = []proto.Message{
+		&v2.ClusterLoadAssignment{
+			ClusterName: "default/simple",
+			Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
+				LbEndpoints: lbendpoints(envoy.SocketAddress("10.0.0.3", 8080)),
+			}, {
+				Locality: &envoy_api_v2_core.Locality{
+					Region: "us-east-1",
+					Zone:   "us-east-1a",
+				},
+				LbEndpoints: lbendpoints(envoy.SocketAddress("10.0.0.1", 8080)),
+			}, {
+				Locality: &envoy_api_v2_core.Locality{
+					Region: "us-east-1",
+					Zone:   "us-east-1b",
+				},
+				LbEndpoints: lbendpoints(envoy.SocketAddress("10.0.0.2", 8080)),
+			}},
+		},
+	}
+	assert.Equal(t, want, et.Contents())
+
+	et.OnDelete(nodeA)
+
+	// Assert that endpoints on a deleted node lose their locality.
+	want = []proto.Message{
+		&v2.ClusterLoadAssignment{
+			ClusterName: "default/simple",
+			Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
+				LbEndpoints: lbendpoints(
+					envoy.SocketAddress("10.0.0.1", 8080),
+					envoy.SocketAddress("10.0.0.3", 8080),
+				),
+			}, {
+				Locality: &envoy_api_v2_core.Locality{
+					Region: "us-east-1",
+					Zone:   "us-east-1b",
+				},
+				LbEndpoints: lbendpoints(envoy.SocketAddress("10.0.0.2", 8080)),
+			}},
+		},
+	}
+	assert.Equal(t, want, et.Contents())
+}
+
+func TestEndpointsTranslatorNotReadyAddresses(t *testing.T) {
+	et := &EndpointsTranslator{
+		FieldLogger: testLogger(t),
+	}
+
+	et.OnAdd(&v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: "default",
+		},
+		Subsets: []v1.EndpointSubset{{
+			Addresses:         addresses("10.0.0.1"),
+			NotReadyAddresses: addresses("10.0.0.3", "10.0.0.2"),
+			Ports: ports(
+				port("a", 8080),
+			),
+		}, {
+			NotReadyAddresses: addresses("10.0.0.4"),
+			Ports: ports(
+				port("b", 8081),
+			),
+		}},
+	})
+
+	// Assert that not ready addresses are not published by default.
+	want := []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple/a", envoy.SocketAddress("10.0.0.1", 8080)),
+	}
+	assert.Equal(t, want, et.Contents())
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"projectcontour.io/not-ready-endpoints": "draining",
+			},
+		},
+	}
+	et.OnAdd(svc)
+
+	// Assert that not ready addresses are published after the
+	// ready addresses, with the health status of the annotation.
+	draining := func(addr *envoy_api_v2_core.Address) *envoy_api_v2_endpoint.LbEndpoint {
+		lbendpoint := envoy.LBEndpoint(addr)
+		lbendpoint.HealthStatus = envoy_api_v2_core.HealthStatus_DRAINING
+		return lbendpoint
+	}
+	want = []proto.Message{
+		&v2.ClusterLoadAssignment{
+			ClusterName: "default/simple/a",
+			Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
+				LbEndpoints: []*envoy_api_v2_endpoint.LbEndpoint{
+					envoy.LBEndpoint(envoy.SocketAddress("10.0.0.1", 8080)),
+					draining(envoy.SocketAddress("10.0.0.2", 8080)),
+					draining(envoy.SocketAddress("10.0.0.3", 8080)),
+				},
+			}},
+		},
+		&v2.ClusterLoadAssignment{
+			ClusterName: "default/simple/b",
+			Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
+				LbEndpoints: []*envoy_api_v2_endpoint.LbEndpoint{
+					draining(envoy.SocketAddress("10.0.0.4", 8081)),
+				},
+			}},
+		},
+	}
+	assert.Equal(t, want, et.Contents())
+
+	et.OnDelete(svc)
+
+	// Assert that the not ready addresses, and the ports
+	// with only not ready addresses, are removed.
+	want = []proto.Message{
+		envoy.ClusterLoadAssignment("default/simple/a", envoy.SocketAddress("10.0.0.1", 8080)),
+	}
+	assert.Equal(t, want, et.Contents())
+}
+
+func TestEndpointsTranslatorExternalBackend(t *testing.T) {
+	et := &EndpointsTranslator{
+		FieldLogger: testLogger(t),
+	}
+
+	eb1 := externalBackend("default", "vms",
+		projectcontour.ExternalBackendEndpoint{Address: "172.16.0.1", Port: 8080},
+		projectcontour.ExternalBackendEndpoint{Address: "172.16.0.2", Port: 8080},
+		projectcontour.ExternalBackendEndpoint{Address: "172.16.0.2", Port: 9090},
+		projectcontour.ExternalBackendEndpoint{Address: "vm.example.com", Port: 8080},
+	)
+	et.OnAdd(eb1)
+
+	// Assert that the endpoints are published per port, without
+	// the addresses that aren't IP addresses.
+	want := []proto.Message{
+		envoy.ClusterLoadAssignment("default/vms/8080",
+			envoy.SocketAddress("172.16.0.1", 8080),
+			envoy.SocketAddress("172.16.0.2", 8080),
+		),
+		envoy.ClusterLoadAssignment("default/vms/9090", envoy.SocketAddress("172.16.0.2", 9090)),
+	}
+	assert.Equal(t, want, et.Contents())
+
+	eb2 := externalBackend("default", "vms",
+		projectcontour.ExternalBackendEndpoint{Address: "172.16.0.1", Port: 8080},
+	)
+	eb2.Spec.Locality = &projectcontour.Locality{
+		Region: "us-east-1",
+		Zone:   "us-east-1a",
+	}
+	et.OnUpdate(eb1, eb2)
+
+	// Assert that the ports no longer present are removed,
+	// and that the endpoints have the backend's locality.
+	want = []proto.Message{
+		&v2.ClusterLoadAssignment{
+			ClusterName: "default/vms/8080",
+			Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
+				Locality: &envoy_api_v2_core.Locality{
+					Region: "us-east-1",
+					Zone:   "us-east-1a",
+				},
+				LbEndpoints: lbendpoints(envoy.SocketAddress("172.16.0.1", 8080)),
+			}},
+		},
+	}
+	assert.Equal(t, want, et.Contents())
+
+	et.OnDelete(eb2)
+
+	// Assert that deleting the backend removes its endpoints.
+	want = nil
+	assert.Equal(t, want, et.Contents())
+}
+
+func externalBackend(ns, name string, endpoints ...projectcontour.ExternalBackendEndpoint) *projectcontour.ExternalBackend {
+	return &projectcontour.ExternalBackend{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Spec: projectcontour.ExternalBackendSpec{
+			Endpoints: endpoints,
+		},
+	}
+}
+
+func lbendpoints(addrs ...*envoy_api_v2_core.Address) []*envoy_api_v2_endpoint.LbEndpoint {
+	var lbendpoints []*envoy_api_v2_endpoint.LbEndpoint
+	for _, addr := range addrs {
+		lbendpoints = append(lbendpoints, envoy.LBEndpoint(addr))
+	}
+	return lbendpoints
+}
+
+func node(name, region, zone string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				v1.LabelZoneRegionStable:        region,
+				v1.LabelZoneFailureDomainStable: zone,
+			},
+		},
+	}
+}
+
 func ports(eps ...v1.EndpointPort) []v1.EndpointPort {
 	return eps
 }
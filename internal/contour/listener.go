@@ -14,6 +14,7 @@
 package contour
 
 import (
+	"fmt"
 	"path"
 	"sort"
 	"sync"
@@ -23,6 +24,7 @@ import (
 	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
 	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	envoy_api_v2_accesslog "github.com/envoyproxy/go-control-plane/envoy/config/filter/accesslog/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
 	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
 	"github.com/golang/protobuf/proto"
 	"github.com/projectcontour/contour/internal/dag"
@@ -108,6 +110,15 @@ type ListenerVisitorConfig struct {
 	// MaxConnectionDuration configures the common_http_protocol_options.max_connection_duration for all
 	// Connection Managers.
 	MaxConnectionDuration time.Duration
+
+	// Tracing configures the tracing of requests for all Connection Managers.
+	// If nil, requests are not traced.
+	Tracing *envoy.TracingConfig
+
+	// ClientAddress configures how all Connection Managers derive
+	// the client address from X-Forwarded-For. If nil, the client
+	// address is the peer address.
+	ClientAddress *dag.ClientAddressPolicy
 }
 
 // httpAddress returns the port for the HTTP (non TLS)
@@ -250,6 +261,42 @@ func (lvc *ListenerVisitorConfig) maxConnectionDuration() time.Duration {
 
 // minTLSVersion returns the requested minimum TLS protocol
 // version or envoy_api_v2_auth.TlsParameters_TLSv1_1 if not configured.
+// skipXFFAppend returns whether the Connection Managers should
+// not append the peer address to X-Forwarded-For.
+func (lvc *ListenerVisitorConfig) skipXFFAppend() bool {
+	return lvc.ClientAddress != nil && lvc.ClientAddress.SkipXFFAppend
+}
+
+// httpFilterChains returns the supplied filter chain with the
+// Connection Manager that cm returns for the number of trusted
+// X-Forwarded-For hops. If only peers in some ranges are trusted,
+// it is preceded by a copy that matches those peers and trusts
+// X-Forwarded-For, and the supplied filter chain trusts no hops.
+func (lvc *ListenerVisitorConfig) httpFilterChains(fc *envoy_api_v2_listener.FilterChain, cm func(hops uint32) *envoy_api_v2_listener.Filter) []*envoy_api_v2_listener.FilterChain {
+	ca := lvc.ClientAddress
+	if ca == nil || len(ca.TrustedCIDRs) == 0 {
+		var hops uint32
+		if ca != nil {
+			hops = ca.NumTrustedHops
+		}
+		fc.Filters = envoy.Filters(cm(hops))
+		return []*envoy_api_v2_listener.FilterChain{fc}
+	}
+
+	trusted := proto.Clone(fc).(*envoy_api_v2_listener.FilterChain)
+	if trusted.FilterChainMatch == nil {
+		trusted.FilterChainMatch = &envoy_api_v2_listener.FilterChainMatch{}
+	}
+	trusted.FilterChainMatch.SourcePrefixRanges = envoy.SourcePrefixRanges(ca.TrustedCIDRs)
+	if trusted.Name != "" {
+		trusted.Name += "-trusted"
+	}
+	trusted.Filters = envoy.Filters(cm(ca.NumTrustedHops))
+
+	fc.Filters = envoy.Filters(cm(0))
+	return []*envoy_api_v2_listener.FilterChain{trusted, fc}
+}
+
 func (lvc *ListenerVisitorConfig) minTLSVersion() envoy_api_v2_auth.TlsParameters_TlsProtocol {
 	if lvc.MinimumTLSVersion > envoy_api_v2_auth.TlsParameters_TLSv1_1 {
 		return lvc.MinimumTLSVersion
@@ -329,13 +376,19 @@ func (*ListenerCache) TypeURL() string { return resource.ListenerType }
 type listenerVisitor struct {
 	*ListenerVisitorConfig
 
-	listeners map[string]*v2.Listener
-	http      bool // at least one dag.VirtualHost encountered
+	listeners   map[string]*v2.Listener
+	http        bool        // at least one dag.VirtualHost encountered
+	httpFilters httpFilters // filters required by dag.VirtualHost routes
+
+	fallback        *dag.SecureVirtualHost // first dag.SecureVirtualHost with a fallback certificate
+	fallbackALPN    []string               // ALPN protocols of the fallback certificate filter chain
+	fallbackFilters httpFilters            // filters required by the routes of fallback certificate vhosts
 }
 
 func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*v2.Listener {
 	lv := listenerVisitor{
 		ListenerVisitorConfig: lvc,
+		fallbackFilters:       httpFilters{secure: true},
 		listeners: map[string]*v2.Listener{
 			ENVOY_HTTPS_LISTENER: envoy.Listener(
 				ENVOY_HTTPS_LISTENER,
@@ -360,15 +413,28 @@ func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*v2.
 			ConnectionIdleTimeout(lvc.connectionIdleTimeout()).
 			StreamIdleTimeout(lvc.streamIdleTimeout()).
 			MaxConnectionDuration(lvc.maxConnectionDuration()).
-			Get()
+			Tracing(lvc.Tracing).
+			SkipXFFAppend(lvc.skipXFFAppend())
+		for _, filter := range lv.httpFilters.filters() {
+			cm.AddFilter(filter)
+		}
 
-		lv.listeners[ENVOY_HTTP_LISTENER] = envoy.Listener(
+		listener := envoy.Listener(
 			ENVOY_HTTP_LISTENER,
 			lvc.httpAddress(),
 			lvc.httpPort(),
 			proxyProtocol(lvc.UseProxyProto),
-			cm,
 		)
+		listener.FilterChains = lvc.httpFilterChains(&envoy_api_v2_listener.FilterChain{},
+			func(hops uint32) *envoy_api_v2_listener.Filter {
+				return cm.NumTrustedHops(hops).Get()
+			})
+		lv.listeners[ENVOY_HTTP_LISTENER] = listener
+	}
+
+	if lv.fallback != nil {
+		lv.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(lv.listeners[ENVOY_HTTPS_LISTENER].FilterChains,
+			lv.fallbackFilterChains()...)
 	}
 
 	// Remove the https listener if there are no vhosts bound to it.
@@ -383,6 +449,71 @@ func visitListeners(root dag.Vertex, lvc *ListenerVisitorConfig) map[string]*v2.
 	return lv.listeners
 }
 
+// httpFilters records the optional HTTP filters that the
+// routes of one or more virtual hosts require.
+type httpFilters struct {
+	secure    bool                   // the virtual hosts are secure, so routes requiring HTTPS are not redirected
+	rbac      bool                   // at least one route filters requests by address
+	jwtHosts  []*dag.VirtualHost     // virtual hosts with routes that verify JWTs
+	basicAuth []*dag.BasicAuthPolicy // policies of the routes that require basic authentication
+	lua       bool                   // at least one route forwards JWT claims
+	fault     bool                   // at least one route injects faults
+}
+
+// add records the filters required by the routes of the
+// supplied virtual host.
+func (f *httpFilters) add(vh *dag.VirtualHost) {
+	jwt := false
+	vh.Visit(func(v dag.Vertex) {
+		r, ok := v.(*dag.Route)
+		if !ok || (r.HTTPSUpgrade && !f.secure) {
+			return
+		}
+		f.rbac = f.rbac || len(r.IPFilterRules) > 0
+		if r.BasicAuthPolicy != nil {
+			f.basicAuth = append(f.basicAuth, r.BasicAuthPolicy)
+		}
+		f.fault = f.fault || r.FaultPolicy != nil
+		if p := r.JWTProvider; p != nil {
+			jwt = true
+			f.lua = f.lua || len(p.ClaimsToHeaders) > 0
+		}
+	})
+	if jwt {
+		f.jwtHosts = append(f.jwtHosts, vh)
+	}
+}
+
+// filters returns the recorded filters in the order Envoy
+// should apply them. Requests are authorized and authenticated
+// before any faults are injected, and JWTs are verified before
+// their claims are forwarded.
+func (f *httpFilters) filters() []*http.HttpFilter {
+	var filters []*http.HttpFilter
+	if f.rbac {
+		filters = append(filters, envoy.FilterRBAC())
+	}
+	if len(f.jwtHosts) > 0 {
+		filters = append(filters, envoy.FilterJWTAuthn(f.secure, f.jwtHosts...))
+	}
+	if len(f.basicAuth) > 0 {
+		filters = append(filters, envoy.FilterBasicAuth(f.basicAuth...))
+	}
+	if f.lua {
+		filters = append(filters, envoy.FilterLua())
+	}
+	if f.fault {
+		filters = append(filters, envoy.FilterFault())
+	}
+	return filters
+}
+
+// tcpListenerName returns the name of the plain TCP listener
+// on the supplied port.
+func tcpListenerName(port int) string {
+	return fmt.Sprintf("ingress_tcp_%d", port)
+}
+
 func proxyProtocol(useProxy bool) []*envoy_api_v2_listener.ListenerFilter {
 	if useProxy {
 		return envoy.ListenerFilters(
@@ -396,6 +527,45 @@ func secureProxyProtocol(useProxy bool) []*envoy_api_v2_listener.ListenerFilter
 	return append(proxyProtocol(useProxy), envoy.TLSInspector())
 }
 
+// fallbackFilterChains returns the default FilterChains which allow
+// the routes of the virtual hosts that enabled the fallback certificate
+// to accept non-SNI TLS requests. Note that we don't add the misdirected
+// requests filter on this chain because the full set of server names
+// bound to the filter chain through the ENVOY_FALLBACK_ROUTECONFIG route
+// configuration can't be matched by a single filter. The chain has the
+// HTTP filters of all of those virtual hosts, so that their routes'
+// policies are enforced without SNI.
+func (v *listenerVisitor) fallbackFilterChains() []*envoy_api_v2_listener.FilterChain {
+	// Construct the downstreamTLSContext passing the configured fallbackCertificate. The TLS minProtocolVersion will use
+	// the value defined in the Contour Configuration file if defined.
+	downstreamTLS := envoy.DownstreamTLSContext(
+		v.fallback.FallbackCertificate,
+		v.ListenerVisitorConfig.minTLSVersion(),
+		v.fallback.DownstreamValidation,
+		v.fallbackALPN...)
+
+	// Default filter chain
+	cm := envoy.HTTPConnectionManagerBuilder().
+		DefaultFilters().
+		RouteConfigName(ENVOY_FALLBACK_ROUTECONFIG).
+		MetricsPrefix(ENVOY_HTTPS_LISTENER).
+		AccessLoggers(v.ListenerVisitorConfig.newSecureAccessLog()).
+		RequestTimeout(v.ListenerVisitorConfig.requestTimeout()).
+		ConnectionIdleTimeout(v.ListenerVisitorConfig.connectionIdleTimeout()).
+		StreamIdleTimeout(v.ListenerVisitorConfig.streamIdleTimeout()).
+		MaxConnectionDuration(v.ListenerVisitorConfig.maxConnectionDuration()).
+		Tracing(v.ListenerVisitorConfig.Tracing).
+		SkipXFFAppend(v.ListenerVisitorConfig.skipXFFAppend())
+	for _, filter := range v.fallbackFilters.filters() {
+		cm.AddFilter(filter)
+	}
+
+	return v.ListenerVisitorConfig.httpFilterChains(envoy.FilterChainTLSFallback(downstreamTLS, nil),
+		func(hops uint32) *envoy_api_v2_listener.Filter {
+			return cm.NumTrustedHops(hops).Get()
+		})
+}
+
 func (v *listenerVisitor) visit(vertex dag.Vertex) {
 	max := func(a, b envoy_api_v2_auth.TlsParameters_TlsProtocol) envoy_api_v2_auth.TlsParameters_TlsProtocol {
 		if a > b {
@@ -405,14 +575,30 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 	}
 
 	switch vh := vertex.(type) {
+	case *dag.Listener:
+		// Plain TCP listeners proxy every connection, and
+		// the HTTP and HTTPS listeners have virtual hosts.
+		if vh.TCPProxy != nil {
+			name := tcpListenerName(vh.Port)
+			v.listeners[name] = envoy.Listener(
+				name,
+				v.ListenerVisitorConfig.httpAddress(),
+				vh.Port,
+				proxyProtocol(v.UseProxyProto),
+				envoy.TCPProxy(name, vh.TCPProxy, v.ListenerVisitorConfig.newInsecureAccessLog()),
+			)
+		}
+		vertex.Visit(v.visit)
 	case *dag.VirtualHost:
 		// we only create on http listener so record the fact
 		// that we need to then double back at the end and add
 		// the listener properly.
 		v.http = true
+		v.httpFilters.add(vh)
 	case *dag.SecureVirtualHost:
 		var alpnProtos []string
 		var filters []*envoy_api_v2_listener.Filter
+		var connectionManager func(hops uint32) *envoy_api_v2_listener.Filter
 
 		if vh.TCPProxy == nil {
 			// Create a uniquely named HTTP connection manager for
@@ -422,20 +608,28 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 			// metrics prefix to keep compatibility with previous
 			// Contour versions since the metrics prefix will be
 			// coded into monitoring dashboards.
-			filters = envoy.Filters(
-				envoy.HTTPConnectionManagerBuilder().
-					Codec(envoy.CodecForVersions(v.DefaultHTTPVersions...)).
-					AddFilter(envoy.FilterMisdirectedRequests(vh.VirtualHost.Name)).
-					DefaultFilters().
-					RouteConfigName(path.Join("https", vh.VirtualHost.Name)).
-					MetricsPrefix(ENVOY_HTTPS_LISTENER).
-					AccessLoggers(v.ListenerVisitorConfig.newSecureAccessLog()).
-					RequestTimeout(v.ListenerVisitorConfig.requestTimeout()).
-					ConnectionIdleTimeout(v.ListenerVisitorConfig.connectionIdleTimeout()).
-					StreamIdleTimeout(v.ListenerVisitorConfig.streamIdleTimeout()).
-					MaxConnectionDuration(v.ListenerVisitorConfig.maxConnectionDuration()).
-					Get(),
-			)
+			cm := envoy.HTTPConnectionManagerBuilder().
+				Codec(envoy.CodecForVersions(v.DefaultHTTPVersions...)).
+				AddFilter(envoy.FilterMisdirectedRequests(vh.VirtualHost.Name)).
+				DefaultFilters().
+				RouteConfigName(path.Join("https", vh.VirtualHost.Name)).
+				MetricsPrefix(ENVOY_HTTPS_LISTENER).
+				AccessLoggers(v.ListenerVisitorConfig.newSecureAccessLog()).
+				RequestTimeout(v.ListenerVisitorConfig.requestTimeout()).
+				ConnectionIdleTimeout(v.ListenerVisitorConfig.connectionIdleTimeout()).
+				StreamIdleTimeout(v.ListenerVisitorConfig.streamIdleTimeout()).
+				MaxConnectionDuration(v.ListenerVisitorConfig.maxConnectionDuration()).
+				Tracing(v.ListenerVisitorConfig.Tracing).
+				SkipXFFAppend(v.ListenerVisitorConfig.skipXFFAppend())
+			required := httpFilters{secure: true}
+			required.add(&vh.VirtualHost)
+			for _, filter := range required.filters() {
+				cm.AddFilter(filter)
+			}
+
+			connectionManager = func(hops uint32) *envoy_api_v2_listener.Filter {
+				return cm.NumTrustedHops(hops).Get()
+			}
 
 			alpnProtos = envoy.ProtoNamesForVersions(v.DefaultHTTPVersions...)
 		} else {
@@ -464,38 +658,24 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 				alpnProtos...)
 		}
 
+		filterChains := []*envoy_api_v2_listener.FilterChain{
+			envoy.FilterChainTLS(vh.VirtualHost.Name, downstreamTLS, filters),
+		}
+		if connectionManager != nil {
+			filterChains = v.ListenerVisitorConfig.httpFilterChains(filterChains[0], connectionManager)
+		}
 		v.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains,
-			envoy.FilterChainTLS(vh.VirtualHost.Name, downstreamTLS, filters))
-
-		// If this VirtualHost has enabled the fallback certificate then set a default
-		// FilterChain which will allow routes with this vhost to accept non-SNI TLS requests.
-		// Note that we don't add the misdirected requests filter on this chain because at this
-		// point we don't actually know the full set of server names that will be bound to the
-		// filter chain through the ENVOY_FALLBACK_ROUTECONFIG route configuration.
-		if vh.FallbackCertificate != nil && !envoy.ContainsFallbackFilterChain(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains) {
-			// Construct the downstreamTLSContext passing the configured fallbackCertificate. The TLS minProtocolVersion will use
-			// the value defined in the Contour Configuration file if defined.
-			downstreamTLS = envoy.DownstreamTLSContext(
-				vh.FallbackCertificate,
-				v.ListenerVisitorConfig.minTLSVersion(),
-				vh.DownstreamValidation,
-				alpnProtos...)
-
-			// Default filter chain
-			filters = envoy.Filters(
-				envoy.HTTPConnectionManagerBuilder().
-					RouteConfigName(ENVOY_FALLBACK_ROUTECONFIG).
-					MetricsPrefix(ENVOY_HTTPS_LISTENER).
-					AccessLoggers(v.ListenerVisitorConfig.newSecureAccessLog()).
-					RequestTimeout(v.ListenerVisitorConfig.requestTimeout()).
-					ConnectionIdleTimeout(v.ListenerVisitorConfig.connectionIdleTimeout()).
-					StreamIdleTimeout(v.ListenerVisitorConfig.streamIdleTimeout()).
-					MaxConnectionDuration(v.ListenerVisitorConfig.maxConnectionDuration()).
-					Get(),
-			)
-
-			v.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains,
-				envoy.FilterChainTLSFallback(downstreamTLS, filters))
+			filterChains...)
+
+		// If this VirtualHost has enabled the fallback certificate then it is also
+		// served by the default FilterChain, which is added once all of the
+		// virtual hosts that share it are known.
+		if vh.FallbackCertificate != nil {
+			if v.fallback == nil {
+				v.fallback = vh
+				v.fallbackALPN = alpnProtos
+			}
+			v.fallbackFilters.add(&vh.VirtualHost)
 		}
 
 	default:
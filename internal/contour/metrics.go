@@ -61,6 +61,7 @@ func calculateRouteMetric(statuses map[k8s.FullName]dag.Status) metrics.RouteMet
 	proxyMetricInvalid := make(map[metrics.Meta]int)
 	proxyMetricOrphaned := make(map[metrics.Meta]int)
 	proxyMetricRoots := make(map[metrics.Meta]int)
+	proxyMetricFQDNPolicyViolations := make(map[metrics.Meta]int)
 
 	for _, v := range statuses {
 		switch o := v.Object.(type) {
@@ -69,6 +70,9 @@ func calculateRouteMetric(statuses map[k8s.FullName]dag.Status) metrics.RouteMet
 			if o.Spec.VirtualHost != nil {
 				proxyMetricRoots[metrics.Meta{Namespace: v.Object.GetObjectMeta().GetNamespace()}]++
 			}
+			if v.Reason == dag.ReasonFQDNPolicyViolation {
+				proxyMetricFQDNPolicyViolations[metrics.Meta{VHost: v.Vhost, Namespace: v.Object.GetObjectMeta().GetNamespace()}]++
+			}
 		}
 	}
 
@@ -78,6 +82,8 @@ func calculateRouteMetric(statuses map[k8s.FullName]dag.Status) metrics.RouteMet
 		Orphaned: proxyMetricOrphaned,
 		Total:    proxyMetricTotal,
 		Root:     proxyMetricRoots,
+
+		FQDNPolicyViolation: proxyMetricFQDNPolicyViolations,
 	}
 }
 
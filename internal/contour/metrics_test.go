@@ -376,6 +376,7 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "roots"}: 1,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
 		},
 	})
 
@@ -394,6 +395,7 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "roots"}: 1,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
 		},
 	})
 
@@ -412,6 +414,7 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "finance"}: 1,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
 		},
 		rootNamespaces: []string{"foo"},
 	})
@@ -431,6 +434,7 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "roots"}: 1,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
 		},
 	})
 
@@ -449,6 +453,7 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "roots"}: 1,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
 		},
 	})
 
@@ -469,6 +474,7 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "roots"}: 2,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
 		},
 	})
 
@@ -485,6 +491,7 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "roots"}: 1,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
 		},
 	})
 
@@ -506,6 +513,7 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "roots"}: 3,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
 		},
 	})
 
@@ -526,6 +534,38 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "roots"}: 2,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
+		},
+	})
+
+	run(t, "root not permitted by fqdnpolicy - proxy", testcase{
+		objs: []interface{}{proxy1, s3, &projcontour.FQDNPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "domains",
+			},
+			Spec: projcontour.FQDNPolicySpec{
+				Rules: []projcontour.FQDNRule{{
+					Fqdn:       "example.com",
+					Namespaces: []string{"marketing"},
+				}},
+			},
+		}},
+		wantIR: nil,
+		wantProxy: &metrics.RouteMetric{
+			Invalid: map[metrics.Meta]int{
+				{Namespace: "roots", VHost: "example.com"}: 1,
+			},
+			Valid:    map[metrics.Meta]int{},
+			Orphaned: map[metrics.Meta]int{},
+			Root: map[metrics.Meta]int{
+				{Namespace: "roots"}: 1,
+			},
+			Total: map[metrics.Meta]int{
+				{Namespace: "roots"}: 1,
+			},
+			FQDNPolicyViolation: map[metrics.Meta]int{
+				{Namespace: "roots", VHost: "example.com"}: 1,
+			},
 		},
 	})
 
@@ -547,6 +587,7 @@ func TestHTTPProxyMetrics(t *testing.T) {
 			Total: map[metrics.Meta]int{
 				{Namespace: "roots"}: 3,
 			},
+			FQDNPolicyViolation: map[metrics.Meta]int{},
 		},
 	})
 }
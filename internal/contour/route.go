@@ -16,6 +16,7 @@ package contour
 import (
 	"path"
 	"sort"
+	"strings"
 	"sync"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
@@ -89,6 +90,9 @@ func (*RouteCache) TypeURL() string { return resource.RouteType }
 
 type routeVisitor struct {
 	routes map[string]*v2.RouteConfiguration
+
+	// secure holds the names of the visited secure virtual hosts.
+	secure []string
 }
 
 func visitRoutes(root dag.Vertex) map[string]*v2.RouteConfiguration {
@@ -104,6 +108,7 @@ func visitRoutes(root dag.Vertex) map[string]*v2.RouteConfiguration {
 	}
 
 	rv.visit(root)
+	rv.misdirectWildcards()
 
 	for _, v := range rv.routes {
 		sort.Stable(sorter.For(v.VirtualHosts))
@@ -131,15 +136,19 @@ func (v *routeVisitor) onVirtualHost(vh *dag.VirtualHost) {
 			})
 		} else {
 			rt := &envoy_api_v2_route.Route{
-				Match:  envoy.RouteMatch(route),
-				Action: envoy.RouteRoute(route),
+				Match:                envoy.RouteMatch(route),
+				Action:               envoy.RouteRoute(route),
+				Tracing:              envoy.RouteTracing(route),
+				TypedPerFilterConfig: envoy.TypedPerFilterConfig(route),
+				Metadata:             envoy.RouteMetadata(route),
 			}
 			if route.RequestHeadersPolicy != nil {
 				rt.RequestHeadersToAdd = envoy.HeaderValueList(route.RequestHeadersPolicy.Set, false)
 				rt.RequestHeadersToRemove = route.RequestHeadersPolicy.Remove
 			}
 			if route.ResponseHeadersPolicy != nil {
-				rt.ResponseHeadersToAdd = envoy.HeaderValueList(route.ResponseHeadersPolicy.Set, false)
+				rt.ResponseHeadersToAdd = append(envoy.HeaderValueList(route.ResponseHeadersPolicy.Set, false),
+					envoy.HeaderValueList(route.ResponseHeadersPolicy.Add, true)...)
 				rt.ResponseHeadersToRemove = route.ResponseHeadersPolicy.Remove
 			}
 			routes = append(routes, rt)
@@ -157,6 +166,8 @@ func (v *routeVisitor) onVirtualHost(vh *dag.VirtualHost) {
 func (v *routeVisitor) onSecureVirtualHost(svh *dag.SecureVirtualHost) {
 	var routes []*envoy_api_v2_route.Route
 
+	v.secure = append(v.secure, svh.VirtualHost.Name)
+
 	svh.Visit(func(v dag.Vertex) {
 		route, ok := v.(*dag.Route)
 		if !ok {
@@ -164,15 +175,20 @@ func (v *routeVisitor) onSecureVirtualHost(svh *dag.SecureVirtualHost) {
 		}
 
 		rt := &envoy_api_v2_route.Route{
-			Match:  envoy.RouteMatch(route),
-			Action: envoy.RouteRoute(route),
+			Match:                envoy.RouteMatch(route),
+			Action:               envoy.RouteRoute(route),
+			Tracing:              envoy.RouteTracing(route),
+			TypedPerFilterConfig: envoy.TypedPerFilterConfig(route),
+			Metadata:             envoy.RouteMetadata(route),
 		}
 		if route.RequestHeadersPolicy != nil {
 			rt.RequestHeadersToAdd = envoy.HeaderValueList(route.RequestHeadersPolicy.Set, false)
 			rt.RequestHeadersToRemove = route.RequestHeadersPolicy.Remove
 		}
+		rt.ResponseHeadersToAdd = envoy.HeaderValueList(secureResponseHeaders(svh.SecurityHeaders, route.ResponseHeadersPolicy), false)
 		if route.ResponseHeadersPolicy != nil {
-			rt.ResponseHeadersToAdd = envoy.HeaderValueList(route.ResponseHeadersPolicy.Set, false)
+			rt.ResponseHeadersToAdd = append(rt.ResponseHeadersToAdd,
+				envoy.HeaderValueList(route.ResponseHeadersPolicy.Add, true)...)
 			rt.ResponseHeadersToRemove = route.ResponseHeadersPolicy.Remove
 		}
 		routes = append(routes, rt)
@@ -205,6 +221,56 @@ func (v *routeVisitor) onSecureVirtualHost(svh *dag.SecureVirtualHost) {
 	}
 }
 
+// misdirectWildcards gives exact names precedence over wildcards.
+// The SNI of a connection selects the wildcard's route configuration
+// only if no more specific name matches, but a client may go on to
+// send requests for any name the certificate covers. The wildcard's
+// route configuration rejects the names that have their own secure
+// virtual host as misdirected, so the client retries on a connection
+// with the matching SNI.
+func (v *routeVisitor) misdirectWildcards() {
+	for _, wildcard := range v.secure {
+		rc, ok := v.routes[path.Join("https", wildcard)]
+		if !ok || !strings.HasPrefix(wildcard, "*.") {
+			continue
+		}
+
+		suffix := wildcard[1:]
+		for _, name := range v.secure {
+			if name == wildcard || len(name) <= len(suffix) || !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			rc.VirtualHosts = append(rc.VirtualHosts,
+				envoy.VirtualHost(name, &envoy_api_v2_route.Route{
+					Match:  envoy.RouteMatch(&dag.Route{PathCondition: &dag.PrefixCondition{Prefix: "/"}}),
+					Action: envoy.MisdirectedRequest(),
+				}))
+		}
+	}
+}
+
+// secureResponseHeaders returns the response headers to add to a route
+// of a secure virtual host. Headers set by the route take precedence over
+// the security headers, and security headers the route removes are not
+// added.
+func secureResponseHeaders(sh *dag.SecurityHeadersPolicy, hp *dag.HeadersPolicy) map[string]string {
+	headers := envoy.SecurityHeaders(sh)
+	if hp == nil {
+		return headers
+	}
+
+	for _, k := range hp.Remove {
+		delete(headers, k)
+	}
+	if len(headers) == 0 {
+		return hp.Set
+	}
+	for k, v := range hp.Set {
+		headers[k] = v
+	}
+	return headers
+}
+
 func (v *routeVisitor) visit(vertex dag.Vertex) {
 	switch l := vertex.(type) {
 	case *dag.Listener:
@@ -14,19 +14,28 @@
 package dag
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/google/go-cmp/cmp"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/annotation"
+	"github.com/projectcontour/contour/internal/htpasswd"
 	"github.com/projectcontour/contour/internal/k8s"
 )
 
@@ -51,6 +60,30 @@ type Builder struct {
 
 	FallbackCertificate *k8s.FullName
 
+	// WebsocketIdleTimeout is the default idle timeout
+	// applied to websocket routes that do not specify
+	// their own. Zero means no default is applied.
+	WebsocketIdleTimeout time.Duration
+
+	// SecurityHeaders is the default security headers policy
+	// of every SecureVirtualHost. A HTTPProxy's TLS HSTS
+	// setting replaces the policy's HSTS for its vhost.
+	SecurityHeaders *SecurityHeadersPolicy
+
+	// ClientAddress defines how Envoy derives the client
+	// address that Remote IP filter rules match.
+	ClientAddress *ClientAddressPolicy
+
+	// ZoneAwareRouting is the zone aware routing policy
+	// of every Service that isn't an ExternalName.
+	ZoneAwareRouting *ZoneAwareRoutingPolicy
+
+	// TCPPorts are the ports of the plain TCP listeners
+	// that HTTPProxies without a virtual host may claim.
+	TCPPorts []int
+
+	tcplisteners map[int]*Listener
+
 	StatusWriter
 }
 
@@ -78,6 +111,7 @@ func (b *Builder) reset() {
 
 	b.virtualhosts = make(map[string]*VirtualHost)
 	b.securevirtualhosts = make(map[string]*SecureVirtualHost)
+	b.tcplisteners = make(map[int]*Listener)
 
 	b.statuses = make(map[k8s.FullName]Status, len(b.statuses))
 }
@@ -117,6 +151,58 @@ func (b *Builder) lookupService(m k8s.FullName, port intstr.IntOrString) *Servic
 	return nil
 }
 
+// lookupExternalBackend returns a Service for the endpoints, on the supplied
+// port, of the ExternalBackend that matches the Meta, or an error if the
+// ExternalBackend is missing, invalid, or has no endpoints on the port.
+func (b *Builder) lookupExternalBackend(m k8s.FullName, port int) (*Service, error) {
+	sm := servicemeta{
+		name:      m.Name,
+		namespace: m.Namespace,
+		port:      int32(port),
+		kind:      "ExternalBackend",
+	}
+	if s, ok := b.services[sm]; ok {
+		return s, nil
+	}
+
+	eb, ok := b.Source.externalbackends[m]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+
+	found := false
+	for _, ep := range eb.Spec.Endpoints {
+		// endpoints are published by EDS, which can't resolve names.
+		if net.ParseIP(ep.Address) == nil {
+			return nil, fmt.Errorf("endpoint address %q is not an IP address", ep.Address)
+		}
+		found = found || ep.Port == port
+	}
+	if !found {
+		return nil, fmt.Errorf("no endpoints on port %d", port)
+	}
+
+	s := &Service{
+		Name:      eb.Name,
+		Namespace: eb.Namespace,
+		// the EDS name of the endpoints on a port is
+		// the port number, which can't be a port name.
+		ServicePort: &v1.ServicePort{
+			Name:     strconv.Itoa(port),
+			Protocol: v1.ProtocolTCP,
+			Port:     int32(port),
+		},
+		Kind:                  "ExternalBackend",
+		HTTPHealthCheckPolicy: httpHealthCheckPolicy(eb.Spec.HealthCheckPolicy),
+		ZoneAwareRouting:      b.ZoneAwareRouting,
+	}
+	if eb.Spec.Protocol != nil {
+		s.AppProtocol = *eb.Spec.Protocol
+	}
+	b.services[s.ToFullName()] = s
+	return s, nil
+}
+
 func (b *Builder) addService(svc *v1.Service, port *v1.ServicePort) *Service {
 	s := &Service{
 		Name:        svc.Name,
@@ -124,12 +210,19 @@ func (b *Builder) addService(svc *v1.Service, port *v1.ServicePort) *Service {
 		ServicePort: port,
 
 		Protocol:           upstreamProtocol(svc, port),
+		AppProtocol:        appProtocol(port),
 		MaxConnections:     annotation.MaxConnections(svc),
 		MaxPendingRequests: annotation.MaxPendingRequests(svc),
 		MaxRequests:        annotation.MaxRequests(svc),
 		MaxRetries:         annotation.MaxRetries(svc),
 		ExternalName:       externalName(svc),
 	}
+	if s.ExternalName == "" {
+		// only the endpoints of discovered services have a locality.
+		s.ZoneAwareRouting = b.ZoneAwareRouting
+	} else {
+		s.DNSPolicy = dnsPolicy(svc)
+	}
 	b.services[s.ToFullName()] = s
 	return s
 }
@@ -143,6 +236,60 @@ func upstreamProtocol(svc *v1.Service, port *v1.ServicePort) string {
 	return protocol
 }
 
+// appProtocol returns the protocol of the supplied port from
+// its appProtocol, or "" if the appProtocol is not one that
+// Contour recognises.
+func appProtocol(port *v1.ServicePort) string {
+	if port.AppProtocol == nil {
+		return ""
+	}
+	switch strings.ToLower(*port.AppProtocol) {
+	case "h2c", "kubernetes.io/h2c", "grpc":
+		return "h2c"
+	case "h2":
+		return "h2"
+	case "tls", "https":
+		return "tls"
+	default:
+		return ""
+	}
+}
+
+// serviceProtocol returns the protocol used to speak to the supplied
+// service. The upstream-protocol annotations of the Service take
+// precedence over the supplied protocol, from an HTTPProxy, which takes
+// precedence over the appProtocol of the Service's port, or the protocol
+// of the ExternalBackend. The returned error describes the first conflict
+// between the protocols, if any.
+func serviceProtocol(s *Service, protocol string) (string, error) {
+	appProtocol := "appProtocol"
+	if s.Kind == "ExternalBackend" {
+		appProtocol = "ExternalBackend"
+	}
+	sources := []struct {
+		name     string
+		protocol string
+	}{
+		{name: "upstream-protocol annotation", protocol: s.Protocol},
+		{name: "HTTPProxy", protocol: protocol},
+		{name: appProtocol, protocol: s.AppProtocol},
+	}
+
+	var selected, from string
+	var err error
+	for _, source := range sources {
+		switch {
+		case source.protocol == "":
+			continue
+		case selected == "":
+			selected, from = source.protocol, source.name
+		case source.protocol != selected && err == nil:
+			err = fmt.Errorf("protocol %q from %s overrides protocol %q from %s", selected, from, source.protocol, source.name)
+		}
+	}
+	return selected, err
+}
+
 // lookupSecret returns a Secret if present or nil if the underlying kubernetes
 // secret fails validation or is missing.
 func (b *Builder) lookupSecret(m k8s.FullName, validate func(*v1.Secret) error) (*Secret, error) {
@@ -182,6 +329,7 @@ func (b *Builder) lookupSecureVirtualHost(name string) *SecureVirtualHost {
 			VirtualHost: VirtualHost{
 				Name: name,
 			},
+			SecurityHeaders: b.SecurityHeaders,
 		}
 		b.securevirtualhosts[svh.VirtualHost.Name] = svh
 		return svh
@@ -195,37 +343,125 @@ func (b *Builder) lookupSecureVirtualHost(name string) *SecureVirtualHost {
 func (b *Builder) validHTTPProxies() []*projcontour.HTTPProxy {
 	// ensure that a given fqdn is only referenced in a single HTTPProxy resource
 	var valid []*projcontour.HTTPProxy
+	var roots []*projcontour.HTTPProxy
 	fqdnHTTPProxies := make(map[string][]*projcontour.HTTPProxy)
+	portHTTPProxies := make(map[int][]*projcontour.HTTPProxy)
 	for _, proxy := range b.Source.httpproxies {
 		if proxy.Spec.VirtualHost == nil {
+			if port := tcpListenerPort(proxy); port != 0 {
+				portHTTPProxies[port] = append(portHTTPProxies[port], proxy)
+				continue
+			}
 			valid = append(valid, proxy)
 			continue
 		}
-		fqdnHTTPProxies[proxy.Spec.VirtualHost.Fqdn] = append(fqdnHTTPProxies[proxy.Spec.VirtualHost.Fqdn], proxy)
+		if !b.fqdnPoliciesPermit(proxy) {
+			// a proxy that may not use its names doesn't
+			// conflict with those that may.
+			continue
+		}
+		roots = append(roots, proxy)
+		for _, fqdn := range virtualHostNames(proxy.Spec.VirtualHost) {
+			fqdnHTTPProxies[fqdn] = append(fqdnHTTPProxies[fqdn], proxy)
+		}
 	}
 
-	for fqdn, proxies := range fqdnHTTPProxies {
-		switch len(proxies) {
-		case 1:
-			valid = append(valid, proxies[0])
-		default:
-			// multiple irs use the same fqdn. mark them as invalid.
-			var conflicting []string
-			for _, proxy := range proxies {
-				conflicting = append(conflicting, proxy.Namespace+"/"+proxy.Name)
-			}
-			sort.Strings(conflicting) // sort for test stability
-			msg := fmt.Sprintf("fqdn %q is used in multiple HTTPProxies: %s", fqdn, strings.Join(conflicting, ", "))
-			for _, proxy := range proxies {
-				sw, commit := b.WithObject(proxy)
-				sw.WithValue("vhost", fqdn).SetInvalid(msg)
-				commit()
+	// sort the fqdns so that a proxy which conflicts on several
+	// of its names always reports the same one.
+	var fqdns []string
+	for fqdn := range fqdnHTTPProxies {
+		fqdns = append(fqdns, fqdn)
+	}
+	sort.Strings(fqdns)
+
+	invalid := make(map[*projcontour.HTTPProxy]bool)
+	for _, fqdn := range fqdns {
+		proxies := fqdnHTTPProxies[fqdn]
+		if len(proxies) == 1 {
+			continue
+		}
+
+		// multiple irs use the same fqdn. mark them as invalid.
+		var conflicting []string
+		for _, proxy := range proxies {
+			conflicting = append(conflicting, proxy.Namespace+"/"+proxy.Name)
+		}
+		sort.Strings(conflicting) // sort for test stability
+		msg := fmt.Sprintf("fqdn %q is used in multiple HTTPProxies: %s", fqdn, strings.Join(conflicting, ", "))
+		for _, proxy := range proxies {
+			if invalid[proxy] {
+				continue
 			}
+			invalid[proxy] = true
+			sw, commit := b.WithObject(proxy)
+			sw.WithValue("vhost", fqdn).SetInvalid(msg)
+			commit()
+		}
+	}
+
+	// ensure that a given TCP listener port is only claimed
+	// by a single HTTPProxy resource.
+	var ports []int
+	for port := range portHTTPProxies {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	for _, port := range ports {
+		proxies := portHTTPProxies[port]
+		if len(proxies) == 1 {
+			roots = append(roots, proxies[0])
+			continue
+		}
+
+		var conflicting []string
+		for _, proxy := range proxies {
+			conflicting = append(conflicting, proxy.Namespace+"/"+proxy.Name)
+		}
+		sort.Strings(conflicting) // sort for test stability
+		msg := fmt.Sprintf("port %d is claimed by multiple HTTPProxies: %s", port, strings.Join(conflicting, ", "))
+		for _, proxy := range proxies {
+			sw, commit := b.WithObject(proxy)
+			sw.SetInvalid(msg)
+			commit()
+		}
+	}
+
+	for _, proxy := range roots {
+		if !invalid[proxy] {
+			valid = append(valid, proxy)
 		}
 	}
 	return valid
 }
 
+// tcpListenerPort returns the plain TCP listener port claimed
+// by the supplied HTTPProxy, or zero if it claims none.
+func tcpListenerPort(proxy *projcontour.HTTPProxy) int {
+	if proxy.Spec.TCPProxy == nil {
+		return 0
+	}
+	return proxy.Spec.TCPProxy.Port
+}
+
+// virtualHostNames returns the distinct fqdn and aliases of
+// the supplied virtual host.
+func virtualHostNames(vhost *projcontour.VirtualHost) []string {
+	names := []string{vhost.Fqdn}
+	for _, alias := range vhost.Aliases {
+		if !contains(names, alias) {
+			names = append(names, alias)
+		}
+	}
+	return names
+}
+
+// isWildcardName returns true if name is a wildcard domain name
+// of the form "*.example.com".
+func isWildcardName(name string) bool {
+	return strings.HasPrefix(name, "*.") && len(name) > 2 && !strings.Contains(name[2:], "*")
+}
+
 // computeSecureVirtualhosts populates tls parameters of
 // secure virtual hosts.
 func (b *Builder) computeSecureVirtualhosts() {
@@ -328,6 +564,7 @@ func (b *Builder) computeIngressRule(ing *v1beta1.Ingress, rule v1beta1.IngressR
 		}
 
 		r := route(ing, path, s)
+		b.setWebsocketIdleTimeout(r)
 
 		// should we create port 80 routes for this ingress
 		if annotation.TLSRequired(ing) || annotation.HTTPAllowed(ing) {
@@ -355,6 +592,10 @@ func (b *Builder) computeHTTPProxy(proxy *projcontour.HTTPProxy) {
 	defer commit()
 
 	if proxy.Spec.VirtualHost == nil {
+		if tcpListenerPort(proxy) != 0 {
+			b.computeTCPListener(sw, proxy)
+			return
+		}
 		// mark HTTPProxy as orphaned.
 		b.setOrphaned(proxy)
 		return
@@ -372,11 +613,27 @@ func (b *Builder) computeHTTPProxy(proxy *projcontour.HTTPProxy) {
 		return
 	}
 	sw = sw.WithValue("vhost", host)
-	if strings.Contains(host, "*") {
+	if strings.Contains(host, "*") && !isWildcardName(host) {
 		sw.SetInvalid("Spec.VirtualHost.Fqdn %q cannot use wildcards", host)
 		return
 	}
 
+	hosts := []string{host}
+	for _, alias := range proxy.Spec.VirtualHost.Aliases {
+		switch {
+		case isBlank(alias):
+			sw.SetInvalid("Spec.VirtualHost.Aliases cannot contain an empty name")
+			return
+		case strings.Contains(alias, "*") && !isWildcardName(alias):
+			sw.SetInvalid("Spec.VirtualHost.Aliases %q cannot use wildcards", alias)
+			return
+		case contains(hosts, alias):
+			sw.SetInvalid("Spec.VirtualHost.Aliases %q is duplicated", alias)
+			return
+		}
+		hosts = append(hosts, alias)
+	}
+
 	var tlsValid bool
 	if tls := proxy.Spec.VirtualHost.TLS; tls != nil {
 		// tls is valid if passthrough == true XOR secretName != ""
@@ -397,10 +654,32 @@ func (b *Builder) computeHTTPProxy(proxy *projcontour.HTTPProxy) {
 				return
 			}
 
+			// Aliases and wildcards are opt in, so only they
+			// require the certificate to name the vhost.
+			if len(hosts) > 1 || isWildcardName(host) {
+				if err := certificateCovers(sec.Cert(), hosts...); err != nil {
+					sw.SetInvalid("Spec.VirtualHost.TLS Secret %q is invalid: %s", tls.SecretName, err)
+					return
+				}
+			}
+
 			svhost := b.lookupSecureVirtualHost(host)
 			svhost.Secret = sec
 			svhost.MinTLSVersion = annotation.MinTLSVersion(tls.MinimumProtocolVersion)
 
+			if tls.HSTS != nil {
+				hsts, err := hstsPolicy(tls.HSTS)
+				if err != nil {
+					sw.SetInvalid("Spec.VirtualHost.TLS.HSTS: %s", err)
+					return
+				}
+				sh := &SecurityHeadersPolicy{HSTS: hsts}
+				if svhost.SecurityHeaders != nil {
+					sh.Set = svhost.SecurityHeaders.Set
+				}
+				svhost.SecurityHeaders = sh
+			}
+
 			// Check if FallbackCertificate && ClientValidation are both enabled in the same vhost
 			if tls.EnableFallbackCertificate && tls.ClientValidation != nil {
 				sw.SetInvalid("Spec.Virtualhost.TLS fallback & client validation are incompatible together")
@@ -437,6 +716,16 @@ func (b *Builder) computeHTTPProxy(proxy *projcontour.HTTPProxy) {
 				}
 				svhost.DownstreamValidation = dv
 			}
+
+			// Aliases share the TLS properties of the fqdn, so they
+			// are copies of its secure virtual host that keep their
+			// own name and routes.
+			for _, alias := range hosts[1:] {
+				asvhost := b.lookupSecureVirtualHost(alias)
+				vhost := asvhost.VirtualHost
+				*asvhost = *svhost
+				asvhost.VirtualHost = vhost
+			}
 		} else if tls.ClientValidation != nil {
 			sw.SetInvalid("Spec.VirtualHost.TLS passthrough cannot be combined with tls.clientValidation")
 			return
@@ -448,20 +737,100 @@ func (b *Builder) computeHTTPProxy(proxy *projcontour.HTTPProxy) {
 			sw.SetInvalid("tcpproxy: missing tls.passthrough or tls.secretName")
 			return
 		}
-		if !b.processHTTPProxyTCPProxy(sw, proxy, nil, host) {
+		if proxy.Spec.TCPProxy.Port != 0 {
+			sw.SetInvalid("tcpproxy: port cannot be combined with a virtual host")
+			return
+		}
+		tcpproxy, ok := b.processHTTPProxyTCPProxy(sw, proxy, nil, b.lookupSecureVirtualHost(host).Secret == nil)
+		if !ok {
+			return
+		}
+		for _, host := range hosts {
+			b.lookupSecureVirtualHost(host).TCPProxy = tcpproxy
+		}
+	}
+
+	providers, err := b.lookupJWTProviders(proxy)
+	if err != nil {
+		sw.SetInvalid("Spec.VirtualHost.JWTProviders: %s", err)
+		return
+	}
+
+	routes := b.computeRoutes(sw, proxy, nil, nil, tlsValid, providers)
+
+	tp, err := tracingPolicy(proxy.Spec.VirtualHost.TracingPolicy)
+	if err != nil {
+		sw.SetInvalid("Spec.VirtualHost.TracingPolicy: %s", err)
+		return
+	}
+	if tp != nil {
+		// The virtual host tracing policy applies to the
+		// routes that don't set their own.
+		for _, r := range routes {
+			if r.TracingPolicy == nil {
+				r.TracingPolicy = tp
+			}
+		}
+	}
+
+	allow, rules, err := ipFilterPolicy(proxy.Spec.VirtualHost.IPAllowFilterPolicy, proxy.Spec.VirtualHost.IPDenyFilterPolicy, b.ClientAddress)
+	if err != nil {
+		sw.SetInvalid("Spec.VirtualHost: %s", err)
+		return
+	}
+	if len(rules) > 0 {
+		// The virtual host IP filter policy applies to
+		// the routes that don't set their own. A virtual
+		// host deny policy also applies to the routes that
+		// do, so that they can't allow the denied ranges.
+		for _, r := range routes {
+			switch {
+			case len(r.IPFilterRules) == 0:
+				r.IPFilterAllow = allow
+				r.IPFilterRules = rules
+			case allow:
+			case r.IPFilterAllow:
+				r.IPFilterDenyRules = rules
+			default:
+				r.IPFilterRules = append(r.IPFilterRules, rules...)
+			}
+		}
+	}
+
+	if ba := proxy.Spec.VirtualHost.BasicAuth; ba != nil {
+		for _, prefix := range ba.SkipPrefixes {
+			if !strings.HasPrefix(prefix, "/") {
+				sw.SetInvalid("Spec.VirtualHost.BasicAuth: skip prefix %q must start with \"/\"", prefix)
+				return
+			}
+		}
+
+		bap, err := b.lookupBasicAuthPolicy(ba, proxy.Namespace)
+		if err != nil {
+			sw.SetInvalid("Spec.VirtualHost.BasicAuth: %s", err)
 			return
 		}
+
+		// The virtual host basic authentication policy applies
+		// to the routes that don't set their own, unless they
+		// are skipped.
+		for _, r := range routes {
+			if r.BasicAuthPolicy == nil && !skipsBasicAuth(r, ba.SkipPrefixes) {
+				r.BasicAuthPolicy = bap
+			}
+		}
 	}
 
-	routes := b.computeRoutes(sw, proxy, nil, nil, tlsValid)
-	insecure := b.lookupVirtualHost(host)
-	addRoutes(insecure, routes)
+	for _, host := range hosts {
+		insecure := b.lookupVirtualHost(host)
+		addRoutes(insecure, routes)
 
-	// if TLS is enabled for this virtual host and there is no tcp proxy defined,
-	// then add routes to the secure virtualhost definition.
-	if tlsValid && proxy.Spec.TCPProxy == nil {
-		secure := b.lookupSecureVirtualHost(host)
-		addRoutes(secure, routes)
+		// if TLS is enabled for this virtual host and there is no tcp proxy defined,
+		// then add routes to the secure virtualhost definition.
+		if tlsValid && proxy.Spec.TCPProxy == nil {
+			secure := b.lookupSecureVirtualHost(host)
+			addRoutes(secure, routes)
+		}
 	}
 }
 
@@ -567,7 +936,7 @@ func expandPrefixMatches(routes []*Route) []*Route {
 	return expandedRoutes
 }
 
-func getProtocol(service projcontour.Service, s *Service) (string, error) {
+func getProtocol(sw *ObjectStatusWriter, service projcontour.Service, s *Service) (string, error) {
 	// Determine the protocol to use to speak to this Cluster.
 	var protocol string
 	if service.Protocol != nil {
@@ -577,14 +946,17 @@ func getProtocol(service projcontour.Service, s *Service) (string, error) {
 		default:
 			return "", fmt.Errorf("unsupported protocol: %v", protocol)
 		}
-	} else {
-		protocol = s.Protocol
 	}
 
+	protocol, err := serviceProtocol(s, protocol)
+	if err != nil {
+		// conflicts are resolved by precedence, so are only reported.
+		sw.SetWarning("service %q: %s", service.Name, err)
+	}
 	return protocol, nil
 }
 
-func (b *Builder) computeRoutes(sw *ObjectStatusWriter, proxy *projcontour.HTTPProxy, conditions []projcontour.Condition, visited []*projcontour.HTTPProxy, enforceTLS bool) []*Route {
+func (b *Builder) computeRoutes(sw *ObjectStatusWriter, proxy *projcontour.HTTPProxy, conditions []projcontour.Condition, visited []*projcontour.HTTPProxy, enforceTLS bool, providers map[string]*JWTProvider) []*Route {
 	for _, v := range visited {
 		// ensure we are not following an edge that produces a cycle
 		var path []string
@@ -607,6 +979,10 @@ func (b *Builder) computeRoutes(sw *ObjectStatusWriter, proxy *projcontour.HTTPP
 		return nil
 	}
 
+	// Routes of weighted includes, merged after all
+	// includes are processed.
+	var weighted []*Route
+
 	// Loop over and process all includes
 	for _, include := range proxy.Spec.Includes {
 		namespace := include.Namespace
@@ -624,20 +1000,53 @@ func (b *Builder) computeRoutes(sw *ObjectStatusWriter, proxy *projcontour.HTTPP
 			return nil
 		}
 
+		if !b.includeAdmitted(visited[0], proxy, delegate) {
+			sw.SetInvalid("include %s/%s: not admitted by its include admission policy", namespace, include.Name)
+			return nil
+		}
+
 		if err := pathConditionsValid(include.Conditions); err != nil {
 			sw.SetInvalid("include: %s", err)
 			return nil
 		}
 
+		if include.Weight < 0 || include.Weight > 100 {
+			sw.SetInvalid("include %s/%s: weight must be between 0 and 100", namespace, include.Name)
+			return nil
+		}
+
 		sw, commit := b.WithObject(delegate)
-		routes = append(routes, b.computeRoutes(sw, delegate, append(conditions, include.Conditions...), visited, enforceTLS)...)
+		included := b.computeRoutes(sw, delegate, append(conditions, include.Conditions...), visited, enforceTLS, providers)
 		commit()
 
+		if include.Weight > 0 {
+			weighted = append(weighted, weightRoutes(included, include.Weight)...)
+		} else {
+			routes = append(routes, included...)
+		}
+
 		// dest is not an orphaned httpproxy, as there is an httpproxy that points to it
 		delete(b.orphaned, k8s.FullName{Name: delegate.Name, Namespace: delegate.Namespace})
 	}
 
+	merged, err := mergeWeightedRoutes(weighted)
+	if err != nil {
+		sw.SetInvalid("%s", err)
+		return nil
+	}
+	routes = append(routes, merged...)
+
+	// the defaults applied to the routes of proxy, in order.
+	var defaults []string
+
 	for _, route := range proxy.Spec.Routes {
+		route, applied := b.applyProxyDefaults(proxy.Namespace, route)
+		for _, d := range applied {
+			if !contains(defaults, d) {
+				defaults = append(defaults, d)
+			}
+		}
+
 		if err := pathConditionsValid(route.Conditions); err != nil {
 			sw.SetInvalid("route: %s", err)
 			return nil
@@ -679,6 +1088,54 @@ func (b *Builder) computeRoutes(sw *ObjectStatusWriter, proxy *projcontour.HTTPP
 			ResponseHeadersPolicy: respHP,
 		}
 
+		if len(route.GetPrefixReplacements()) > 0 && route.GetRegexRewrite() != nil {
+			sw.SetInvalid("cannot specify both prefix replacements and a regex rewrite")
+			return nil
+		}
+
+		b.setWebsocketIdleTimeout(r)
+
+		r.TracingPolicy, err = tracingPolicy(route.TracingPolicy)
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+
+		r.RegexRewrite, err = regexRewritePolicy(route.GetRegexRewrite())
+		if err != nil {
+			sw.SetInvalid(err.Error())
+			return nil
+		}
+
+		r.FaultPolicy, err = faultPolicy(route.FaultInjectionPolicy)
+		if err != nil {
+			sw.SetInvalid("route.faultInjectionPolicy: %s", err)
+			return nil
+		}
+
+		r.IPFilterAllow, r.IPFilterRules, err = ipFilterPolicy(route.IPAllowFilterPolicy, route.IPDenyFilterPolicy, b.ClientAddress)
+		if err != nil {
+			sw.SetInvalid("route: %s", err)
+			return nil
+		}
+
+		if route.BasicAuth != nil && len(route.BasicAuth.SkipPrefixes) > 0 {
+			sw.SetInvalid("route.basicAuth: skipPrefixes is only valid on a virtual host")
+			return nil
+		}
+
+		r.BasicAuthPolicy, err = b.lookupBasicAuthPolicy(route.BasicAuth, proxy.Namespace)
+		if err != nil {
+			sw.SetInvalid("route.basicAuth: %s", err)
+			return nil
+		}
+
+		r.JWTProvider, r.JWTAllowMissing, err = jwtVerificationPolicy(route.JWTVerificationPolicy, providers)
+		if err != nil {
+			sw.SetInvalid("route.jwtVerificationPolicy: %s", err)
+			return nil
+		}
+
 		if len(route.GetPrefixReplacements()) > 0 {
 			if !r.HasPathPrefix() {
 				sw.SetInvalid("cannot specify prefix replacements without a prefix condition")
@@ -717,59 +1174,11 @@ func (b *Builder) computeRoutes(sw *ObjectStatusWriter, proxy *projcontour.HTTPP
 		}
 
 		for _, service := range route.Services {
-			if service.Port < 1 || service.Port > 65535 {
-				sw.SetInvalid("service %q: port must be in the range 1-65535", service.Name)
-				return nil
-			}
-			m := k8s.FullName{Name: service.Name, Namespace: proxy.Namespace}
-			s := b.lookupService(m, intstr.FromInt(service.Port))
-
-			if s == nil {
-				sw.SetInvalid("Service [%s:%d] is invalid or missing", service.Name, service.Port)
-				return nil
-			}
-
-			// Determine the protocol to use to speak to this Cluster.
-			protocol, err := getProtocol(service, s)
-			if err != nil {
-				sw.SetInvalid(err.Error())
-				return nil
-			}
-
-			var uv *PeerValidationContext
-			if protocol == "tls" {
-				// we can only validate TLS connections to services that talk TLS
-				uv, err = b.lookupUpstreamValidation(service.UpstreamValidation, proxy.Namespace)
-				if err != nil {
-					sw.SetInvalid("Service [%s:%d] TLS upstream validation policy error: %s",
-						service.Name, service.Port, err)
-					return nil
-				}
-			}
-
-			reqHP, err := headersPolicy(service.RequestHeadersPolicy, true /* allow Host */)
-			if err != nil {
-				sw.SetInvalid(err.Error())
-				return nil
-			}
-
-			respHP, err := headersPolicy(service.ResponseHeadersPolicy, false /* disallow Host */)
+			c, err := b.routeCluster(sw, proxy, &route, r, service)
 			if err != nil {
 				sw.SetInvalid(err.Error())
 				return nil
 			}
-
-			c := &Cluster{
-				Upstream:              s,
-				LoadBalancerPolicy:    loadBalancerPolicy(route.LoadBalancerPolicy),
-				Weight:                uint32(service.Weight),
-				HTTPHealthCheckPolicy: httpHealthCheckPolicy(route.HealthCheckPolicy),
-				UpstreamValidation:    uv,
-				RequestHeadersPolicy:  reqHP,
-				ResponseHeadersPolicy: respHP,
-				Protocol:              protocol,
-				SNI:                   determineSNI(r.RequestHeadersPolicy, reqHP, s),
-			}
 			if service.Mirror && r.MirrorPolicy != nil {
 				sw.SetInvalid("only one service per route may be nominated as mirror")
 				return nil
@@ -782,46 +1191,584 @@ func (b *Builder) computeRoutes(sw *ObjectStatusWriter, proxy *projcontour.HTTPP
 				r.Clusters = append(r.Clusters, c)
 			}
 		}
-		routes = append(routes, r)
+
+		if route.CanaryPolicy == nil {
+			setExternalNameHost(r)
+			routes = append(routes, r)
+			continue
+		}
+
+		cp, err := canaryPolicy(route.CanaryPolicy)
+		if err != nil {
+			sw.SetInvalid("route.canaryPolicy: %s", err)
+			return nil
+		}
+
+		var canary []*Cluster
+		for _, service := range route.CanaryPolicy.Services {
+			if service.Mirror {
+				sw.SetInvalid("route.canaryPolicy: service %q cannot be a mirror", service.Name)
+				return nil
+			}
+			c, err := b.routeCluster(sw, proxy, &route, r, service)
+			if err != nil {
+				sw.SetInvalid(err.Error())
+				return nil
+			}
+			canary = append(canary, c)
+		}
+		routes = append(routes, canaryRoutes(r, canary, cp)...)
+	}
+
+	if err := weightedRoutesUnique(routes, merged); err != nil {
+		sw.SetInvalid("%s", err)
+		return nil
 	}
 
 	routes = expandPrefixMatches(routes)
 
+	if len(defaults) > 0 {
+		sw.SetDefaults(strings.Join(defaults, ", "))
+	}
 	sw.SetValid()
 	return routes
 }
 
-// determineSNI decides what the SNI should be on the request. It is configured via RequestHeadersPolicy.Host key.
-// Policies set on service are used before policies set on a route. Otherwise the value of the externalService
-// is used if the route is configured to proxy to an externalService type.
-func determineSNI(routeRequestHeaders *HeadersPolicy, clusterRequestHeaders *HeadersPolicy, service *Service) string {
-
-	// Service RequestHeadersPolicy take precedence
-	if clusterRequestHeaders != nil {
-		if clusterRequestHeaders.HostRewrite != "" {
-			return clusterRequestHeaders.HostRewrite
+// applyProxyDefaults returns the supplied route, of an HTTPProxy in the
+// supplied namespace, with the policies of the namespace's ProxyDefaults,
+// then those of the ClusterProxyDefaults, merged under its own, and the
+// kind and name of each of the defaults that were applied.
+func (b *Builder) applyProxyDefaults(namespace string, route projcontour.Route) (projcontour.Route, []string) {
+	var applied []string
+	var ok bool
+
+	if pd := b.namespaceProxyDefaults(namespace); pd != nil {
+		if route, ok = mergeDefaults(route, &pd.Spec); ok {
+			applied = append(applied, fmt.Sprintf("ProxyDefaults %s/%s", pd.Namespace, pd.Name))
 		}
 	}
-
-	// Route RequestHeadersPolicy take precedence after service
-	if routeRequestHeaders != nil {
-		if routeRequestHeaders.HostRewrite != "" {
-			return routeRequestHeaders.HostRewrite
+	if cpd := b.clusterProxyDefaults(); cpd != nil {
+		if route, ok = mergeDefaults(route, &cpd.Spec); ok {
+			applied = append(applied, fmt.Sprintf("ClusterProxyDefaults %s", cpd.Name))
 		}
 	}
-
-	return service.ExternalName
+	return route, applied
 }
 
-func escapeHeaderValue(value string) string {
-	// Envoy supports %-encoded variables, so literal %'s in the header's value must be escaped.  See:
-	// https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_conn_man/headers#custom-request-response-headers
-	return strings.Replace(value, "%", "%%", -1)
-}
+// fqdnPoliciesPermit returns true if the FQDNPolicies permit the
+// namespace of the supplied root HTTPProxy to use each of its names.
+// If not, it marks the HTTPProxy invalid.
+func (b *Builder) fqdnPoliciesPermit(proxy *projcontour.HTTPProxy) bool {
+	deny := func(fqdn, format string, policies []string) bool {
+		sw, commit := b.WithObject(proxy)
+		sw.WithValue("vhost", fqdn).WithValue("reason", ReasonFQDNPolicyViolation)
+		sw.SetInvalid(format, fqdn, proxy.Namespace, strings.Join(policies, ", "))
+		commit()
+		return false
+	}
+
+	for _, fqdn := range virtualHostNames(proxy.Spec.VirtualHost) {
+		namespaces, policies := b.fqdnPolicyNamespaces(fqdn)
+		if len(policies) > 0 && !contains(namespaces, proxy.Namespace) {
+			return deny(fqdn, "fqdn %q is not permitted in namespace %q by FQDNPolicy %s", policies)
+		}
+
+		// A wildcard name serves the names beneath it that no
+		// other virtual host claims, so it must be permitted to
+		// use each of them.
+		if isWildcardName(fqdn) {
+			if policies := b.fqdnPoliciesBeneath(fqdn, proxy.Namespace); len(policies) > 0 {
+				return deny(fqdn, "fqdn %q includes names that are not permitted in namespace %q by FQDNPolicy %s", policies)
+			}
+		}
+	}
+	return true
+}
+
+// fqdnPolicyNames returns the names of the FQDNPolicies in order.
+func (b *Builder) fqdnPolicyNames() []string {
+	var names []string
+	for name := range b.Source.fqdnpolicies {
+		names = append(names, name)
+	}
+	sort.Strings(names) // sort for status stability
+	return names
+}
+
+// fqdnPolicyNamespaces returns the namespaces that the most specific
+// FQDNPolicy rules matching the supplied fqdn permit to use it, and the
+// names of the policies of those rules. If no rule matches, it returns
+// no policies, and any namespace may use the fqdn.
+func (b *Builder) fqdnPolicyNamespaces(fqdn string) ([]string, []string) {
+	var namespaces, policies []string
+	best := 0
+	for _, name := range b.fqdnPolicyNames() {
+		for _, rule := range b.Source.fqdnpolicies[name].Spec.Rules {
+			specificity := fqdnRuleMatch(rule.Fqdn, fqdn)
+			switch {
+			case specificity == 0 || specificity < best:
+				continue
+			case specificity > best:
+				best = specificity
+				namespaces, policies = nil, nil
+			}
+			namespaces = append(namespaces, rule.Namespaces...)
+			if !contains(policies, name) {
+				policies = append(policies, name)
+			}
+		}
+	}
+	return namespaces, policies
+}
+
+// fqdnPoliciesBeneath returns the names of the FQDNPolicies that have
+// rules for names beneath the supplied wildcard name, such as
+// "*.team-a.example.com" beneath "*.example.com", which do not permit
+// the supplied namespace to use them.
+func (b *Builder) fqdnPoliciesBeneath(wildcard, namespace string) []string {
+	domain := strings.ToLower(wildcard[1:]) // keep the leading dot
+	var policies []string
+	for _, name := range b.fqdnPolicyNames() {
+		for _, rule := range b.Source.fqdnpolicies[name].Spec.Rules {
+			fqdn := strings.TrimPrefix(strings.ToLower(rule.Fqdn), "*.")
+			if strings.HasSuffix(fqdn, domain) && !contains(rule.Namespaces, namespace) {
+				policies = append(policies, name)
+				break
+			}
+		}
+	}
+	return policies
+}
+
+// fqdnRuleMatch returns how specifically the supplied FQDNRule fqdn,
+// either a name or a pattern with a leading "*." label, matches the
+// supplied fqdn, or zero if it doesn't match. A name is more specific
+// than any pattern matching it, and a pattern is more specific than
+// the patterns for the domains above its own. Names are matched
+// case insensitively.
+func fqdnRuleMatch(pattern, fqdn string) int {
+	pattern, fqdn = strings.ToLower(pattern), strings.ToLower(fqdn)
+	if strings.HasPrefix(pattern, "*.") {
+		domain := pattern[1:] // keep the leading dot
+		if strings.HasSuffix(fqdn, domain) {
+			return len(domain)
+		}
+		return 0
+	}
+	if pattern == fqdn {
+		return len(pattern)
+	}
+	return 0
+}
+
+// includeAdmitted returns true if the supplied parent, under the
+// supplied root, may include the supplied child. HTTPProxies in the
+// namespace of the child may always include it.
+func (b *Builder) includeAdmitted(root, parent, child *projcontour.HTTPProxy) bool {
+	if parent.Namespace == child.Namespace {
+		return true
+	}
+
+	policy := b.includeAdmissionPolicy(child)
+	if policy == nil {
+		// includes are admitted unless a policy restricts them.
+		return true
+	}
+	if contains(policy.Namespaces, parent.Namespace) {
+		return true
+	}
+	if root.Spec.VirtualHost == nil {
+		return false
+	}
+	for _, fqdn := range policy.Fqdns {
+		// FQDNs are compared case insensitively.
+		if strings.EqualFold(fqdn, root.Spec.VirtualHost.Fqdn) {
+			return true
+		}
+	}
+	return false
+}
+
+// includeAdmissionPolicy returns the include admission policy of the
+// supplied HTTPProxy, or if it has none, that of the ProxyDefaults of
+// its namespace, or that of the ClusterProxyDefaults.
+func (b *Builder) includeAdmissionPolicy(proxy *projcontour.HTTPProxy) *projcontour.IncludeAdmissionPolicy {
+	if proxy.Spec.IncludeAdmissionPolicy != nil {
+		return proxy.Spec.IncludeAdmissionPolicy
+	}
+	if pd := b.namespaceProxyDefaults(proxy.Namespace); pd != nil && pd.Spec.IncludeAdmissionPolicy != nil {
+		return pd.Spec.IncludeAdmissionPolicy
+	}
+	if cpd := b.clusterProxyDefaults(); cpd != nil {
+		return cpd.Spec.IncludeAdmissionPolicy
+	}
+	return nil
+}
+
+// namespaceProxyDefaults returns the ProxyDefaults of the supplied
+// namespace, the first by name if there is more than one, or nil
+// if there are none.
+func (b *Builder) namespaceProxyDefaults(namespace string) *projcontour.ProxyDefaults {
+	var first *projcontour.ProxyDefaults
+	for _, pd := range b.Source.proxydefaults {
+		if pd.Namespace == namespace && (first == nil || pd.Name < first.Name) {
+			first = pd
+		}
+	}
+	return first
+}
+
+// clusterProxyDefaults returns the ClusterProxyDefaults, the first by
+// name if there is more than one, or nil if there are none.
+func (b *Builder) clusterProxyDefaults() *projcontour.ClusterProxyDefaults {
+	var first *projcontour.ClusterProxyDefaults
+	for _, cpd := range b.Source.clusterproxydefaults {
+		if first == nil || cpd.Name < first.Name {
+			first = cpd
+		}
+	}
+	return first
+}
+
+// routeCluster returns the Cluster for a service of route r.
+func (b *Builder) routeCluster(sw *ObjectStatusWriter, proxy *projcontour.HTTPProxy, route *projcontour.Route, r *Route, service projcontour.Service) (*Cluster, error) {
+	if service.Port < 1 || service.Port > 65535 {
+		return nil, fmt.Errorf("service %q: port must be in the range 1-65535", service.Name)
+	}
+	m := k8s.FullName{Name: service.Name, Namespace: proxy.Namespace}
+	var s *Service
+	if service.Kind == "ExternalBackend" {
+		var err error
+		s, err = b.lookupExternalBackend(m, service.Port)
+		if err != nil {
+			return nil, fmt.Errorf("ExternalBackend [%s:%d] is invalid: %s", service.Name, service.Port, err)
+		}
+	} else {
+		s = b.lookupService(m, intstr.FromInt(service.Port))
+		if s == nil {
+			return nil, fmt.Errorf("Service [%s:%d] is invalid or missing", service.Name, service.Port)
+		}
+	}
+
+	// Determine the protocol to use to speak to this Cluster.
+	protocol, err := getProtocol(sw, service, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var uv *PeerValidationContext
+	if protocol == "tls" {
+		// we can only validate TLS connections to services that talk TLS
+		uv, err = b.lookupUpstreamValidation(service.UpstreamValidation, proxy.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("Service [%s:%d] TLS upstream validation policy error: %s",
+				service.Name, service.Port, err)
+		}
+	}
+
+	reqHP, err := headersPolicy(service.RequestHeadersPolicy, true /* allow Host */)
+	if err != nil {
+		return nil, err
+	}
+
+	respHP, err := headersPolicy(service.ResponseHeadersPolicy, false /* disallow Host */)
+	if err != nil {
+		return nil, err
+	}
+
+	hc := httpHealthCheckPolicy(route.HealthCheckPolicy)
+	if hc == nil {
+		hc = s.HTTPHealthCheckPolicy
+	}
+
+	return &Cluster{
+		Upstream:              s,
+		LoadBalancerPolicy:    loadBalancerPolicy(route.LoadBalancerPolicy),
+		Weight:                uint32(service.Weight),
+		HTTPHealthCheckPolicy: hc,
+		UpstreamValidation:    uv,
+		RequestHeadersPolicy:  reqHP,
+		ResponseHeadersPolicy: respHP,
+		Protocol:              protocol,
+		MaxStreamDuration:     maxStreamDuration(r.TimeoutPolicy),
+		SNI:                   determineSNI(r.RequestHeadersPolicy, reqHP, s),
+	}, nil
+}
+
+// Values of the canary assignment cookie and override header.
+const (
+	canaryAssigned   = "canary"
+	canaryUnassigned = "stable"
+	canaryAlways     = "always"
+	canaryNever      = "never"
+)
+
+// cookieRegex returns a regular expression that matches a Cookie header
+// containing the named cookie with exactly the supplied value. Envoy
+// matches the expression against the whole header value.
+func cookieRegex(name, value string) string {
+	return `.*(^|;\s*)` + regexp.QuoteMeta(name+"="+value) + `(;|$).*`
+}
+
+// canaryRoutes returns the routes that apply canary policy cp to route r.
+// Requests with the policy's header, then requests with an assignment
+// cookie, are routed to the canary or route clusters they ask for. Other
+// requests are split between them by the policy's percentage, and the
+// response sets a cookie recording the assignment.
+func canaryRoutes(r *Route, canary []*Cluster, cp *canaryOptions) []*Route {
+	route := func(clusters []*Cluster, headers ...HeaderCondition) *Route {
+		cr := *r
+		cr.HeaderConditions = append(append([]HeaderCondition{}, r.HeaderConditions...), headers...)
+		cr.Clusters = clusters
+		return &cr
+	}
+
+	override := func(value string) HeaderCondition {
+		return HeaderCondition{Name: cp.Header, Value: value, MatchType: "exact"}
+	}
+
+	// Requests with a cookie are only routed by it if
+	// they don't override the assignment.
+	assigned := func(value string) []HeaderCondition {
+		return []HeaderCondition{{
+			Name:      "Cookie",
+			Value:     cookieRegex(cp.CookieName, value),
+			MatchType: "regex",
+		}, {
+			Name:      cp.Header,
+			Value:     canaryAlways,
+			MatchType: "exact",
+			Invert:    true,
+		}, {
+			Name:      cp.Header,
+			Value:     canaryNever,
+			MatchType: "exact",
+			Invert:    true,
+		}}
+	}
+
+	assign := func(clusters []*Cluster, weight int64, value string) []*Cluster {
+		cookie := cp.CookieName + "=" + value + "; Path=/"
+		if cp.CookieMaxAge > 0 {
+			cookie += fmt.Sprintf("; Max-Age=%d", int64(cp.CookieMaxAge.Seconds()))
+		}
+
+		clusters = weightClusters(clusters, weight)
+		for _, c := range clusters {
+			var hp HeadersPolicy
+			if c.ResponseHeadersPolicy != nil {
+				hp = *c.ResponseHeadersPolicy
+			}
+			hp.Add = map[string]string{"Set-Cookie": escapeHeaderValue(cookie)}
+			c.ResponseHeadersPolicy = &hp
+		}
+		return clusters
+	}
+
+	return []*Route{
+		route(canary, override(canaryAlways)),
+		route(r.Clusters, override(canaryNever)),
+		route(canary, assigned(canaryAssigned)...),
+		route(r.Clusters, assigned(canaryUnassigned)...),
+		route(append(
+			assign(r.Clusters, 100-cp.Percentage, canaryUnassigned),
+			assign(canary, cp.Percentage, canaryAssigned)...,
+		)),
+	}
+}
+
+// weightScale preserves the precision of cluster weights when
+// weightClusters divides a weight between them.
+const weightScale = 100
+
+// weightRoutes scales the weights of the clusters of each route so that
+// together they carry the supplied weight.
+func weightRoutes(routes []*Route, weight int64) []*Route {
+	for _, r := range routes {
+		r.Clusters = weightClusters(r.Clusters, weight)
+	}
+	return routes
+}
+
+// weightClusters returns copies of the supplied clusters with weights
+// scaled so that together they carry the supplied weight. Clusters
+// without weights share it equally, as they would in a route of their
+// own. The clusters are copied, as routes may share them.
+func weightClusters(clusters []*Cluster, weight int64) []*Cluster {
+	var total uint64
+	for _, c := range clusters {
+		total += uint64(c.Weight)
+	}
+
+	weighted := make([]*Cluster, 0, len(clusters))
+	for _, c := range clusters {
+		cw, t := uint64(c.Weight), total
+		if total == 0 {
+			cw, t = 1, uint64(len(clusters))
+		}
+		wc := *c
+		wc.Weight = uint32(uint64(weight) * weightScale * cw / t)
+		weighted = append(weighted, &wc)
+	}
+	return weighted
+}
+
+// mergeWeightedRoutes merges the routes with identical conditions into a
+// single route that splits traffic across all of their clusters. Routes
+// with identical conditions must have identical policies, as the merged
+// route applies a single set of them.
+func mergeWeightedRoutes(routes []*Route) ([]*Route, error) {
+	var merged []*Route
+	seen := make(map[string]*Route)
+	for _, r := range routes {
+		key := conditionsToString(r)
+		if m, ok := seen[key]; ok {
+			if !routePoliciesEqual(m, r) {
+				return nil, fmt.Errorf("weighted includes define different policies for routes with conditions %q", key)
+			}
+			m.Clusters = append(m.Clusters, r.Clusters...)
+			continue
+		}
+		seen[key] = r
+		merged = append(merged, r)
+	}
+	return merged, nil
+}
+
+// weightedRoutesUnique returns an error if a route other than the merged
+// weighted routes has the same conditions as one of them, as one of the
+// two routes would shadow the other.
+func weightedRoutesUnique(routes, merged []*Route) error {
+	weighted := make(map[string]*Route, len(merged))
+	for _, r := range merged {
+		weighted[conditionsToString(r)] = r
+	}
+	for _, r := range routes {
+		key := conditionsToString(r)
+		if m, ok := weighted[key]; ok && m != r {
+			return fmt.Errorf("weighted includes define routes with conditions %q that another route also defines", key)
+		}
+	}
+	return nil
+}
+
+// routePoliciesEqual returns true if the supplied routes are identical
+// other than their clusters.
+func routePoliciesEqual(a, b *Route) bool {
+	ac, bc := *a, *b
+	ac.Clusters, bc.Clusters = nil, nil
+	return reflect.DeepEqual(ac, bc)
+}
+
+// determineSNI decides what the SNI should be on the request. It is configured via RequestHeadersPolicy.Host key.
+// Policies set on service are used before policies set on a route. Otherwise the value of the externalService
+// is used if the route is configured to proxy to an externalService type.
+func determineSNI(routeRequestHeaders *HeadersPolicy, clusterRequestHeaders *HeadersPolicy, service *Service) string {
+
+	// Service RequestHeadersPolicy take precedence
+	if clusterRequestHeaders != nil {
+		if clusterRequestHeaders.HostRewrite != "" {
+			return clusterRequestHeaders.HostRewrite
+		}
+	}
+
+	// Route RequestHeadersPolicy take precedence after service
+	if routeRequestHeaders != nil {
+		if routeRequestHeaders.HostRewrite != "" {
+			return routeRequestHeaders.HostRewrite
+		}
+	}
+
+	return service.ExternalName
+}
+
+// headerValueVariables is the set of Envoy custom header variables
+// that may be used in HTTPProxy header policies. Only variables that
+// expose request or connection properties are permitted.
+//
+// See https://www.envoyproxy.io/docs/envoy/v1.14.3/configuration/http/http_conn_man/headers#custom-request-response-headers
+var headerValueVariables = map[string]bool{
+	"DOWNSTREAM_REMOTE_ADDRESS":              true,
+	"DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT": true,
+	"DOWNSTREAM_LOCAL_ADDRESS":               true,
+	"DOWNSTREAM_LOCAL_ADDRESS_WITHOUT_PORT":  true,
+	"DOWNSTREAM_LOCAL_PORT":                  true,
+	"DOWNSTREAM_LOCAL_URI_SAN":               true,
+	"DOWNSTREAM_PEER_URI_SAN":                true,
+	"DOWNSTREAM_LOCAL_SUBJECT":               true,
+	"DOWNSTREAM_PEER_SUBJECT":                true,
+	"DOWNSTREAM_PEER_ISSUER":                 true,
+	"DOWNSTREAM_TLS_SESSION_ID":              true,
+	"DOWNSTREAM_TLS_CIPHER":                  true,
+	"DOWNSTREAM_TLS_VERSION":                 true,
+	"DOWNSTREAM_PEER_FINGERPRINT_256":        true,
+	"DOWNSTREAM_PEER_FINGERPRINT_1":          true,
+	"DOWNSTREAM_PEER_SERIAL":                 true,
+	"DOWNSTREAM_PEER_CERT_V_START":           true,
+	"DOWNSTREAM_PEER_CERT_V_END":             true,
+	"UPSTREAM_REMOTE_ADDRESS":                true,
+	"HOSTNAME":                               true,
+	"PROTOCOL":                               true,
+}
+
+// headerValueVariableRegex matches a single Envoy header variable of the
+// form %NAME% or %NAME(argument)%.
+var headerValueVariableRegex = regexp.MustCompile(`%([A-Z0-9_]+)(\(([^%()]*)\))?%`)
+
+// escapeHeaderValue escapes the supplied value so that Envoy treats it
+// literally, except for any variables in headerValueVariables, or
+// %REQ(header-name)%, which are passed through for Envoy to expand.
+func escapeHeaderValue(value string) string {
+	// Envoy supports %-encoded variables, so literal %'s in the header's value must be escaped.  See:
+	// https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_conn_man/headers#custom-request-response-headers
+	escape := func(s string) string {
+		return strings.Replace(s, "%", "%%", -1)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range headerValueVariableRegex.FindAllStringSubmatchIndex(value, -1) {
+		sb.WriteString(escape(value[last:m[0]]))
+
+		variable := value[m[0]:m[1]]
+		name := value[m[2]:m[3]]
+		hasArg := m[4] >= 0
+		var arg string
+		if hasArg {
+			arg = value[m[6]:m[7]]
+		}
+
+		if validHeaderValueVariable(name, hasArg, arg) {
+			sb.WriteString(variable)
+		} else {
+			sb.WriteString(escape(variable))
+		}
+		last = m[1]
+	}
+	sb.WriteString(escape(value[last:]))
+
+	return sb.String()
+}
+
+// validHeaderValueVariable returns true if the named variable, and its
+// argument if present, is permitted to be expanded by Envoy.
+func validHeaderValueVariable(name string, hasArg bool, arg string) bool {
+	if name == "REQ" {
+		// %REQ(header-name)% must name a single, valid header.
+		return hasArg && len(validation.IsHTTPHeaderName(arg)) == 0
+	}
+	return !hasArg && headerValueVariables[name]
+}
 
 func includeConditionsIdentical(includes []projcontour.Include) bool {
 	j := 0
 	for i := 1; i < len(includes); i++ {
+		// Weighted includes share conditions to split traffic.
+		if includes[i].Weight > 0 && includes[j].Weight > 0 {
+			j++
+			continue
+		}
+
 		// Now compare each include's set of conditions
 		for _, cA := range includes[i].Conditions {
 			for _, cB := range includes[j].Conditions {
@@ -849,6 +1796,15 @@ func (b *Builder) buildDAG() *DAG {
 		dag.roots = append(dag.roots, https)
 	}
 
+	var ports []int
+	for port := range b.tcplisteners {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	for _, port := range ports {
+		dag.roots = append(dag.roots, b.tcplisteners[port])
+	}
+
 	for meta := range b.orphaned {
 		proxy, ok := b.Source.httpproxies[meta]
 		if ok {
@@ -960,15 +1916,228 @@ func (b *Builder) lookupDownstreamValidation(vc *projcontour.DownstreamValidatio
 	}, nil
 }
 
+// lookupBasicAuthPolicy returns the BasicAuthPolicy for the supplied
+// HTTPProxy basic authentication policy, or nil if it is nil.
+func (b *Builder) lookupBasicAuthPolicy(ba *projcontour.BasicAuthPolicy, namespace string) (*BasicAuthPolicy, error) {
+	if ba == nil {
+		return nil, nil
+	}
+
+	// The realm is quoted in the WWW-Authenticate header.
+	if strings.ContainsAny(ba.Realm, `"\`) {
+		return nil, fmt.Errorf("invalid realm %q", ba.Realm)
+	}
+
+	secretName := k8s.FullName{Name: ba.SecretName, Namespace: namespace}
+	sec, err := b.lookupSecret(secretName, validBasicAuth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid htpasswd Secret %q: %s", secretName, err)
+	}
+
+	credentials, err := htpasswd.Parse(sec.Object.Data[BasicAuthKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid htpasswd Secret %q: %s", secretName, err)
+	}
+
+	realm := ba.Realm
+	if realm == "" {
+		realm = "Restricted"
+	}
+
+	return &BasicAuthPolicy{
+		Realm:       realm,
+		Secret:      secretName,
+		Credentials: credentials,
+	}, nil
+}
+
+// skipsBasicAuth returns whether the prefix condition of the
+// supplied route is at or below one of the skipped prefixes.
+func skipsBasicAuth(r *Route, skipPrefixes []string) bool {
+	prefix, ok := r.PathCondition.(*PrefixCondition)
+	if !ok {
+		return false
+	}
+	for _, skip := range skipPrefixes {
+		if matchesPathPrefix(prefix.Prefix, skip) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupJWTProviders returns the JWT providers of the supplied root
+// HTTPProxy, keyed by name. The default provider, if there is one,
+// is also keyed by the empty name.
+func (b *Builder) lookupJWTProviders(proxy *projcontour.HTTPProxy) (map[string]*JWTProvider, error) {
+	providers := make(map[string]*JWTProvider)
+
+	for _, jp := range proxy.Spec.VirtualHost.JWTProviders {
+		if jp.Name == "" {
+			return nil, fmt.Errorf("provider name cannot be empty")
+		}
+		if _, ok := providers[jp.Name]; ok {
+			return nil, fmt.Errorf("duplicate provider %q", jp.Name)
+		}
+		if (jp.LocalJWKS == nil) == (jp.RemoteJWKS == nil) {
+			return nil, fmt.Errorf("provider %q: exactly one of localJWKS or remoteJWKS must be specified", jp.Name)
+		}
+
+		provider := &JWTProvider{
+			Name:       path.Join(proxy.Namespace, proxy.Name, jp.Name),
+			Issuer:     jp.Issuer,
+			Audiences:  jp.Audiences,
+			ForwardJWT: jp.ForwardJWT,
+		}
+
+		for _, ch := range jp.ClaimsToHeaders {
+			key := http.CanonicalHeaderKey(ch.Header)
+			if msgs := validation.IsHTTPHeaderName(key); len(msgs) != 0 {
+				return nil, fmt.Errorf("provider %q: invalid header %q: %v", jp.Name, key, msgs)
+			}
+			if _, ok := provider.ClaimsToHeaders[key]; ok {
+				return nil, fmt.Errorf("provider %q: duplicate header %q", jp.Name, key)
+			}
+			if provider.ClaimsToHeaders == nil {
+				provider.ClaimsToHeaders = make(map[string]string)
+			}
+			provider.ClaimsToHeaders[key] = ch.Claim
+		}
+
+		if jwks := jp.LocalJWKS; jwks != nil {
+			secretName := k8s.FullName{Name: jwks.SecretName, Namespace: proxy.Namespace}
+			sec, err := b.lookupSecret(secretName, validJWKS)
+			if err != nil {
+				return nil, fmt.Errorf("provider %q: invalid JWKS Secret %q: %s", jp.Name, secretName, err)
+			}
+			provider.LocalJWKS = string(sec.Object.Data[JWKSKey])
+		}
+
+		if jwks := jp.RemoteJWKS; jwks != nil {
+			remote, err := b.lookupRemoteJWKS(jwks, proxy.Namespace)
+			if err != nil {
+				return nil, fmt.Errorf("provider %q: %s", jp.Name, err)
+			}
+			provider.RemoteJWKS = remote
+		}
+
+		providers[jp.Name] = provider
+
+		if jp.Default {
+			if _, ok := providers[""]; ok {
+				return nil, fmt.Errorf("only one provider may be the default")
+			}
+			providers[""] = provider
+		}
+	}
+
+	return providers, nil
+}
+
+// lookupRemoteJWKS returns the RemoteJWKS that fetches a JSON
+// Web Key Set from a Service in the supplied namespace.
+func (b *Builder) lookupRemoteJWKS(jwks *projcontour.RemoteJWKS, namespace string) (*RemoteJWKS, error) {
+	m := k8s.FullName{Name: jwks.ServiceName, Namespace: namespace}
+	s := b.lookupService(m, intstr.FromInt(jwks.ServicePort))
+	if s == nil {
+		return nil, fmt.Errorf("Service [%s:%d] is invalid or missing", jwks.ServiceName, jwks.ServicePort)
+	}
+
+	duration := func(name, value string, def time.Duration) (time.Duration, error) {
+		if value == "" {
+			return def, nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid %s %q", name, value)
+		}
+		return d, nil
+	}
+
+	timeout, err := duration("timeout", jwks.Timeout, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	cacheDuration, err := duration("cacheDuration", jwks.CacheDuration, 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	jwksPath := jwks.Path
+	if jwksPath == "" {
+		jwksPath = "/"
+	}
+	if !strings.HasPrefix(jwksPath, "/") {
+		return nil, fmt.Errorf("path %q must start with \"/\"", jwksPath)
+	}
+
+	protocol, _ := serviceProtocol(s, "")
+	scheme := "http"
+	if protocol == "tls" {
+		scheme = "https"
+	}
+
+	return &RemoteJWKS{
+		URI: fmt.Sprintf("%s://%s.%s:%d%s", scheme, s.Name, s.Namespace, s.Port, jwksPath),
+		Cluster: &Cluster{
+			Upstream: s,
+			Protocol: protocol,
+		},
+		Timeout:       timeout,
+		CacheDuration: cacheDuration,
+	}, nil
+}
+
+// computeTCPListener builds the plain TCP listener claimed by
+// the tcpproxy of an HTTPProxy without a virtual host.
+func (b *Builder) computeTCPListener(sw *ObjectStatusWriter, proxy *projcontour.HTTPProxy) {
+	// ensure root httpproxy lives in allowed namespace
+	if !b.rootAllowed(proxy.Namespace) {
+		sw.SetInvalid("root HTTPProxy cannot be defined in this namespace")
+		return
+	}
+
+	port := proxy.Spec.TCPProxy.Port
+	if !containsPort(b.TCPPorts, port) {
+		sw.SetInvalid("tcpproxy: port %d is not a TCP listener port", port)
+		return
+	}
+
+	tcpproxy, ok := b.processHTTPProxyTCPProxy(sw, proxy, nil, false)
+	if !ok {
+		return
+	}
+	if tcpproxy == nil {
+		sw.SetInvalid("tcpproxy: included HTTPProxy has no tcpproxy")
+		return
+	}
+
+	b.tcplisteners[port] = &Listener{
+		Port:     port,
+		TCPProxy: tcpproxy,
+	}
+	sw.SetValid()
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
 // processHTTPProxyTCPProxy processes the spec.tcpproxy stanza in a HTTPProxy document
-// following the chain of spec.tcpproxy.include references. It returns true if processing
-// was successful, otherwise false if an error was encountered. The details of the error
-// will be recorded on the status of the relevant HTTPProxy object,
-func (b *Builder) processHTTPProxyTCPProxy(sw *ObjectStatusWriter, httpproxy *projcontour.HTTPProxy, visited []*projcontour.HTTPProxy, host string) bool {
+// following the chain of spec.tcpproxy.include references. It returns the TCPProxy, if any,
+// and true if processing was successful, otherwise false if an error was encountered. The
+// details of the error will be recorded on the status of the relevant HTTPProxy object.
+// Passthrough is true when the connections are not terminated by Envoy.
+func (b *Builder) processHTTPProxyTCPProxy(sw *ObjectStatusWriter, httpproxy *projcontour.HTTPProxy, visited []*projcontour.HTTPProxy, passthrough bool) (*TCPProxy, bool) {
 	tcpproxy := httpproxy.Spec.TCPProxy
 	if tcpproxy == nil {
 		// nothing to do
-		return true
+		return nil, true
 	}
 
 	visited = append(visited, httpproxy)
@@ -982,33 +2151,82 @@ func (b *Builder) processHTTPProxyTCPProxy(sw *ObjectStatusWriter, httpproxy *pr
 
 	if len(tcpproxy.Services) > 0 && tcpProxyInclude != nil {
 		sw.SetInvalid("tcpproxy: cannot specify services and include in the same httpproxy")
-		return false
+		return nil, false
 	}
 
 	if len(tcpproxy.Services) > 0 {
 		var proxy TCPProxy
 		for _, service := range httpproxy.Spec.TCPProxy.Services {
 			m := k8s.FullName{Name: service.Name, Namespace: httpproxy.Namespace}
-			s := b.lookupService(m, intstr.FromInt(service.Port))
-			if s == nil {
-				sw.SetInvalid("tcpproxy: service %s/%s/%d: not found", httpproxy.Namespace, service.Name, service.Port)
-				return false
+			var s *Service
+			if service.Kind == "ExternalBackend" {
+				var err error
+				s, err = b.lookupExternalBackend(m, service.Port)
+				if err != nil {
+					sw.SetInvalid("tcpproxy: ExternalBackend %s/%s/%d: %s", httpproxy.Namespace, service.Name, service.Port, err)
+					return nil, false
+				}
+			} else {
+				s = b.lookupService(m, intstr.FromInt(service.Port))
+				if s == nil {
+					sw.SetInvalid("tcpproxy: service %s/%s/%d: not found", httpproxy.Namespace, service.Name, service.Port)
+					return nil, false
+				}
 			}
+
+			// A TCP proxy can re-encrypt the connections it
+			// terminates, but it can't speak HTTP/2 to a service.
+			var protocol string
+			if service.Protocol != nil {
+				protocol = *service.Protocol
+				if protocol != "tls" {
+					sw.SetInvalid("tcpproxy: service %s/%s/%d: unsupported protocol: %v", httpproxy.Namespace, service.Name, service.Port, protocol)
+					return nil, false
+				}
+				if passthrough {
+					sw.SetInvalid("tcpproxy: service %s/%s/%d: protocol tls cannot be combined with tls.passthrough", httpproxy.Namespace, service.Name, service.Port)
+					return nil, false
+				}
+			}
+			if _, err := serviceProtocol(s, protocol); err != nil {
+				// conflicts are resolved by precedence, so are only reported.
+				sw.SetWarning("tcpproxy: service %s/%s/%d: %s", httpproxy.Namespace, service.Name, service.Port, err)
+			}
+			switch {
+			case s.Protocol != "":
+				// the upstream-protocol annotations take precedence.
+				protocol = s.Protocol
+			case protocol == "" && s.AppProtocol == "tls" && !passthrough:
+				// of the appProtocols, only tls can be proxied.
+				protocol = "tls"
+			}
+
+			var uv *PeerValidationContext
+			if protocol == "tls" {
+				var err error
+				uv, err = b.lookupUpstreamValidation(service.UpstreamValidation, httpproxy.Namespace)
+				if err != nil {
+					sw.SetInvalid("tcpproxy: service %s/%s/%d: TLS upstream validation policy error: %s", httpproxy.Namespace, service.Name, service.Port, err)
+					return nil, false
+				}
+			}
+
 			proxy.Clusters = append(proxy.Clusters, &Cluster{
 				Upstream:             s,
-				Protocol:             s.Protocol,
+				Protocol:             protocol,
+				UpstreamValidation:   uv,
+				SNI:                  s.ExternalName,
 				LoadBalancerPolicy:   loadBalancerPolicy(tcpproxy.LoadBalancerPolicy),
 				TCPHealthCheckPolicy: tcpHealthCheckPolicy(tcpproxy.HealthCheckPolicy),
 			})
 		}
-		b.lookupSecureVirtualHost(host).TCPProxy = &proxy
-		return true
+		return &proxy, true
 	}
 
 	if tcpProxyInclude == nil {
 		// We don't allow an empty TCPProxy object.
 		sw.SetInvalid("tcpproxy: either services or inclusion must be specified")
-		return false
+		return nil, false
 	}
 
 	namespace := tcpProxyInclude.Namespace
@@ -1021,12 +2239,17 @@ func (b *Builder) processHTTPProxyTCPProxy(sw *ObjectStatusWriter, httpproxy *pr
 	dest, ok := b.Source.httpproxies[m]
 	if !ok {
 		sw.SetInvalid("tcpproxy: include %s/%s not found", m.Namespace, m.Name)
-		return false
+		return nil, false
 	}
 
-	if dest.Spec.VirtualHost != nil {
+	if dest.Spec.VirtualHost != nil || tcpListenerPort(dest) != 0 {
 		sw.SetInvalid("root httpproxy cannot delegate to another root httpproxy")
-		return false
+		return nil, false
+	}
+
+	if !b.includeAdmitted(visited[0], httpproxy, dest) {
+		sw.SetInvalid("tcpproxy: include %s/%s: not admitted by its include admission policy", m.Namespace, m.Name)
+		return nil, false
 	}
 
 	// dest is no longer an orphan
@@ -1041,18 +2264,18 @@ func (b *Builder) processHTTPProxyTCPProxy(sw *ObjectStatusWriter, httpproxy *pr
 		if dest.Name == hp.Name && dest.Namespace == hp.Namespace {
 			path = append(path, fmt.Sprintf("%s/%s", dest.Namespace, dest.Name))
 			sw.SetInvalid("tcpproxy include creates a cycle: %s", strings.Join(path, " -> "))
-			return false
+			return nil, false
 		}
 	}
 
 	// follow the link and process the target tcpproxy
 	sw, commit := sw.WithObject(dest)
 	defer commit()
-	ok = b.processHTTPProxyTCPProxy(sw, dest, visited, host)
+	proxy, ok := b.processHTTPProxyTCPProxy(sw, dest, visited, passthrough)
 	if ok {
 		sw.SetValid()
 	}
-	return ok
+	return proxy, ok
 }
 
 func externalName(svc *v1.Service) string {
@@ -1062,9 +2285,71 @@ func externalName(svc *v1.Service) string {
 	return svc.Spec.ExternalName
 }
 
+// dnsPolicy returns the DNSPolicy of the external name of the supplied
+// Service from its annotations, or nil if it has none.
+func dnsPolicy(svc *v1.Service) *DNSPolicy {
+	dp := DNSPolicy{
+		RefreshRate:  annotation.DNSRefreshRate(svc),
+		RespectTTL:   annotation.DNSRespectTTL(svc),
+		LookupFamily: annotation.DNSLookupFamily(svc),
+		LogicalDNS:   annotation.LogicalDNS(svc),
+	}
+	if dp == (DNSPolicy{}) {
+		return nil
+	}
+	return &dp
+}
+
+// setExternalNameHost rewrites the Host header of the requests of r to
+// the external name of its clusters, if they all proxy over TLS to the
+// same external name and neither r nor its clusters rewrite the Host.
+func setExternalNameHost(r *Route) {
+	if r.RequestHeadersPolicy != nil && r.RequestHeadersPolicy.HostRewrite != "" {
+		return
+	}
+
+	var host string
+	for _, c := range r.Clusters {
+		switch {
+		case c.Protocol != "tls" && c.Protocol != "h2":
+			return
+		case c.Upstream.ExternalName == "":
+			return
+		case c.RequestHeadersPolicy != nil && c.RequestHeadersPolicy.HostRewrite != "":
+			return
+		case host != "" && host != c.Upstream.ExternalName:
+			return
+		}
+		host = c.Upstream.ExternalName
+	}
+	if host == "" {
+		return
+	}
+
+	if r.RequestHeadersPolicy == nil {
+		r.RequestHeadersPolicy = &HeadersPolicy{}
+	}
+	r.RequestHeadersPolicy.HostRewrite = host
+}
+
+// setWebsocketIdleTimeout applies the default websocket idle
+// timeout to r if it is a websocket route without one.
+func (b *Builder) setWebsocketIdleTimeout(r *Route) {
+	if !r.Websocket || b.WebsocketIdleTimeout == 0 {
+		return
+	}
+	if r.TimeoutPolicy == nil {
+		r.TimeoutPolicy = &TimeoutPolicy{}
+	}
+	if r.TimeoutPolicy.WebsocketIdleTimeout == 0 {
+		r.TimeoutPolicy.WebsocketIdleTimeout = b.WebsocketIdleTimeout
+	}
+}
+
 // route builds a dag.Route for the supplied Ingress.
 func route(ingress *v1beta1.Ingress, path string, service *Service) *Route {
 	wr := annotation.WebsocketRoutes(ingress)
+	protocol, _ := serviceProtocol(service, "")
 	r := &Route{
 		HTTPSUpgrade:  annotation.TLSRequired(ingress),
 		Websocket:     wr[path],
@@ -1072,7 +2357,7 @@ func route(ingress *v1beta1.Ingress, path string, service *Service) *Route {
 		RetryPolicy:   ingressRetryPolicy(ingress),
 		Clusters: []*Cluster{{
 			Upstream: service,
-			Protocol: service.Protocol,
+			Protocol: protocol,
 		}},
 	}
 
@@ -1091,6 +2376,16 @@ func isBlank(s string) bool {
 	return len(strings.TrimSpace(s)) == 0
 }
 
+// contains returns true if s is present in strs.
+func contains(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
 // splitSecret splits a secretName into its namespace and name components.
 // If there is no namespace prefix, the default namespace is returned.
 func splitSecret(secret, defns string) k8s.FullName {
@@ -1161,6 +2456,25 @@ func validSecret(s *v1.Secret) error {
 	return nil
 }
 
+func validBasicAuth(s *v1.Secret) error {
+	if len(s.Data[BasicAuthKey]) == 0 {
+		return fmt.Errorf("empty %q key", BasicAuthKey)
+	}
+
+	return nil
+}
+
+func validJWKS(s *v1.Secret) error {
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(s.Data[JWKSKey], &jwks); err != nil || len(jwks.Keys) == 0 {
+		return fmt.Errorf("%q key does not hold a JSON Web Key Set", JWKSKey)
+	}
+
+	return nil
+}
+
 func validCA(s *v1.Secret) error {
 	if len(s.Data[CACertificateKey]) == 0 {
 		return fmt.Errorf("empty %q key", CACertificateKey)
@@ -15,6 +15,7 @@ package dag
 
 import (
 	"errors"
+	"regexp"
 	"testing"
 	"time"
 
@@ -909,6 +910,24 @@ func TestDAGInsert(t *testing.T) {
 		Spec: s3b.Spec,
 	}
 
+	// s3g has an h2c appProtocol
+	appProtocolH2C := "kubernetes.io/h2c"
+	s3g := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s3a.Name,
+			Namespace: s3a.Namespace,
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:        "http",
+				Protocol:    "TCP",
+				Port:        80,
+				TargetPort:  intstr.FromInt(8888),
+				AppProtocol: &appProtocolH2C,
+			}},
+		},
+	}
+
 	sec13 := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "example-tls",
@@ -1251,6 +1270,50 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	proxyNoTimeoutPolicy := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bar-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "bar.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Prefix: "/",
+				}},
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxyDefaultsTimeoutPolicy := &projcontour.ProxyDefaults{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "defaults",
+			Namespace: "default",
+		},
+		Spec: projcontour.ProxyDefaultsSpec{
+			TimeoutPolicy: &projcontour.TimeoutPolicy{
+				Response: "1m30s",
+			},
+		},
+	}
+
+	clusterProxyDefaultsTimeoutPolicy := &projcontour.ClusterProxyDefaults{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "defaults",
+		},
+		Spec: projcontour.ProxyDefaultsSpec{
+			TimeoutPolicy: &projcontour.TimeoutPolicy{
+				Response: "infinite",
+			},
+		},
+	}
+
 	s1 := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "kuard",
@@ -1266,6 +1329,24 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	// s1c has an https appProtocol
+	appProtocolHTTPS := "https"
+	s1c := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:        "http",
+				Protocol:    "TCP",
+				Port:        8080,
+				TargetPort:  intstr.FromInt(8080),
+				AppProtocol: &appProtocolHTTPS,
+			}},
+		},
+	}
+
 	// s1a carries the tls annotation
 	s1a := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1463,6 +1544,18 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	s14a := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"projectcontour.io/dns-refresh-rate":   "1m",
+				"projectcontour.io/dns-discovery-type": "logical",
+			},
+		},
+		Spec: s14.Spec,
+	}
+
 	proxyDelegatedTLSSecret := &projcontour.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "app-with-tls-delegation",
@@ -3018,6 +3111,93 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	protocolTLS := "tls"
+
+	externalBackend := &projcontour.ExternalBackend{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vms",
+			Namespace: "default",
+		},
+		Spec: projcontour.ExternalBackendSpec{
+			Endpoints: []projcontour.ExternalBackendEndpoint{{
+				Address: "172.16.0.1",
+				Port:    8443,
+			}, {
+				Address: "172.16.0.2",
+				Port:    8443,
+			}},
+			HealthCheckPolicy: &projcontour.HTTPHealthCheckPolicy{
+				Path: "/healthz",
+			},
+			Protocol: &protocolTLS,
+		},
+	}
+
+	proxyExternalBackend := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Prefix: "/",
+				}},
+				Services: []projcontour.Service{{
+					Name: externalBackend.Name,
+					Kind: "ExternalBackend",
+					Port: 8443,
+				}},
+			}},
+		},
+	}
+
+	proxyExternalBackendMissingPort := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Prefix: "/",
+				}},
+				Services: []projcontour.Service{{
+					Name: externalBackend.Name,
+					Kind: "ExternalBackend",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+	proxyExternalNameServiceTLS := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-com",
+			Namespace: "default",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: []projcontour.Condition{{
+					Prefix: "/",
+				}},
+				Services: []projcontour.Service{{
+					Name:     s14.GetName(),
+					Port:     80,
+					Protocol: &protocolTLS,
+				}},
+			}},
+		},
+	}
+
 	tests := map[string]struct {
 		objs                         []interface{}
 		disablePermitInsecure        bool
@@ -3797,6 +3977,91 @@ func TestDAGInsert(t *testing.T) {
 				},
 			),
 		},
+		"insert httpproxy w/ timeoutpolicy from namespace proxydefaults": {
+			objs: []interface{}{
+				proxyNoTimeoutPolicy,
+				proxyDefaultsTimeoutPolicy,
+				s1,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("bar.com", &Route{
+							PathCondition: prefix("/"),
+							Clusters:      clustermap(s1),
+							TimeoutPolicy: &TimeoutPolicy{
+								ResponseTimeout: 90 * time.Second,
+							},
+						}),
+					),
+				},
+			),
+		},
+		"insert httpproxy w/ timeoutpolicy from clusterproxydefaults": {
+			objs: []interface{}{
+				proxyNoTimeoutPolicy,
+				clusterProxyDefaultsTimeoutPolicy,
+				s1,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("bar.com", &Route{
+							PathCondition: prefix("/"),
+							Clusters:      clustermap(s1),
+							TimeoutPolicy: &TimeoutPolicy{
+								ResponseTimeout: -1,
+							},
+						}),
+					),
+				},
+			),
+		},
+		"insert httpproxy w/ namespace proxydefaults over clusterproxydefaults": {
+			objs: []interface{}{
+				proxyNoTimeoutPolicy,
+				proxyDefaultsTimeoutPolicy,
+				clusterProxyDefaultsTimeoutPolicy,
+				s1,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("bar.com", &Route{
+							PathCondition: prefix("/"),
+							Clusters:      clustermap(s1),
+							TimeoutPolicy: &TimeoutPolicy{
+								ResponseTimeout: 90 * time.Second,
+							},
+						}),
+					),
+				},
+			),
+		},
+		"insert httpproxy w/ timeoutpolicy over proxydefaults": {
+			objs: []interface{}{
+				proxyTimeoutPolicyInfiniteResponse,
+				proxyDefaultsTimeoutPolicy,
+				s1,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("bar.com", &Route{
+							PathCondition: prefix("/"),
+							Clusters:      clustermap(s1),
+							TimeoutPolicy: &TimeoutPolicy{
+								ResponseTimeout: -1,
+							},
+						}),
+					),
+				},
+			),
+		},
 		"insert ingress w/ legacy infinite timeout annotation": {
 			objs: []interface{}{
 				i12c,
@@ -4174,6 +4439,26 @@ func TestDAGInsert(t *testing.T) {
 				},
 			),
 		},
+		"h2c service appProtocol": {
+			objs: []interface{}{
+				i3a, s3g,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("*",
+							routeProtocol("/", "h2c", &Service{
+								Name:        s3g.Name,
+								Namespace:   s3g.Namespace,
+								ServicePort: &s3g.Spec.Ports[0],
+								AppProtocol: "h2c",
+							}),
+						),
+					),
+				},
+			),
+		},
 		"insert ingress then service w/ upstream annotations": {
 			objs: []interface{}{
 				i1,
@@ -4493,6 +4778,46 @@ func TestDAGInsert(t *testing.T) {
 			),
 		},
 
+		"insert httpproxy with protocol and service with appProtocol": {
+			objs: []interface{}{
+				proxy110, s1c,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com",
+							routeProtocol("/", protocol, &Service{
+								Name:        s1c.Name,
+								Namespace:   s1c.Namespace,
+								ServicePort: &s1c.Spec.Ports[0],
+								AppProtocol: "tls",
+							})),
+					),
+				},
+			),
+		},
+
+		"insert httpproxy with protocol and service with upstream-protocol annotation": {
+			objs: []interface{}{
+				proxy110, s1a,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com",
+							routeProtocol("/", "tls", &Service{
+								Name:        s1a.Name,
+								Namespace:   s1a.Namespace,
+								ServicePort: &s1a.Spec.Ports[0],
+								Protocol:    "tls",
+							})),
+					),
+				},
+			),
+		},
+
 		"insert httpproxy without tls version": {
 			objs: []interface{}{
 				proxy6, s1, sec1,
@@ -5348,6 +5673,122 @@ func TestDAGInsert(t *testing.T) {
 				},
 			),
 		},
+		"insert proxy with externalName service with dns policy": {
+			objs: []interface{}{
+				proxyExternalNameService,
+				s14a,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com", &Route{
+							PathCondition: prefix("/"),
+							Clusters: []*Cluster{{
+								Upstream: &Service{
+									Name:         s14a.Name,
+									Namespace:    s14a.Namespace,
+									ServicePort:  &s14a.Spec.Ports[0],
+									ExternalName: "externalservice.io",
+									DNSPolicy: &DNSPolicy{
+										RefreshRate: time.Minute,
+										LogicalDNS:  true,
+									},
+								},
+								SNI: "externalservice.io",
+							}},
+						}),
+					),
+				},
+			),
+		},
+		"insert proxy with tls externalName service": {
+			objs: []interface{}{
+				proxyExternalNameServiceTLS,
+				s14,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com", &Route{
+							PathCondition: prefix("/"),
+							Clusters: []*Cluster{{
+								Upstream: &Service{
+									Name:         s14.Name,
+									Namespace:    s14.Namespace,
+									ServicePort:  &s14.Spec.Ports[0],
+									ExternalName: "externalservice.io",
+								},
+								Protocol: "tls",
+								SNI:      "externalservice.io",
+							}},
+							RequestHeadersPolicy: &HeadersPolicy{
+								HostRewrite: "externalservice.io",
+							},
+						}),
+					),
+				},
+			),
+		},
+		"insert proxy with externalbackend": {
+			objs: []interface{}{
+				proxyExternalBackend,
+				externalBackend,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com", &Route{
+							PathCondition: prefix("/"),
+							Clusters: []*Cluster{{
+								Upstream: &Service{
+									Name:      externalBackend.Name,
+									Namespace: externalBackend.Namespace,
+									ServicePort: &v1.ServicePort{
+										Name:     "8443",
+										Protocol: "TCP",
+										Port:     8443,
+									},
+									Kind:        "ExternalBackend",
+									AppProtocol: "tls",
+									HTTPHealthCheckPolicy: &HTTPHealthCheckPolicy{
+										Path: "/healthz",
+									},
+								},
+								Protocol: "tls",
+								HTTPHealthCheckPolicy: &HTTPHealthCheckPolicy{
+									Path: "/healthz",
+								},
+							}},
+						}),
+					),
+				},
+			),
+		},
+		"insert proxy with externalbackend without endpoints on its port": {
+			objs: []interface{}{
+				proxyExternalBackendMissingPort,
+				externalBackend,
+			},
+			want: listeners(),
+		},
+		"insert proxy with missing externalbackend and service of the same name": {
+			objs: []interface{}{
+				proxyExternalBackend,
+				&v1.Service{
+					ObjectMeta: externalBackend.ObjectMeta,
+					Spec: v1.ServiceSpec{
+						Ports: []v1.ServicePort{{
+							Protocol: "TCP",
+							Port:     8443,
+						}},
+					},
+				},
+			},
+			want: listeners(),
+		},
 		"insert proxy with replace header policy - route - host header": {
 			objs: []interface{}{
 				proxyReplaceHostHeaderRoute,
@@ -6410,6 +6851,53 @@ func TestHttpPaths(t *testing.T) {
 	}
 }
 
+func TestCookieRegex(t *testing.T) {
+	tests := map[string]struct {
+		cookie string
+		want   bool
+	}{
+		"only cookie": {
+			cookie: "canary=always",
+			want:   true,
+		},
+		"first cookie": {
+			cookie: "canary=always; session=abc",
+			want:   true,
+		},
+		"last cookie": {
+			cookie: "session=abc; canary=always",
+			want:   true,
+		},
+		"cookie without space": {
+			cookie: "session=abc;canary=always",
+			want:   true,
+		},
+		"name with prefix": {
+			cookie: "xcanary=always",
+			want:   false,
+		},
+		"value with suffix": {
+			cookie: "canary=alwaysnot",
+			want:   false,
+		},
+		"value of another cookie": {
+			cookie: "session=canary=always",
+			want:   false,
+		},
+	}
+
+	// Envoy requires the whole header value to match.
+	re := regexp.MustCompile("^(?:" + cookieRegex("canary", "always") + ")$")
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := re.MatchString(tc.cookie)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf(diff)
+			}
+		})
+	}
+}
+
 func TestDetermineSNI(t *testing.T) {
 	tests := map[string]struct {
 		routeRequestHeaders   *HeadersPolicy
@@ -6527,6 +7015,64 @@ func TestEnforceRoute(t *testing.T) {
 	}
 }
 
+func TestFQDNRuleMatch(t *testing.T) {
+	tests := map[string]struct {
+		pattern string
+		fqdn    string
+		want    int
+	}{
+		"name matches": {
+			pattern: "api.example.com",
+			fqdn:    "api.example.com",
+			want:    len("api.example.com"),
+		},
+		"name does not match": {
+			pattern: "api.example.com",
+			fqdn:    "www.example.com",
+			want:    0,
+		},
+		"pattern matches name under its domain": {
+			pattern: "*.team-a.example.com",
+			fqdn:    "api.team-a.example.com",
+			want:    len(".team-a.example.com"),
+		},
+		"pattern matches name deeper under its domain": {
+			pattern: "*.team-a.example.com",
+			fqdn:    "v1.api.team-a.example.com",
+			want:    len(".team-a.example.com"),
+		},
+		"pattern does not match its domain": {
+			pattern: "*.team-a.example.com",
+			fqdn:    "team-a.example.com",
+			want:    0,
+		},
+		"pattern does not match name with its domain as suffix": {
+			pattern: "*.team-a.example.com",
+			fqdn:    "api.other-team-a.example.com",
+			want:    0,
+		},
+		"name matches regardless of case": {
+			pattern: "API.example.com",
+			fqdn:    "api.EXAMPLE.com",
+			want:    len("api.example.com"),
+		},
+		"pattern matches regardless of case": {
+			pattern: "*.Team-A.example.com",
+			fqdn:    "API.team-a.EXAMPLE.com",
+			want:    len(".team-a.example.com"),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := fqdnRuleMatch(tc.pattern, tc.fqdn)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf(diff)
+			}
+		})
+	}
+}
+
 func TestSplitSecret(t *testing.T) {
 	tests := map[string]struct {
 		secret, defns string
@@ -6659,16 +7205,62 @@ func TestValidateHeaderAlteration(t *testing.T) {
 				Value: "%%%%%",
 			}, {
 				Name:  "k-baz", // This gets canonicalized
-				Value: "%DOWNSTREAM_LOCAL_ADDRESS%",
+				Value: "%UPSTREAM_METADATA([\"a\", \"b\"])%",
 			}},
 		},
 		want: &HeadersPolicy{
 			Set: map[string]string{
 				"K-Foo":           "100%%",
-				"K-Baz":           "%%DOWNSTREAM_LOCAL_ADDRESS%%",
+				"K-Baz":           "%%UPSTREAM_METADATA([\"a\", \"b\"])%%",
 				"Lot-Of-Percents": "%%%%%%%%%%",
 			},
 		},
+	}, {
+		name: "dynamic values are not escaped",
+		in: &projcontour.HeadersPolicy{
+			Set: []projcontour.HeaderValue{{
+				Name:  "X-Client-IP",
+				Value: "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+			}, {
+				Name:  "X-Served-By",
+				Value: "%HOSTNAME% (%UPSTREAM_REMOTE_ADDRESS%)",
+			}, {
+				Name:  "X-Request-Foo",
+				Value: "foo=%REQ(X-Foo)%",
+			}},
+		},
+		want: &HeadersPolicy{
+			Set: map[string]string{
+				"X-Client-Ip":   "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+				"X-Served-By":   "%HOSTNAME% (%UPSTREAM_REMOTE_ADDRESS%)",
+				"X-Request-Foo": "foo=%REQ(X-Foo)%",
+			},
+		},
+	}, {
+		name: "unknown and malformed dynamic values are escaped",
+		in: &projcontour.HeadersPolicy{
+			Set: []projcontour.HeaderValue{{
+				Name:  "X-Unknown",
+				Value: "%NOT_A_VARIABLE%",
+			}, {
+				Name:  "X-Bad-Req",
+				Value: "%REQ(not a header)%",
+			}, {
+				Name:  "X-Hostname-Arg",
+				Value: "%HOSTNAME(foo)%",
+			}, {
+				Name:  "X-Mixed",
+				Value: "50% of %HOSTNAME%",
+			}},
+		},
+		want: &HeadersPolicy{
+			Set: map[string]string{
+				"X-Unknown":      "%%NOT_A_VARIABLE%%",
+				"X-Bad-Req":      "%%REQ(not a header)%%",
+				"X-Hostname-Arg": "%%HOSTNAME(foo)%%",
+				"X-Mixed":        "50%% of %HOSTNAME%",
+			},
+		},
 	}}
 
 	for _, test := range tests {
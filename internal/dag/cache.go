@@ -43,6 +43,10 @@ type KubernetesCache struct {
 	httpproxies          map[k8s.FullName]*projectcontour.HTTPProxy
 	secrets              map[k8s.FullName]*v1.Secret
 	httpproxydelegations map[k8s.FullName]*projectcontour.TLSCertificateDelegation
+	externalbackends     map[k8s.FullName]*projectcontour.ExternalBackend
+	proxydefaults        map[k8s.FullName]*projectcontour.ProxyDefaults
+	clusterproxydefaults map[string]*projectcontour.ClusterProxyDefaults
+	fqdnpolicies         map[string]*projectcontour.FQDNPolicy
 	services             map[k8s.FullName]*v1.Service
 	gatewayclasses       map[k8s.FullName]*serviceapis.GatewayClass
 	gateways             map[k8s.FullName]*serviceapis.Gateway
@@ -60,6 +64,10 @@ func (kc *KubernetesCache) init() {
 	kc.httpproxies = make(map[k8s.FullName]*projectcontour.HTTPProxy)
 	kc.secrets = make(map[k8s.FullName]*v1.Secret)
 	kc.httpproxydelegations = make(map[k8s.FullName]*projectcontour.TLSCertificateDelegation)
+	kc.externalbackends = make(map[k8s.FullName]*projectcontour.ExternalBackend)
+	kc.proxydefaults = make(map[k8s.FullName]*projectcontour.ProxyDefaults)
+	kc.clusterproxydefaults = make(map[string]*projectcontour.ClusterProxyDefaults)
+	kc.fqdnpolicies = make(map[string]*projectcontour.FQDNPolicy)
 	kc.services = make(map[k8s.FullName]*v1.Service)
 	kc.gatewayclasses = make(map[k8s.FullName]*serviceapis.GatewayClass)
 	kc.gateways = make(map[k8s.FullName]*serviceapis.Gateway)
@@ -149,6 +157,18 @@ func (kc *KubernetesCache) Insert(obj interface{}) bool {
 	case *projectcontour.TLSCertificateDelegation:
 		kc.httpproxydelegations[k8s.ToFullName(obj)] = obj
 		return true
+	case *projectcontour.ExternalBackend:
+		kc.externalbackends[k8s.ToFullName(obj)] = obj
+		return true
+	case *projectcontour.ProxyDefaults:
+		kc.proxydefaults[k8s.ToFullName(obj)] = obj
+		return true
+	case *projectcontour.ClusterProxyDefaults:
+		kc.clusterproxydefaults[obj.Name] = obj
+		return true
+	case *projectcontour.FQDNPolicy:
+		kc.fqdnpolicies[obj.Name] = obj
+		return true
 	case *serviceapis.GatewayClass:
 		m := k8s.ToFullName(obj)
 		// TODO(youngnick): Remove this once service-apis actually have behavior
@@ -227,6 +247,24 @@ func (kc *KubernetesCache) remove(obj interface{}) bool {
 		_, ok := kc.httpproxydelegations[m]
 		delete(kc.httpproxydelegations, m)
 		return ok
+	case *projectcontour.ExternalBackend:
+		m := k8s.ToFullName(obj)
+		_, ok := kc.externalbackends[m]
+		delete(kc.externalbackends, m)
+		return ok
+	case *projectcontour.ProxyDefaults:
+		m := k8s.ToFullName(obj)
+		_, ok := kc.proxydefaults[m]
+		delete(kc.proxydefaults, m)
+		return ok
+	case *projectcontour.ClusterProxyDefaults:
+		_, ok := kc.clusterproxydefaults[obj.Name]
+		delete(kc.clusterproxydefaults, obj.Name)
+		return ok
+	case *projectcontour.FQDNPolicy:
+		_, ok := kc.fqdnpolicies[obj.Name]
+		delete(kc.fqdnpolicies, obj.Name)
+		return ok
 	case *serviceapis.GatewayClass:
 		m := k8s.ToFullName(obj)
 		_, ok := kc.gatewayclasses[m]
@@ -311,11 +349,40 @@ func (kc *KubernetesCache) serviceTriggersRebuild(service *v1.Service) bool {
 				}
 			}
 		}
+		if vh := ir.Spec.VirtualHost; vh != nil {
+			for _, jp := range vh.JWTProviders {
+				if jp.RemoteJWKS != nil && jp.RemoteJWKS.ServiceName == service.Name {
+					return true
+				}
+			}
+		}
 	}
 
 	return false
 }
 
+// usesSecret returns whether the supplied HTTPProxy authenticates
+// requests with the named Secret, either as htpasswd data or as a
+// JSON Web Key Set.
+func usesSecret(proxy *projectcontour.HTTPProxy, name string) bool {
+	if vh := proxy.Spec.VirtualHost; vh != nil {
+		if vh.BasicAuth != nil && vh.BasicAuth.SecretName == name {
+			return true
+		}
+		for _, jp := range vh.JWTProviders {
+			if jp.LocalJWKS != nil && jp.LocalJWKS.SecretName == name {
+				return true
+			}
+		}
+	}
+	for _, route := range proxy.Spec.Routes {
+		if route.BasicAuth != nil && route.BasicAuth.SecretName == name {
+			return true
+		}
+	}
+	return false
+}
+
 // secretTriggersRebuild returns true if this secret is referenced by an Ingress
 // or HTTPProxy object in this cache. If the secret is not in the same namespace
 // it must be mentioned by a TLSCertificateDelegation.
@@ -366,6 +433,10 @@ func (kc *KubernetesCache) secretTriggersRebuild(secret *v1.Secret) bool {
 	}
 
 	for _, proxy := range kc.httpproxies {
+		if proxy.Namespace == secret.Namespace && usesSecret(proxy, secret.Name) {
+			return true
+		}
+
 		vh := proxy.Spec.VirtualHost
 		if vh == nil {
 			// not a root ingress
@@ -267,6 +267,47 @@ func TestKubernetesCacheInsert(t *testing.T) {
 			},
 			want: true,
 		},
+		"insert htpasswd secret referenced by httpproxy route": {
+			pre: []interface{}{
+				&projcontour.HTTPProxy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "simple",
+						Namespace: "default",
+					},
+					Spec: projcontour.HTTPProxySpec{
+						Routes: []projcontour.Route{{
+							BasicAuth: &projcontour.BasicAuthPolicy{
+								SecretName: "htpasswd",
+							},
+						}},
+					},
+				},
+			},
+			obj: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "htpasswd",
+					Namespace: "default",
+				},
+				Type: v1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					BasicAuthKey: []byte("jane:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="),
+				},
+			},
+			want: true,
+		},
+		"insert htpasswd secret not referenced": {
+			obj: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "htpasswd",
+					Namespace: "default",
+				},
+				Type: v1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					BasicAuthKey: []byte("jane:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="),
+				},
+			},
+			want: false,
+		},
 		"insert secret referenced by httpproxy via tls delegation": {
 			pre: []interface{}{
 				&projcontour.HTTPProxy{
@@ -574,6 +615,40 @@ func TestKubernetesCacheInsert(t *testing.T) {
 			},
 			want: true,
 		},
+		"insert externalbackend": {
+			obj: &projcontour.ExternalBackend{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "backend",
+					Namespace: "default",
+				},
+			},
+			want: true,
+		},
+		"insert proxydefaults": {
+			obj: &projcontour.ProxyDefaults{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "defaults",
+					Namespace: "default",
+				},
+			},
+			want: true,
+		},
+		"insert clusterproxydefaults": {
+			obj: &projcontour.ClusterProxyDefaults{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "defaults",
+				},
+			},
+			want: true,
+		},
+		"insert fqdnpolicy": {
+			obj: &projcontour.FQDNPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "domains",
+				},
+			},
+			want: true,
+		},
 		"insert httpproxy": {
 			obj: &projcontour.HTTPProxy{
 				ObjectMeta: metav1.ObjectMeta{
@@ -866,6 +941,62 @@ func TestKubernetesCacheRemove(t *testing.T) {
 			},
 			want: false,
 		},
+		"remove externalbackend": {
+			cache: cache(&projcontour.ExternalBackend{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "backend",
+					Namespace: "default",
+				},
+			}),
+			obj: &projcontour.ExternalBackend{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "backend",
+					Namespace: "default",
+				},
+			},
+			want: true,
+		},
+		"remove proxydefaults": {
+			cache: cache(&projcontour.ProxyDefaults{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "defaults",
+					Namespace: "default",
+				},
+			}),
+			obj: &projcontour.ProxyDefaults{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "defaults",
+					Namespace: "default",
+				},
+			},
+			want: true,
+		},
+		"remove clusterproxydefaults": {
+			cache: cache(&projcontour.ClusterProxyDefaults{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "defaults",
+				},
+			}),
+			obj: &projcontour.ClusterProxyDefaults{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "defaults",
+				},
+			},
+			want: true,
+		},
+		"remove fqdnpolicy": {
+			cache: cache(&projcontour.FQDNPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "domains",
+				},
+			}),
+			obj: &projcontour.FQDNPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "domains",
+				},
+			},
+			want: true,
+		},
 		"remove service-apis Gatewayclass": {
 			cache: cache(&serviceapis.GatewayClass{
 				ObjectMeta: metav1.ObjectMeta{
@@ -17,11 +17,13 @@ package dag
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"time"
 
 	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	"github.com/projectcontour/contour/internal/htpasswd"
 	"github.com/projectcontour/contour/internal/k8s"
 	v1 "k8s.io/api/core/v1"
 )
@@ -120,6 +122,10 @@ type Route struct {
 	// Indicates that during forwarding, the matched prefix (or path) should be swapped with this value
 	PrefixRewrite string
 
+	// RegexRewrite indicates that during forwarding, the portions of the
+	// path matching a regular expression should be substituted.
+	RegexRewrite *RegexRewrite
+
 	// Mirror Policy defines the mirroring policy for this Route.
 	MirrorPolicy *MirrorPolicy
 
@@ -128,6 +134,123 @@ type Route struct {
 
 	// ResponseHeadersPolicy defines how headers are managed during forwarding
 	ResponseHeadersPolicy *HeadersPolicy
+
+	// TracingPolicy defines the tracing sampling override for this Route.
+	TracingPolicy *TracingPolicy
+
+	// FaultPolicy defines the faults injected into requests to this Route.
+	FaultPolicy *FaultPolicy
+
+	// IPFilterAllow determines whether requests matching the
+	// IPFilterRules are allowed, or denied.
+	IPFilterAllow bool
+
+	// IPFilterRules are the address ranges requests to this Route
+	// are matched against. If empty, requests are not filtered.
+	IPFilterRules []IPFilterRule
+
+	// IPFilterDenyRules are the address ranges requests to this
+	// Route are denied from, even if IPFilterRules allow them.
+	IPFilterDenyRules []IPFilterRule
+
+	// BasicAuthPolicy defines the credentials requests to this
+	// Route must present.
+	BasicAuthPolicy *BasicAuthPolicy
+
+	// JWTProvider verifies the JWTs of requests to this Route.
+	JWTProvider *JWTProvider
+
+	// JWTAllowMissing allows requests to this Route without a JWT.
+	JWTAllowMissing bool
+}
+
+// IPFilterRule defines an address range a request's address
+// is matched against.
+type IPFilterRule struct {
+	// Remote determines whether the client address derived
+	// from X-Forwarded-For is matched, rather than the
+	// address of the network peer.
+	Remote bool
+
+	// ClientAddress defines how Envoy derives the client
+	// address a Remote rule matches from X-Forwarded-For.
+	// If nil, the client address is the peer address.
+	ClientAddress *ClientAddressPolicy
+
+	// CIDR is the address range to match.
+	CIDR net.IPNet
+}
+
+// ClientAddressPolicy defines how Envoy derives the address of
+// the client from the X-Forwarded-For header.
+type ClientAddressPolicy struct {
+	// NumTrustedHops is the number of proxies in front of
+	// Envoy whose X-Forwarded-For entries are trusted.
+	NumTrustedHops uint32
+
+	// TrustedCIDRs are the peer addresses whose X-Forwarded-For
+	// headers are trusted. If empty, every peer is trusted.
+	TrustedCIDRs []net.IPNet
+
+	// SkipXFFAppend stops Envoy appending the peer address
+	// to the X-Forwarded-For header.
+	SkipXFFAppend bool
+}
+
+// BasicAuthPolicy defines the credentials requests must present
+// with HTTP Basic authentication.
+type BasicAuthPolicy struct {
+	// Realm is reported to clients that have not authenticated.
+	Realm string
+
+	// Secret is the name of the htpasswd Secret that holds
+	// the credentials.
+	Secret k8s.FullName
+
+	// Credentials maps user names to their password hashes.
+	Credentials htpasswd.Credentials
+}
+
+// JWTProvider defines how JWTs issued by a single issuer are verified.
+type JWTProvider struct {
+	// Name uniquely identifies the provider across virtual hosts.
+	Name string
+
+	// Issuer is the value of the "iss" claim, if it is verified.
+	Issuer string
+
+	// Audiences are the permitted values of the "aud" claim, if
+	// it is verified.
+	Audiences []string
+
+	// LocalJWKS is the JSON Web Key Set that verifies JWTs,
+	// if it is not fetched with RemoteJWKS.
+	LocalJWKS string
+
+	// RemoteJWKS fetches the JSON Web Key Set that verifies JWTs.
+	RemoteJWKS *RemoteJWKS
+
+	// ForwardJWT keeps verified JWTs in forwarded requests.
+	ForwardJWT bool
+
+	// ClaimsToHeaders maps the names of request headers to the
+	// claims forwarded in them.
+	ClaimsToHeaders map[string]string
+}
+
+// RemoteJWKS defines how a JSON Web Key Set is fetched.
+type RemoteJWKS struct {
+	// URI is the URI the key set is fetched from.
+	URI string
+
+	// Cluster is the Cluster the key set is fetched through.
+	Cluster *Cluster
+
+	// Timeout is how long fetching the key set may take.
+	Timeout time.Duration
+
+	// CacheDuration is how long the key set is cached.
+	CacheDuration time.Duration
 }
 
 // HasPathPrefix returns whether this route has a PrefixPathCondition.
@@ -152,6 +275,51 @@ type TimeoutPolicy struct {
 
 	// IdleTimeout is the timeout applied to idle connections.
 	IdleTimeout time.Duration
+
+	// WebsocketIdleTimeout is the timeout applied to idle
+	// connections on websocket routes. It takes precedence
+	// over IdleTimeout on websocket routes.
+	WebsocketIdleTimeout time.Duration
+
+	// MaxStreamDuration is the maximum duration of a stream
+	// to the route's backends.
+	// A duration of zero or -1 implies no maximum.
+	MaxStreamDuration time.Duration
+
+	// MaxGrpcTimeout is the upper bound on the timeout
+	// requested by a gRPC client via the grpc-timeout header.
+	// A timeout of zero implies "ignore the grpc-timeout header"
+	// A timeout of -1 represents "infinity"
+	MaxGrpcTimeout time.Duration
+}
+
+// FaultPolicy defines the faults injected into requests to a route.
+type FaultPolicy struct {
+	// Delay, if non-zero, is the fixed delay injected into requests.
+	Delay time.Duration
+
+	// DelayPercentage is the percentage of requests delayed.
+	DelayPercentage float64
+
+	// AbortStatus, if non-zero, is the HTTP status of the
+	// response to aborted requests.
+	AbortStatus uint32
+
+	// AbortPercentage is the percentage of requests aborted.
+	AbortPercentage float64
+
+	// HeaderConditions limit the faults to requests that
+	// match all of them.
+	HeaderConditions []HeaderCondition
+}
+
+// RegexRewrite defines a regular expression path rewrite for a route.
+type RegexRewrite struct {
+	// Pattern is the RE2 regular expression matched against the path.
+	Pattern string
+
+	// Substitution is the replacement for each matching portion of the path.
+	Substitution string
 }
 
 // RetryPolicy defines the retry / number / timeout options
@@ -169,6 +337,13 @@ type RetryPolicy struct {
 	PerTryTimeout time.Duration
 }
 
+// TracingPolicy defines the tracing policy for a route.
+type TracingPolicy struct {
+	// Sampling is the percentage of requests to trace,
+	// from 0 to 100.
+	Sampling float64
+}
+
 // MirrorPolicy defines the mirroring policy for a route.
 type MirrorPolicy struct {
 	Cluster *Cluster
@@ -181,6 +356,10 @@ type HeadersPolicy struct {
 
 	Set    map[string]string
 	Remove []string
+
+	// Add holds headers appended to any existing values
+	// of the header rather than replacing them.
+	Add map[string]string
 }
 
 type HeaderValue struct {
@@ -227,6 +406,10 @@ func (r *Route) Visit(f func(Vertex)) {
 	if r.MirrorPolicy != nil && r.MirrorPolicy.Cluster != nil {
 		f(r.MirrorPolicy.Cluster)
 	}
+	// JSON Web Key Sets are also fetched through a cluster.
+	if r.JWTProvider != nil && r.JWTProvider.RemoteJWKS != nil {
+		f(r.JWTProvider.RemoteJWKS.Cluster)
+	}
 }
 
 // A VirtualHost represents a named L4/L7 service.
@@ -282,6 +465,27 @@ type SecureVirtualHost struct {
 
 	// DownstreamValidation defines how to verify the client's certificate.
 	DownstreamValidation *PeerValidationContext
+
+	// SecurityHeaders defines the response headers added to
+	// the routes of this host.
+	SecurityHeaders *SecurityHeadersPolicy
+}
+
+// SecurityHeadersPolicy defines the security related response
+// headers added to the routes of a SecureVirtualHost.
+type SecurityHeadersPolicy struct {
+	// HSTS defines the Strict-Transport-Security header.
+	HSTS *HSTSPolicy
+
+	// Set holds additional headers keyed by canonical name.
+	Set map[string]string
+}
+
+// HSTSPolicy defines the Strict-Transport-Security header.
+type HSTSPolicy struct {
+	MaxAge            time.Duration
+	IncludeSubdomains bool
+	Preload           bool
 }
 
 func (s *SecureVirtualHost) Visit(f func(Vertex)) {
@@ -321,12 +525,19 @@ type Listener struct {
 	Port int
 
 	VirtualHosts []Vertex
+
+	// TCPProxy proxies all connections to a plain TCP
+	// listener, which has no virtual hosts.
+	*TCPProxy
 }
 
 func (l *Listener) Visit(f func(Vertex)) {
 	for _, vh := range l.VirtualHosts {
 		f(vh)
 	}
+	if l.TCPProxy != nil {
+		f(l.TCPProxy)
+	}
 }
 
 // TCPProxy represents a cluster of TCP endpoints.
@@ -343,16 +554,34 @@ func (t *TCPProxy) Visit(f func(Vertex)) {
 	}
 }
 
-// Service represents a single Kubernetes' Service's Port.
+// Service represents a single Kubernetes' Service's Port,
+// or the endpoints of an ExternalBackend on a single port.
 type Service struct {
 	Name, Namespace string
 
 	*v1.ServicePort
 
+	// Kind is the kind of the object this service was
+	// built from; either "" for a Kubernetes Service,
+	// or "ExternalBackend".
+	Kind string
+
 	// Protocol is the layer 7 protocol of this service
+	// from its upstream-protocol annotations.
 	// One of "", "h2", "h2c", or "tls".
 	Protocol string
 
+	// AppProtocol is the layer 7 protocol of this service
+	// from the appProtocol of its port, or the protocol
+	// of its ExternalBackend.
+	// One of "", "h2", "h2c", or "tls".
+	AppProtocol string
+
+	// HTTPHealthCheckPolicy is the health check policy of the
+	// clusters of this service whose route has none, from the
+	// health check policy of its ExternalBackend.
+	HTTPHealthCheckPolicy *HTTPHealthCheckPolicy
+
 	// Circuit breaking limits
 
 	// Max connections is maximum number of connections
@@ -373,12 +602,51 @@ type Service struct {
 
 	// ExternalName is an optional field referencing a dns entry for Service type "ExternalName"
 	ExternalName string
+
+	// DNSPolicy defines how Envoy resolves the ExternalName.
+	// If nil, Envoy's defaults are used.
+	DNSPolicy *DNSPolicy
+
+	// ZoneAwareRouting defines how Envoy prefers the endpoints
+	// of this service that are in its own zone. If nil, Envoy
+	// doesn't prefer any endpoints.
+	ZoneAwareRouting *ZoneAwareRoutingPolicy
+}
+
+// DNSPolicy defines how Envoy resolves the external name of a service.
+type DNSPolicy struct {
+	// RefreshRate is the interval at which the name is resolved.
+	// Zero means Envoy's default of 5 seconds.
+	RefreshRate time.Duration
+
+	// RespectTTL resolves the name when the TTL of its
+	// records expire instead of at the RefreshRate.
+	RespectTTL bool
+
+	// LookupFamily is the IP address family the name is
+	// resolved to. One of "", "auto", "v4" or "v6".
+	LookupFamily string
+
+	// LogicalDNS connects to only the first address the name
+	// resolves to, rather than to every address, which suits
+	// names that resolve to large pools of addresses.
+	LogicalDNS bool
+}
+
+// ZoneAwareRoutingPolicy defines how Envoy prefers the
+// endpoints of a service that are in its own zone.
+type ZoneAwareRoutingPolicy struct {
+	// MinClusterSize is the number of endpoints a service
+	// needs before Envoy prefers those in its own zone.
+	// Zero means Envoy's default of 6 applies.
+	MinClusterSize uint64
 }
 
 type servicemeta struct {
 	name      string
 	namespace string
 	port      int32
+	kind      string
 }
 
 func (s *Service) ToFullName() servicemeta {
@@ -386,6 +654,7 @@ func (s *Service) ToFullName() servicemeta {
 		name:      s.Name,
 		namespace: s.Namespace,
 		port:      s.Port,
+		kind:      s.Kind,
 	}
 }
 
@@ -426,6 +695,10 @@ type Cluster struct {
 	// ResponseHeadersPolicy defines how headers are managed during forwarding
 	ResponseHeadersPolicy *HeadersPolicy
 
+	// MaxStreamDuration is the maximum duration of streams
+	// to this cluster. Zero means no maximum.
+	MaxStreamDuration time.Duration
+
 	// SNI is used when a route proxies an upstream using tls.
 	// SNI describes how the SNI is set on a Cluster and is configured via RequestHeadersPolicy.Host key.
 	// Policies set on service are used before policies set on a route. Otherwise the value of the externalService
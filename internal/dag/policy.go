@@ -15,7 +15,11 @@ package dag
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
@@ -131,11 +135,276 @@ func timeoutPolicy(tp *projcontour.TimeoutPolicy) *TimeoutPolicy {
 		return nil
 	}
 	return &TimeoutPolicy{
-		ResponseTimeout: annotation.ParseTimeout(tp.Response),
-		IdleTimeout:     annotation.ParseTimeout(tp.Idle),
+		ResponseTimeout:      annotation.ParseTimeout(tp.Response),
+		IdleTimeout:          annotation.ParseTimeout(tp.Idle),
+		WebsocketIdleTimeout: annotation.ParseTimeout(tp.WebsocketIdle),
+		MaxStreamDuration:    annotation.ParseTimeout(tp.MaxStreamDuration),
+		MaxGrpcTimeout:       annotation.ParseTimeout(tp.MaxGrpcTimeout),
 	}
 }
 
+// tracingPolicy returns a TracingPolicy for the supplied
+// HTTPProxy tracing policy.
+func tracingPolicy(tp *projcontour.TracingPolicy) (*TracingPolicy, error) {
+	if tp == nil {
+		return nil, nil
+	}
+
+	sampling, err := strconv.ParseFloat(tp.Sampling, 64)
+	if err != nil || sampling < 0 || sampling > 100 {
+		return nil, fmt.Errorf("invalid tracing sampling percentage %q", tp.Sampling)
+	}
+
+	return &TracingPolicy{
+		Sampling: sampling,
+	}, nil
+}
+
+// grpcFaultStatus maps the gRPC statuses a fault may abort requests
+// with to the HTTP status Envoy converts to that gRPC status.
+var grpcFaultStatus = map[int64]uint32{
+	2:  500, // UNKNOWN
+	7:  403, // PERMISSION_DENIED
+	12: 404, // UNIMPLEMENTED
+	13: 400, // INTERNAL
+	14: 503, // UNAVAILABLE
+	16: 401, // UNAUTHENTICATED
+}
+
+// faultPolicy returns a FaultPolicy for the supplied
+// HTTPProxy fault injection policy.
+func faultPolicy(fp *projcontour.FaultInjectionPolicy) (*FaultPolicy, error) {
+	if fp == nil {
+		return nil, nil
+	}
+
+	percentage := func(s string) (float64, error) {
+		if s == "" {
+			return 100, nil
+		}
+		p, err := strconv.ParseFloat(s, 64)
+		if err != nil || p < 0 || p > 100 {
+			return 0, fmt.Errorf("invalid percentage %q", s)
+		}
+		return p, nil
+	}
+
+	if fp.Delay == nil && fp.Abort == nil {
+		return nil, fmt.Errorf("a delay or an abort must be specified")
+	}
+
+	var conds []projcontour.Condition
+	for i := range fp.Headers {
+		conds = append(conds, projcontour.Condition{Header: &fp.Headers[i]})
+	}
+	if err := headerConditionsValid(conds); err != nil {
+		return nil, err
+	}
+
+	policy := &FaultPolicy{
+		HeaderConditions: mergeHeaderConditions(conds),
+	}
+
+	if d := fp.Delay; d != nil {
+		delay, err := time.ParseDuration(d.Duration)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("invalid delay duration %q", d.Duration)
+		}
+		policy.Delay = delay
+		if policy.DelayPercentage, err = percentage(d.Percentage); err != nil {
+			return nil, fmt.Errorf("delay: %s", err)
+		}
+	}
+
+	if a := fp.Abort; a != nil {
+		switch {
+		case a.HTTPStatus != 0 && a.GRPCStatus != 0:
+			return nil, fmt.Errorf("abort: cannot specify both httpStatus and grpcStatus")
+		case a.HTTPStatus != 0:
+			if a.HTTPStatus < 200 || a.HTTPStatus > 599 {
+				return nil, fmt.Errorf("abort: invalid httpStatus %d", a.HTTPStatus)
+			}
+			policy.AbortStatus = uint32(a.HTTPStatus)
+		case a.GRPCStatus != 0:
+			status, ok := grpcFaultStatus[a.GRPCStatus]
+			if !ok {
+				return nil, fmt.Errorf("abort: unsupported grpcStatus %d", a.GRPCStatus)
+			}
+			policy.AbortStatus = status
+		default:
+			return nil, fmt.Errorf("abort: httpStatus or grpcStatus must be specified")
+		}
+
+		var err error
+		if policy.AbortPercentage, err = percentage(a.Percentage); err != nil {
+			return nil, fmt.Errorf("abort: %s", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// ipFilterPolicy returns the IP filter rules of the supplied HTTPProxy
+// IP filter policies, and whether requests that match them are allowed.
+// Remote rules match the client address that the supplied policy
+// derives from X-Forwarded-For.
+func ipFilterPolicy(allow, deny []projcontour.IPFilterPolicy, ca *ClientAddressPolicy) (bool, []IPFilterRule, error) {
+	if len(allow) > 0 && len(deny) > 0 {
+		return false, nil, fmt.Errorf("cannot specify both ipAllowPolicy and ipDenyPolicy")
+	}
+
+	policies := deny
+	if len(allow) > 0 {
+		policies = allow
+	}
+
+	var rules []IPFilterRule
+	for _, p := range policies {
+		cidr := p.CIDR
+		if !strings.Contains(cidr, "/") {
+			// A bare address matches only itself.
+			if strings.Contains(cidr, ":") {
+				cidr += "/128"
+			} else {
+				cidr += "/32"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return false, nil, fmt.Errorf("invalid CIDR %q", p.CIDR)
+		}
+
+		switch p.Source {
+		case "", projcontour.IPFilterSourcePeer, projcontour.IPFilterSourceRemote:
+		default:
+			return false, nil, fmt.Errorf("invalid source %q", p.Source)
+		}
+
+		rule := IPFilterRule{
+			Remote: p.Source == projcontour.IPFilterSourceRemote,
+			CIDR:   *ipnet,
+		}
+		if rule.Remote && ca != nil && ca.NumTrustedHops > 0 {
+			// Envoy can only match IPv4 ranges against the
+			// entries of X-Forwarded-For.
+			if ipnet.IP.To4() == nil {
+				return false, nil, fmt.Errorf("IPv6 CIDR %q cannot match the Remote address when X-Forwarded-For is trusted", p.CIDR)
+			}
+			rule.ClientAddress = ca
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return len(allow) > 0, rules, nil
+}
+
+// jwtVerificationPolicy returns the provider that verifies the JWTs
+// of requests to a route, and whether requests without a JWT are
+// allowed. Routes without a policy use the default provider, which
+// is keyed by the empty name.
+func jwtVerificationPolicy(jv *projcontour.JWTVerificationPolicy, providers map[string]*JWTProvider) (*JWTProvider, bool, error) {
+	if jv == nil {
+		return providers[""], false, nil
+	}
+
+	if jv.Disabled {
+		if jv.Require != "" || jv.AllowMissing {
+			return nil, false, fmt.Errorf("disabled cannot be combined with require or allowMissing")
+		}
+		return nil, false, nil
+	}
+
+	provider, ok := providers[jv.Require]
+	if !ok {
+		if jv.Require == "" {
+			return nil, false, fmt.Errorf("no default JWT provider")
+		}
+		return nil, false, fmt.Errorf("JWT provider %q not found", jv.Require)
+	}
+
+	return provider, jv.AllowMissing, nil
+}
+
+// canaryOptions are the options of a validated HTTPProxy canary policy.
+type canaryOptions struct {
+	Header       string
+	CookieName   string
+	CookieMaxAge time.Duration
+	Percentage   int64
+}
+
+// cookieNameRegex matches the token characters a cookie name may contain.
+var cookieNameRegex = regexp.MustCompile("^[-!#$%&'*+.^_`|~0-9A-Za-z]+$")
+
+// canaryPolicy returns the options of the supplied HTTPProxy canary
+// policy, with defaults for those it does not set.
+func canaryPolicy(cp *projcontour.CanaryPolicy) (*canaryOptions, error) {
+	opts := &canaryOptions{
+		Header:     "X-Canary",
+		CookieName: "contour-canary",
+		Percentage: cp.Percentage,
+	}
+
+	if cp.Percentage < 0 || cp.Percentage > 100 {
+		return nil, fmt.Errorf("percentage must be in the range 0-100")
+	}
+
+	if cp.Header != "" {
+		opts.Header = http.CanonicalHeaderKey(cp.Header)
+		if msgs := validation.IsHTTPHeaderName(opts.Header); len(msgs) != 0 {
+			return nil, fmt.Errorf("invalid header %q: %v", cp.Header, msgs)
+		}
+	}
+
+	if cp.CookieName != "" {
+		if !cookieNameRegex.MatchString(cp.CookieName) {
+			return nil, fmt.Errorf("invalid cookie name %q", cp.CookieName)
+		}
+		opts.CookieName = cp.CookieName
+	}
+
+	if cp.CookieMaxAge != "" {
+		maxAge, err := time.ParseDuration(cp.CookieMaxAge)
+		if err != nil || maxAge < time.Second {
+			return nil, fmt.Errorf("invalid cookie max age %q", cp.CookieMaxAge)
+		}
+		opts.CookieMaxAge = maxAge
+	}
+
+	return opts, nil
+}
+
+func hstsPolicy(hp *projcontour.HSTSPolicy) (*HSTSPolicy, error) {
+	if hp == nil {
+		return nil, nil
+	}
+
+	maxAge, err := time.ParseDuration(hp.MaxAge)
+	if err != nil || maxAge < 0 {
+		return nil, fmt.Errorf("invalid max age %q", hp.MaxAge)
+	}
+
+	if hp.Preload && !hp.IncludeSubdomains {
+		return nil, fmt.Errorf("preload requires includeSubdomains")
+	}
+
+	return &HSTSPolicy{
+		MaxAge:            maxAge,
+		IncludeSubdomains: hp.IncludeSubdomains,
+		Preload:           hp.Preload,
+	}, nil
+}
+
+// maxStreamDuration returns the maximum stream duration
+// for the clusters of a route with the supplied timeout policy.
+func maxStreamDuration(tp *TimeoutPolicy) time.Duration {
+	if tp == nil || tp.MaxStreamDuration < 0 {
+		return 0
+	}
+	return tp.MaxStreamDuration
+}
+
 func httpHealthCheckPolicy(hc *projcontour.HTTPHealthCheckPolicy) *HTTPHealthCheckPolicy {
 	if hc == nil {
 		return nil
@@ -205,3 +474,134 @@ func prefixReplacementsAreValid(replacements []projcontour.ReplacePrefix) error
 
 	return nil
 }
+
+// regexRewritePolicy validates and returns a RegexRewrite for the
+// supplied HTTPProxy regex rewrite policy.
+func regexRewritePolicy(rr *projcontour.RegexRewrite) (*RegexRewrite, error) {
+	if rr == nil {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(rr.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex rewrite pattern %q: %v", rr.Pattern, err)
+	}
+
+	// Make sure that the substitution only refers to capture
+	// groups that exist in the pattern, otherwise Envoy will
+	// reject the route.
+	for _, m := range regexRewriteGroupRegex.FindAllStringSubmatch(rr.Substitution, -1) {
+		n, _ := strconv.Atoi(m[1])
+		if n > re.NumSubexp() {
+			return nil, fmt.Errorf("regex rewrite substitution %q refers to missing capture group %d", rr.Substitution, n)
+		}
+	}
+
+	return &RegexRewrite{
+		Pattern:      rr.Pattern,
+		Substitution: rr.Substitution,
+	}, nil
+}
+
+// regexRewriteGroupRegex matches capture group references of the form \1.
+var regexRewriteGroupRegex = regexp.MustCompile(`\\([0-9]+)`)
+
+// mergeDefaults returns the supplied route with the supplied default
+// policies merged under its own, and whether any default was applied.
+// The policies of the supplied route are not modified.
+func mergeDefaults(route projcontour.Route, defaults *projcontour.ProxyDefaultsSpec) (projcontour.Route, bool) {
+	var applied, ok bool
+
+	route.TimeoutPolicy, ok = mergeTimeoutPolicy(route.TimeoutPolicy, defaults.TimeoutPolicy)
+	applied = applied || ok
+
+	if route.RetryPolicy == nil && defaults.RetryPolicy != nil {
+		route.RetryPolicy = defaults.RetryPolicy
+		applied = true
+	}
+
+	route.RequestHeadersPolicy, ok = mergeHeadersPolicy(route.RequestHeadersPolicy, defaults.RequestHeadersPolicy)
+	applied = applied || ok
+
+	route.ResponseHeadersPolicy, ok = mergeHeadersPolicy(route.ResponseHeadersPolicy, defaults.ResponseHeadersPolicy)
+	applied = applied || ok
+
+	if route.LoadBalancerPolicy == nil && defaults.LoadBalancerPolicy != nil {
+		route.LoadBalancerPolicy = defaults.LoadBalancerPolicy
+		applied = true
+	}
+
+	return route, applied
+}
+
+// mergeTimeoutPolicy returns a timeout policy with the timeouts of the
+// supplied policy, and those of the supplied defaults that it doesn't
+// set, and whether any default was applied.
+func mergeTimeoutPolicy(tp, defaults *projcontour.TimeoutPolicy) (*projcontour.TimeoutPolicy, bool) {
+	if defaults == nil {
+		return tp, false
+	}
+
+	var merged projcontour.TimeoutPolicy
+	if tp != nil {
+		merged = *tp
+	}
+	applied := false
+	merge := func(timeout *string, def string) {
+		if *timeout == "" && def != "" {
+			*timeout = def
+			applied = true
+		}
+	}
+	merge(&merged.Response, defaults.Response)
+	merge(&merged.Idle, defaults.Idle)
+	merge(&merged.WebsocketIdle, defaults.WebsocketIdle)
+	merge(&merged.MaxStreamDuration, defaults.MaxStreamDuration)
+	merge(&merged.MaxGrpcTimeout, defaults.MaxGrpcTimeout)
+
+	if !applied {
+		return tp, false
+	}
+	return &merged, true
+}
+
+// mergeHeadersPolicy returns a headers policy with the headers that the
+// supplied policy sets and removes, and those that the supplied defaults
+// set and remove that it doesn't, and whether any default was applied.
+func mergeHeadersPolicy(hp, defaults *projcontour.HeadersPolicy) (*projcontour.HeadersPolicy, bool) {
+	if defaults == nil {
+		return hp, false
+	}
+
+	var merged projcontour.HeadersPolicy
+	explicit := sets.NewString()
+	if hp != nil {
+		merged.Set = append(merged.Set, hp.Set...)
+		merged.Remove = append(merged.Remove, hp.Remove...)
+		for _, h := range hp.Set {
+			explicit.Insert(http.CanonicalHeaderKey(h.Name))
+		}
+		for _, name := range hp.Remove {
+			explicit.Insert(http.CanonicalHeaderKey(name))
+		}
+	}
+
+	applied := false
+	for _, h := range defaults.Set {
+		if !explicit.Has(http.CanonicalHeaderKey(h.Name)) {
+			merged.Set = append(merged.Set, h)
+			applied = true
+		}
+	}
+	for _, name := range defaults.Remove {
+		if !explicit.Has(http.CanonicalHeaderKey(name)) {
+			merged.Remove = append(merged.Remove, name)
+			applied = true
+		}
+	}
+
+	if !applied {
+		return hp, false
+	}
+	return &merged, true
+}
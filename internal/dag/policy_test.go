@@ -14,6 +14,7 @@
 package dag
 
 import (
+	"net"
 	"testing"
 	"time"
 
@@ -277,6 +278,30 @@ func TestTimeoutPolicy(t *testing.T) {
 				IdleTimeout: 900 * time.Second,
 			},
 		},
+		"websocket idle timeout": {
+			tp: &projcontour.TimeoutPolicy{
+				WebsocketIdle: "1h",
+			},
+			want: &TimeoutPolicy{
+				WebsocketIdleTimeout: time.Hour,
+			},
+		},
+		"max stream duration": {
+			tp: &projcontour.TimeoutPolicy{
+				MaxStreamDuration: "10m",
+			},
+			want: &TimeoutPolicy{
+				MaxStreamDuration: 10 * time.Minute,
+			},
+		},
+		"infinite max grpc timeout": {
+			tp: &projcontour.TimeoutPolicy{
+				MaxGrpcTimeout: "infinity",
+			},
+			want: &TimeoutPolicy{
+				MaxGrpcTimeout: -1,
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -287,6 +312,467 @@ func TestTimeoutPolicy(t *testing.T) {
 	}
 }
 
+func TestRegexRewritePolicy(t *testing.T) {
+	tests := map[string]struct {
+		rr      *projcontour.RegexRewrite
+		want    *RegexRewrite
+		wantErr bool
+	}{
+		"nil regex rewrite": {
+			rr:   nil,
+			want: nil,
+		},
+		"valid regex rewrite": {
+			rr: &projcontour.RegexRewrite{
+				Pattern:      "^/v1/(.*)/detail$",
+				Substitution: `/api/\1`,
+			},
+			want: &RegexRewrite{
+				Pattern:      "^/v1/(.*)/detail$",
+				Substitution: `/api/\1`,
+			},
+		},
+		"invalid pattern": {
+			rr: &projcontour.RegexRewrite{
+				Pattern:      "^/v1/(.*",
+				Substitution: "/api",
+			},
+			wantErr: true,
+		},
+		"missing capture group": {
+			rr: &projcontour.RegexRewrite{
+				Pattern:      "^/v1/(.*)$",
+				Substitution: `/api/\2`,
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := regexRewritePolicy(tc.rr)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestTracingPolicy(t *testing.T) {
+	tests := map[string]struct {
+		tp      *projcontour.TracingPolicy
+		want    *TracingPolicy
+		wantErr bool
+	}{
+		"nil tracing policy": {
+			tp:   nil,
+			want: nil,
+		},
+		"whole percentage": {
+			tp:   &projcontour.TracingPolicy{Sampling: "100"},
+			want: &TracingPolicy{Sampling: 100},
+		},
+		"fractional percentage": {
+			tp:   &projcontour.TracingPolicy{Sampling: "0.5"},
+			want: &TracingPolicy{Sampling: 0.5},
+		},
+		"not a number": {
+			tp:      &projcontour.TracingPolicy{Sampling: "half"},
+			wantErr: true,
+		},
+		"out of range": {
+			tp:      &projcontour.TracingPolicy{Sampling: "150"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tracingPolicy(tc.tp)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHSTSPolicy(t *testing.T) {
+	tests := map[string]struct {
+		hp      *projcontour.HSTSPolicy
+		want    *HSTSPolicy
+		wantErr bool
+	}{
+		"nil hsts policy": {
+			hp:   nil,
+			want: nil,
+		},
+		"max age only": {
+			hp:   &projcontour.HSTSPolicy{MaxAge: "1h"},
+			want: &HSTSPolicy{MaxAge: time.Hour},
+		},
+		"preload": {
+			hp: &projcontour.HSTSPolicy{
+				MaxAge:            "8760h",
+				IncludeSubdomains: true,
+				Preload:           true,
+			},
+			want: &HSTSPolicy{
+				MaxAge:            8760 * time.Hour,
+				IncludeSubdomains: true,
+				Preload:           true,
+			},
+		},
+		"invalid max age": {
+			hp:      &projcontour.HSTSPolicy{MaxAge: "forever"},
+			wantErr: true,
+		},
+		"negative max age": {
+			hp:      &projcontour.HSTSPolicy{MaxAge: "-1s"},
+			wantErr: true,
+		},
+		"preload without includeSubdomains": {
+			hp: &projcontour.HSTSPolicy{
+				MaxAge:  "8760h",
+				Preload: true,
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := hstsPolicy(tc.hp)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCanaryPolicy(t *testing.T) {
+	tests := map[string]struct {
+		cp      *projcontour.CanaryPolicy
+		want    *canaryOptions
+		wantErr bool
+	}{
+		"defaults": {
+			cp: &projcontour.CanaryPolicy{Percentage: 10},
+			want: &canaryOptions{
+				Header:     "X-Canary",
+				CookieName: "contour-canary",
+				Percentage: 10,
+			},
+		},
+		"all options": {
+			cp: &projcontour.CanaryPolicy{
+				Percentage:   50,
+				Header:       "x-beta",
+				CookieName:   "beta",
+				CookieMaxAge: "24h",
+			},
+			want: &canaryOptions{
+				Header:       "X-Beta",
+				CookieName:   "beta",
+				CookieMaxAge: 24 * time.Hour,
+				Percentage:   50,
+			},
+		},
+		"percentage out of range": {
+			cp:      &projcontour.CanaryPolicy{Percentage: 101},
+			wantErr: true,
+		},
+		"invalid header": {
+			cp:      &projcontour.CanaryPolicy{Header: "x canary"},
+			wantErr: true,
+		},
+		"invalid cookie name": {
+			cp:      &projcontour.CanaryPolicy{CookieName: "canary=1"},
+			wantErr: true,
+		},
+		"invalid cookie max age": {
+			cp:      &projcontour.CanaryPolicy{CookieMaxAge: "1ms"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := canaryPolicy(tc.cp)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFaultPolicy(t *testing.T) {
+	tests := map[string]struct {
+		fp      *projcontour.FaultInjectionPolicy
+		want    *FaultPolicy
+		wantErr bool
+	}{
+		"nil fault policy": {
+			fp:   nil,
+			want: nil,
+		},
+		"delay": {
+			fp: &projcontour.FaultInjectionPolicy{
+				Delay: &projcontour.FaultDelay{Duration: "500ms"},
+			},
+			want: &FaultPolicy{
+				Delay:           500 * time.Millisecond,
+				DelayPercentage: 100,
+			},
+		},
+		"http abort": {
+			fp: &projcontour.FaultInjectionPolicy{
+				Abort: &projcontour.FaultAbort{HTTPStatus: 502, Percentage: "0.5"},
+				Headers: []projcontour.HeaderCondition{{
+					Name:    "x-chaos",
+					Present: true,
+				}},
+			},
+			want: &FaultPolicy{
+				AbortStatus:     502,
+				AbortPercentage: 0.5,
+				HeaderConditions: []HeaderCondition{{
+					Name:      "x-chaos",
+					MatchType: "present",
+				}},
+			},
+		},
+		"grpc abort": {
+			fp: &projcontour.FaultInjectionPolicy{
+				Abort: &projcontour.FaultAbort{GRPCStatus: 16},
+			},
+			want: &FaultPolicy{
+				AbortStatus:     401,
+				AbortPercentage: 100,
+			},
+		},
+		"no faults": {
+			fp:      &projcontour.FaultInjectionPolicy{},
+			wantErr: true,
+		},
+		"invalid delay": {
+			fp: &projcontour.FaultInjectionPolicy{
+				Delay: &projcontour.FaultDelay{Duration: "-1s"},
+			},
+			wantErr: true,
+		},
+		"invalid percentage": {
+			fp: &projcontour.FaultInjectionPolicy{
+				Delay: &projcontour.FaultDelay{Duration: "1s", Percentage: "101"},
+			},
+			wantErr: true,
+		},
+		"http and grpc abort": {
+			fp: &projcontour.FaultInjectionPolicy{
+				Abort: &projcontour.FaultAbort{HTTPStatus: 503, GRPCStatus: 14},
+			},
+			wantErr: true,
+		},
+		"abort without status": {
+			fp: &projcontour.FaultInjectionPolicy{
+				Abort: &projcontour.FaultAbort{Percentage: "10"},
+			},
+			wantErr: true,
+		},
+		"unsupported grpc status": {
+			fp: &projcontour.FaultInjectionPolicy{
+				Abort: &projcontour.FaultAbort{GRPCStatus: 4},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := faultPolicy(tc.fp)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestIPFilterPolicy(t *testing.T) {
+	cidr := func(s string) net.IPNet {
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *ipnet
+	}
+
+	xff := &ClientAddressPolicy{NumTrustedHops: 1}
+
+	tests := map[string]struct {
+		allow         []projcontour.IPFilterPolicy
+		deny          []projcontour.IPFilterPolicy
+		clientAddress *ClientAddressPolicy
+		wantAllow     bool
+		want          []IPFilterRule
+		wantErr       bool
+	}{
+		"no policy": {},
+		"allow": {
+			allow: []projcontour.IPFilterPolicy{{
+				CIDR: "10.0.0.0/8",
+			}, {
+				Source: projcontour.IPFilterSourceRemote,
+				CIDR:   "2001:db8::/32",
+			}},
+			wantAllow: true,
+			want: []IPFilterRule{{
+				CIDR: cidr("10.0.0.0/8"),
+			}, {
+				Remote: true,
+				CIDR:   cidr("2001:db8::/32"),
+			}},
+		},
+		"deny bare addresses": {
+			deny: []projcontour.IPFilterPolicy{{
+				Source: projcontour.IPFilterSourcePeer,
+				CIDR:   "192.168.1.10",
+			}, {
+				CIDR: "::1",
+			}},
+			want: []IPFilterRule{{
+				CIDR: cidr("192.168.1.10/32"),
+			}, {
+				CIDR: cidr("::1/128"),
+			}},
+		},
+		"allow and deny": {
+			allow:   []projcontour.IPFilterPolicy{{CIDR: "10.0.0.0/8"}},
+			deny:    []projcontour.IPFilterPolicy{{CIDR: "10.1.0.0/16"}},
+			wantErr: true,
+		},
+		"invalid cidr": {
+			deny:    []projcontour.IPFilterPolicy{{CIDR: "10.0.0.0/33"}},
+			wantErr: true,
+		},
+		"remote with trusted hops": {
+			deny: []projcontour.IPFilterPolicy{{
+				Source: projcontour.IPFilterSourceRemote,
+				CIDR:   "192.168.0.0/16",
+			}, {
+				CIDR: "::1",
+			}},
+			clientAddress: xff,
+			want: []IPFilterRule{{
+				Remote:        true,
+				ClientAddress: xff,
+				CIDR:          cidr("192.168.0.0/16"),
+			}, {
+				CIDR: cidr("::1/128"),
+			}},
+		},
+		"remote without trusted hops": {
+			deny: []projcontour.IPFilterPolicy{{
+				Source: projcontour.IPFilterSourceRemote,
+				CIDR:   "192.168.0.0/16",
+			}},
+			clientAddress: &ClientAddressPolicy{SkipXFFAppend: true},
+			want: []IPFilterRule{{
+				Remote: true,
+				CIDR:   cidr("192.168.0.0/16"),
+			}},
+		},
+		"remote ipv6 with trusted hops": {
+			deny: []projcontour.IPFilterPolicy{{
+				Source: projcontour.IPFilterSourceRemote,
+				CIDR:   "2001:db8::/32",
+			}},
+			clientAddress: xff,
+			wantErr:       true,
+		},
+		"invalid source": {
+			deny: []projcontour.IPFilterPolicy{{
+				Source: "Forwarded",
+				CIDR:   "10.0.0.0/8",
+			}},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			allow, got, err := ipFilterPolicy(tc.allow, tc.deny, tc.clientAddress)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantAllow, allow)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestJWTVerificationPolicy(t *testing.T) {
+	auth := &JWTProvider{Name: "default/app/auth"}
+	partner := &JWTProvider{Name: "default/app/partner"}
+	providers := map[string]*JWTProvider{
+		"":        auth,
+		"auth":    auth,
+		"partner": partner,
+	}
+
+	tests := map[string]struct {
+		jv               *projcontour.JWTVerificationPolicy
+		providers        map[string]*JWTProvider
+		want             *JWTProvider
+		wantAllowMissing bool
+		wantErr          bool
+	}{
+		"nil policy uses the default provider": {
+			providers: providers,
+			want:      auth,
+		},
+		"nil policy without a default provider": {
+			providers: map[string]*JWTProvider{"partner": partner},
+		},
+		"require a provider": {
+			jv:        &projcontour.JWTVerificationPolicy{Require: "partner"},
+			providers: providers,
+			want:      partner,
+		},
+		"allow missing tokens": {
+			jv:               &projcontour.JWTVerificationPolicy{AllowMissing: true},
+			providers:        providers,
+			want:             auth,
+			wantAllowMissing: true,
+		},
+		"disabled": {
+			jv:        &projcontour.JWTVerificationPolicy{Disabled: true},
+			providers: providers,
+		},
+		"disabled and required": {
+			jv:        &projcontour.JWTVerificationPolicy{Disabled: true, Require: "auth"},
+			providers: providers,
+			wantErr:   true,
+		},
+		"unknown provider": {
+			jv:        &projcontour.JWTVerificationPolicy{Require: "missing"},
+			providers: providers,
+			wantErr:   true,
+		},
+		"no default provider": {
+			jv:        &projcontour.JWTVerificationPolicy{AllowMissing: true},
+			providers: map[string]*JWTProvider{"partner": partner},
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, allowMissing, err := jwtVerificationPolicy(tc.jv, tc.providers)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantAllowMissing, allowMissing)
+		})
+	}
+}
+
 func TestLoadBalancerPolicy(t *testing.T) {
 	tests := map[string]struct {
 		lbp  *projcontour.LoadBalancerPolicy
@@ -334,6 +820,147 @@ func TestLoadBalancerPolicy(t *testing.T) {
 	}
 }
 
+func TestMergeDefaults(t *testing.T) {
+	defaults := &projcontour.ProxyDefaultsSpec{
+		TimeoutPolicy: &projcontour.TimeoutPolicy{
+			Response: "10s",
+			Idle:     "1m",
+		},
+		RetryPolicy: &projcontour.RetryPolicy{
+			NumRetries: 3,
+		},
+		RequestHeadersPolicy: &projcontour.HeadersPolicy{
+			Set: []projcontour.HeaderValue{{
+				Name:  "X-Env",
+				Value: "production",
+			}, {
+				Name:  "X-Team",
+				Value: "platform",
+			}},
+			Remove: []string{"X-Debug"},
+		},
+		LoadBalancerPolicy: &projcontour.LoadBalancerPolicy{
+			Strategy: "Random",
+		},
+	}
+
+	tests := map[string]struct {
+		route       projcontour.Route
+		defaults    *projcontour.ProxyDefaultsSpec
+		want        projcontour.Route
+		wantApplied bool
+	}{
+		"no defaults": {
+			route: projcontour.Route{
+				TimeoutPolicy: &projcontour.TimeoutPolicy{
+					Response: "1s",
+				},
+			},
+			defaults: &projcontour.ProxyDefaultsSpec{},
+			want: projcontour.Route{
+				TimeoutPolicy: &projcontour.TimeoutPolicy{
+					Response: "1s",
+				},
+			},
+			wantApplied: false,
+		},
+		"route without policies": {
+			route:    projcontour.Route{},
+			defaults: defaults,
+			want: projcontour.Route{
+				TimeoutPolicy:        defaults.TimeoutPolicy,
+				RetryPolicy:          defaults.RetryPolicy,
+				RequestHeadersPolicy: defaults.RequestHeadersPolicy,
+				LoadBalancerPolicy:   defaults.LoadBalancerPolicy,
+			},
+			wantApplied: true,
+		},
+		"route policies take precedence": {
+			route: projcontour.Route{
+				TimeoutPolicy: &projcontour.TimeoutPolicy{
+					Response: "1s",
+				},
+				RetryPolicy: &projcontour.RetryPolicy{
+					NumRetries: 1,
+				},
+				RequestHeadersPolicy: &projcontour.HeadersPolicy{
+					Set: []projcontour.HeaderValue{{
+						Name:  "x-env",
+						Value: "staging",
+					}},
+					Remove: []string{"X-Team"},
+				},
+				LoadBalancerPolicy: &projcontour.LoadBalancerPolicy{
+					Strategy: "Cookie",
+				},
+			},
+			defaults: defaults,
+			want: projcontour.Route{
+				TimeoutPolicy: &projcontour.TimeoutPolicy{
+					Response: "1s",
+					Idle:     "1m",
+				},
+				RetryPolicy: &projcontour.RetryPolicy{
+					NumRetries: 1,
+				},
+				RequestHeadersPolicy: &projcontour.HeadersPolicy{
+					Set: []projcontour.HeaderValue{{
+						Name:  "x-env",
+						Value: "staging",
+					}},
+					Remove: []string{"X-Team", "X-Debug"},
+				},
+				LoadBalancerPolicy: &projcontour.LoadBalancerPolicy{
+					Strategy: "Cookie",
+				},
+			},
+			wantApplied: true,
+		},
+		"route sets every default": {
+			route: projcontour.Route{
+				TimeoutPolicy: &projcontour.TimeoutPolicy{
+					Response: "1s",
+					Idle:     "5s",
+				},
+				RetryPolicy: &projcontour.RetryPolicy{
+					NumRetries: 1,
+				},
+				RequestHeadersPolicy: &projcontour.HeadersPolicy{
+					Remove: []string{"X-Env", "X-Team", "X-Debug"},
+				},
+				LoadBalancerPolicy: &projcontour.LoadBalancerPolicy{
+					Strategy: "Cookie",
+				},
+			},
+			defaults: defaults,
+			want: projcontour.Route{
+				TimeoutPolicy: &projcontour.TimeoutPolicy{
+					Response: "1s",
+					Idle:     "5s",
+				},
+				RetryPolicy: &projcontour.RetryPolicy{
+					NumRetries: 1,
+				},
+				RequestHeadersPolicy: &projcontour.HeadersPolicy{
+					Remove: []string{"X-Env", "X-Team", "X-Debug"},
+				},
+				LoadBalancerPolicy: &projcontour.LoadBalancerPolicy{
+					Strategy: "Cookie",
+				},
+			},
+			wantApplied: false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, gotApplied := mergeDefaults(tc.route, tc.defaults)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantApplied, gotApplied)
+		})
+	}
+}
+
 func TestParseTimeout(t *testing.T) {
 	tests := map[string]struct {
 		duration string
@@ -27,10 +27,17 @@ import (
 // CACertificateKey is the key name for accessing TLS CA certificate bundles in Kubernetes Secrets.
 const CACertificateKey = "ca.crt"
 
+// BasicAuthKey is the key name for accessing htpasswd data in Kubernetes Secrets.
+const BasicAuthKey = "auth"
+
+// JWKSKey is the key name for accessing JSON Web Key Sets in Kubernetes Secrets.
+const JWKSKey = "jwks"
+
 // isValidSecret returns true if the secret is interesting and well
 // formed. TLS certificate/key pairs must be secrets of type
 // "kubernetes.io/tls". Certificate bundles may be "kubernetes.io/tls"
-// or generic (type "Opaque" or "") secrets.
+// or generic (type "Opaque" or "") secrets. htpasswd data and JSON
+// Web Key Sets must be generic secrets.
 func isValidSecret(secret *v1.Secret) (bool, error) {
 	switch secret.Type {
 	// We will accept TLS secrets that also have the 'ca.crt' payload.
@@ -53,7 +60,7 @@ func isValidSecret(secret *v1.Secret) (bool, error) {
 			return false, fmt.Errorf("invalid TLS private key: %v", err)
 		}
 
-	// Generic secrets may have a 'ca.crt' only.
+	// Generic secrets may have a 'ca.crt', 'auth' or 'jwks' key only.
 	case v1.SecretTypeOpaque, "":
 		if _, ok := secret.Data[v1.TLSCertKey]; ok {
 			return false, nil
@@ -63,7 +70,9 @@ func isValidSecret(secret *v1.Secret) (bool, error) {
 			return false, nil
 		}
 
-		if data := secret.Data[CACertificateKey]; len(data) == 0 {
+		if len(secret.Data[CACertificateKey]) == 0 &&
+			len(secret.Data[BasicAuthKey]) == 0 &&
+			len(secret.Data[JWKSKey]) == 0 {
 			return false, nil
 		}
 
@@ -194,3 +203,42 @@ func validatePrivateKey(data []byte) error {
 		return errors.New("multiple private keys")
 	}
 }
+
+// certificateCovers returns an error unless the leaf certificate
+// in data has a DNS subject alt name that is valid for every name.
+func certificateCovers(data []byte, names ...string) error {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return errors.New("failed to locate certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if !matchesSubjectAltName(cert.DNSNames, name) {
+			return fmt.Errorf("certificate is not valid for %q", name)
+		}
+	}
+	return nil
+}
+
+// matchesSubjectAltName returns true if one of the sans is valid
+// for name. A wildcard san is valid for a name with exactly one
+// label in place of the wildcard, or for the same wildcard.
+func matchesSubjectAltName(sans []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, san := range sans {
+		san = strings.ToLower(san)
+		if san == name {
+			return true
+		}
+		if strings.HasPrefix(san, "*.") && !strings.HasPrefix(name, "*.") {
+			if i := strings.Index(name, "."); i > 0 && name[i:] == san[1:] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -69,6 +69,64 @@ func TestIsValidSecret(t *testing.T) {
 	}
 }
 
+func TestCertificateCovers(t *testing.T) {
+	tests := map[string]struct {
+		cert    string
+		names   []string
+		wantErr bool
+	}{
+		"exact names": {
+			cert:  EC_CERTIFICATE,
+			names: []string{"example.com", "WWW.example.com"},
+		},
+		"name not in sans": {
+			cert:    EC_CERTIFICATE,
+			names:   []string{"example.com", "api.example.com"},
+			wantErr: true,
+		},
+		"no sans": {
+			cert:    CERTIFICATE,
+			names:   []string{"boring-wozniak.example.com"},
+			wantErr: true,
+		},
+		"not a certificate": {
+			cert:    "hello",
+			names:   []string{"example.com"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := certificateCovers([]byte(tc.cert), tc.names...)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestMatchesSubjectAltName(t *testing.T) {
+	tests := map[string]struct {
+		sans []string
+		name string
+		want bool
+	}{
+		"exact":                       {sans: []string{"www.example.com"}, name: "www.example.com", want: true},
+		"case insensitive":            {sans: []string{"WWW.example.com"}, name: "www.EXAMPLE.com", want: true},
+		"wildcard san":                {sans: []string{"*.example.com"}, name: "www.example.com", want: true},
+		"wildcard san and name":       {sans: []string{"*.example.com"}, name: "*.example.com", want: true},
+		"wildcard san two labels":     {sans: []string{"*.example.com"}, name: "a.b.example.com", want: false},
+		"wildcard san apex":           {sans: []string{"*.example.com"}, name: "example.com", want: false},
+		"wildcard name and exact san": {sans: []string{"www.example.com"}, name: "*.example.com", want: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := matchesSubjectAltName(tc.sans, tc.name)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
 const (
 	// generated by https://www.selfsignedcertificate.com
 	CERTIFICATE = `-----BEGIN CERTIFICATE-----
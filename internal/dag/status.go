@@ -21,11 +21,18 @@ import (
 )
 
 // Status contains the status for an HTTPProxy (valid / invalid / orphan, etc)
+// ReasonFQDNPolicyViolation is the reason of the status of a root
+// HTTPProxy that uses a name an FQDNPolicy doesn't permit it to.
+const ReasonFQDNPolicyViolation = "FQDNPolicyViolation"
+
 type Status struct {
 	Object      k8s.Object
 	Status      string
 	Description string
 	Vhost       string
+	// Reason classifies some invalid statuses, such as
+	// ReasonFQDNPolicyViolation, for metrics.
+	Reason string
 }
 
 type StatusWriter struct {
@@ -73,6 +80,7 @@ func (sw *StatusWriter) commit(osw *ObjectStatusWriter) {
 			Status:      osw.values["status"],
 			Description: osw.values["description"],
 			Vhost:       osw.values["vhost"],
+			Reason:      osw.values["reason"],
 		}
 	}
 }
@@ -85,10 +93,30 @@ func (osw *ObjectStatusWriter) SetInvalid(format string, args ...interface{}) {
 	osw.WithValue("description", fmt.Sprintf(format, args...)).WithValue("status", k8s.StatusInvalid)
 }
 
+// SetWarning records a problem with the object that doesn't make it
+// invalid. The last warning is reported in the description of the
+// object if it is valid.
+func (osw *ObjectStatusWriter) SetWarning(format string, args ...interface{}) {
+	osw.WithValue("warning", fmt.Sprintf(format, args...))
+}
+
+// SetDefaults records the defaults that were applied to the object,
+// which are reported in the description of the object if it is valid.
+func (osw *ObjectStatusWriter) SetDefaults(defaults string) {
+	osw.WithValue("defaults", defaults)
+}
+
 func (osw *ObjectStatusWriter) SetValid() {
 	switch osw.obj.(type) {
 	case *projcontour.HTTPProxy:
-		osw.WithValue("description", "valid HTTPProxy").WithValue("status", k8s.StatusValid)
+		description := "valid HTTPProxy"
+		if defaults, ok := osw.values["defaults"]; ok {
+			description += "; defaults: " + defaults
+		}
+		if warning, ok := osw.values["warning"]; ok {
+			description += "; warning: " + warning
+		}
+		osw.WithValue("description", description).WithValue("status", k8s.StatusValid)
 	default:
 		// not a supported type
 	}
@@ -101,6 +129,10 @@ func (osw *ObjectStatusWriter) SetValid() {
 func (osw *ObjectStatusWriter) WithObject(obj k8s.Object) (_ *ObjectStatusWriter, commit func()) {
 	m := make(map[string]string)
 	for k, v := range osw.values {
+		if k == "warning" || k == "defaults" {
+			// warnings and defaults are about the parent only.
+			continue
+		}
 		m[k] = v
 	}
 	nosw := &ObjectStatusWriter{
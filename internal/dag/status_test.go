@@ -619,6 +619,46 @@ func TestDAGStatus(t *testing.T) {
 		},
 	}
 
+	// proxyInvalidAlias is invalid because it contains a wildcarded alias
+	proxyInvalidAlias := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "example",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn:    "*.example.com",
+				Aliases: []string{"www.*.com"},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "home",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	// proxyDuplicateAlias is invalid because it repeats its fqdn as an alias
+	proxyDuplicateAlias := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "example",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn:    "example.com",
+				Aliases: []string{"example.com"},
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "home",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
 	// proxy16 is invalid because it references an invalid service
 	proxy16 := &projcontour.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1566,6 +1606,315 @@ func TestDAGStatus(t *testing.T) {
 		},
 	}
 
+	appProtocol := "https"
+	serviceKuardAppProtocol := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "roots",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:        "http",
+				Protocol:    "TCP",
+				Port:        8080,
+				TargetPort:  intstr.FromInt(8080),
+				AppProtocol: &appProtocol,
+			}},
+		},
+	}
+
+	h2c := "h2c"
+	proxyConflictingProtocol := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "protocol",
+			Namespace: "roots",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name:     "kuard",
+					Port:     8080,
+					Protocol: &h2c,
+				}},
+			}},
+		},
+	}
+
+	externalBackendWithHostname := &projcontour.ExternalBackend{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vms",
+			Namespace: "roots",
+		},
+		Spec: projcontour.ExternalBackendSpec{
+			Endpoints: []projcontour.ExternalBackendEndpoint{{
+				Address: "vm.example.com",
+				Port:    8080,
+			}},
+		},
+	}
+
+	proxyExternalBackend := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "roots",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "vms",
+					Kind: "ExternalBackend",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxyDefaultsRoots := &projcontour.ProxyDefaults{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "defaults",
+			Namespace: "roots",
+		},
+		Spec: projcontour.ProxyDefaultsSpec{
+			TimeoutPolicy: &projcontour.TimeoutPolicy{
+				Response: "10s",
+			},
+		},
+	}
+
+	clusterProxyDefaults := &projcontour.ClusterProxyDefaults{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster",
+		},
+		Spec: projcontour.ProxyDefaultsSpec{
+			RetryPolicy: &projcontour.RetryPolicy{
+				NumRetries: 3,
+			},
+		},
+	}
+
+	proxyDefaulted := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "defaulted",
+			Namespace: "roots",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxyBlogAdmitRoots := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "blog",
+			Namespace: serviceGreenMarketing.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: serviceGreenMarketing.Name,
+					Port: 80,
+				}},
+			}},
+			IncludeAdmissionPolicy: &projcontour.IncludeAdmissionPolicy{
+				Namespaces: []string{"roots"},
+			},
+		},
+	}
+
+	proxyBlogAdmitTeams := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "blog",
+			Namespace: serviceGreenMarketing.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: serviceGreenMarketing.Name,
+					Port: 80,
+				}},
+			}},
+			IncludeAdmissionPolicy: &projcontour.IncludeAdmissionPolicy{
+				Namespaces: []string{"teams"},
+			},
+		},
+	}
+
+	proxyBlogAdmitFqdn := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "blog",
+			Namespace: serviceGreenMarketing.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: serviceGreenMarketing.Name,
+					Port: 80,
+				}},
+			}},
+			IncludeAdmissionPolicy: &projcontour.IncludeAdmissionPolicy{
+				Fqdns: []string{"blog.containersteve.com"},
+			},
+		},
+	}
+
+	proxyBlogAdmitFqdnUpper := &projcontour.HTTPProxy{
+		ObjectMeta: proxyBlogAdmitFqdn.ObjectMeta,
+		Spec: projcontour.HTTPProxySpec{
+			Routes: proxyBlogAdmitFqdn.Spec.Routes,
+			IncludeAdmissionPolicy: &projcontour.IncludeAdmissionPolicy{
+				Fqdns: []string{"Blog.ContainerSteve.com"},
+			},
+		},
+	}
+
+	proxyTCPRootMarketing := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tcproot",
+			Namespace: serviceKuard.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "tcpproxy.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: secretRootsNS.Name,
+				},
+			},
+			TCPProxy: &projcontour.TCPProxy{
+				Include: &projcontour.TCPProxyInclude{
+					Name:      "tcpchild",
+					Namespace: serviceGreenMarketing.Namespace,
+				},
+			},
+		},
+	}
+
+	proxyTCPChildAdmitTeams := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tcpchild",
+			Namespace: serviceGreenMarketing.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			TCPProxy: &projcontour.TCPProxy{
+				Services: []projcontour.Service{{
+					Name: serviceGreenMarketing.Name,
+					Port: 80,
+				}},
+			},
+			IncludeAdmissionPolicy: &projcontour.IncludeAdmissionPolicy{
+				Namespaces: []string{"teams"},
+			},
+		},
+	}
+
+	proxyDefaultsAdmitTeams := &projcontour.ProxyDefaults{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "defaults",
+			Namespace: serviceGreenMarketing.Namespace,
+		},
+		Spec: projcontour.ProxyDefaultsSpec{
+			IncludeAdmissionPolicy: &projcontour.IncludeAdmissionPolicy{
+				Namespaces: []string{"teams"},
+			},
+		},
+	}
+
+	fqdnPolicyTeams := &projcontour.FQDNPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "teams",
+		},
+		Spec: projcontour.FQDNPolicySpec{
+			Rules: []projcontour.FQDNRule{{
+				Fqdn:       "*.team-a.example.com",
+				Namespaces: []string{"roots"},
+			}, {
+				Fqdn:       "shared.team-a.example.com",
+				Namespaces: []string{"marketing"},
+			}},
+		},
+	}
+
+	serviceKuardMarketing := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "marketing",
+		},
+		Spec: serviceKuard.Spec,
+	}
+
+	proxyRootsTeamA := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api",
+			Namespace: "roots",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "api.team-a.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	proxyMarketingClaimsTeamA := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api",
+			Namespace: "marketing",
+		},
+		Spec: proxyRootsTeamA.Spec,
+	}
+
+	proxyMarketingShared := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "shared",
+			Namespace: "marketing",
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "shared.team-a.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	wildcard := func(fqdn string) *projcontour.HTTPProxy {
+		return &projcontour.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "wildcard",
+				Namespace: "marketing",
+			},
+			Spec: projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: fqdn,
+				},
+				Routes: proxyMarketingShared.Spec.Routes,
+			},
+		}
+	}
+	proxyMarketingWildcard := wildcard("*.example.com")
+	proxyMarketingWildcardOther := wildcard("*.other.example.com")
+
 	tests := map[string]struct {
 		objs                []interface{}
 		fallbackCertificate *k8s.FullName
@@ -1755,6 +2104,18 @@ func TestDAGStatus(t *testing.T) {
 				{Name: proxy15.Name, Namespace: proxy15.Namespace}: {Object: proxy15, Status: "invalid", Description: `Spec.VirtualHost.Fqdn "example.*.com" cannot use wildcards`, Vhost: "example.*.com"},
 			},
 		},
+		"proxy invalid alias contains wildcard": {
+			objs: []interface{}{proxyInvalidAlias},
+			want: map[k8s.FullName]Status{
+				{Name: proxyInvalidAlias.Name, Namespace: proxyInvalidAlias.Namespace}: {Object: proxyInvalidAlias, Status: "invalid", Description: `Spec.VirtualHost.Aliases "www.*.com" cannot use wildcards`, Vhost: "*.example.com"},
+			},
+		},
+		"proxy alias duplicates fqdn": {
+			objs: []interface{}{proxyDuplicateAlias},
+			want: map[k8s.FullName]Status{
+				{Name: proxyDuplicateAlias.Name, Namespace: proxyDuplicateAlias.Namespace}: {Object: proxyDuplicateAlias, Status: "invalid", Description: `Spec.VirtualHost.Aliases "example.com" is duplicated`, Vhost: "example.com"},
+			},
+		},
 		"proxy missing service shows invalid status": {
 			objs: []interface{}{proxy16},
 			want: map[k8s.FullName]Status{
@@ -2103,6 +2464,165 @@ func TestDAGStatus(t *testing.T) {
 				{Name: fallbackCertificate.Name, Namespace: fallbackCertificate.Namespace}: {Object: fallbackCertificate, Status: "invalid", Description: "Spec.Virtualhost.TLS enabled fallback but the fallback Certificate Secret is not configured in Contour configuration file", Vhost: "example.com"},
 			},
 		},
+		"valid HTTPProxy with a protocol that conflicts with appProtocol": {
+			objs: []interface{}{proxyConflictingProtocol, serviceKuardAppProtocol},
+			want: map[k8s.FullName]Status{
+				{Name: proxyConflictingProtocol.Name, Namespace: proxyConflictingProtocol.Namespace}: {
+					Object:      proxyConflictingProtocol,
+					Status:      "valid",
+					Description: `valid HTTPProxy; warning: service "kuard": protocol "h2c" from HTTPProxy overrides protocol "tls" from appProtocol`,
+					Vhost:       "example.com",
+				},
+			},
+		},
+		"invalid HTTPProxy with an externalbackend endpoint that isn't an IP address": {
+			objs: []interface{}{proxyExternalBackend, externalBackendWithHostname},
+			want: map[k8s.FullName]Status{
+				{Name: proxyExternalBackend.Name, Namespace: proxyExternalBackend.Namespace}: {
+					Object:      proxyExternalBackend,
+					Status:      "invalid",
+					Description: `ExternalBackend [vms:8080] is invalid: endpoint address "vm.example.com" is not an IP address`,
+					Vhost:       "example.com",
+				},
+			},
+		},
+		"valid HTTPProxy with namespace and cluster defaults applied": {
+			objs: []interface{}{proxyDefaulted, serviceKuard, proxyDefaultsRoots, clusterProxyDefaults},
+			want: map[k8s.FullName]Status{
+				{Name: proxyDefaulted.Name, Namespace: proxyDefaulted.Namespace}: {
+					Object:      proxyDefaulted,
+					Status:      "valid",
+					Description: "valid HTTPProxy; defaults: ProxyDefaults roots/defaults, ClusterProxyDefaults cluster",
+					Vhost:       "example.com",
+				},
+			},
+		},
+		"valid HTTPProxy with defaults from another namespace": {
+			objs: []interface{}{proxyDefaulted, serviceKuard, &projcontour.ProxyDefaults{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "defaults",
+					Namespace: "other",
+				},
+				Spec: proxyDefaultsRoots.Spec,
+			}},
+			want: map[k8s.FullName]Status{
+				{Name: proxyDefaulted.Name, Namespace: proxyDefaulted.Namespace}: {Object: proxyDefaulted, Status: "valid", Description: "valid HTTPProxy", Vhost: "example.com"},
+			},
+		},
+		"include admitted by namespace": {
+			objs: []interface{}{proxy22, proxyBlogAdmitRoots, serviceGreenMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxy22.Name, Namespace: proxy22.Namespace}:                         {Object: proxy22, Status: "valid", Description: "valid HTTPProxy", Vhost: "blog.containersteve.com"},
+				{Name: proxyBlogAdmitRoots.Name, Namespace: proxyBlogAdmitRoots.Namespace}: {Object: proxyBlogAdmitRoots, Status: "valid", Description: "valid HTTPProxy"},
+			},
+		},
+		"include admitted by fqdn": {
+			objs: []interface{}{proxy22, proxyBlogAdmitFqdn, serviceGreenMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxy22.Name, Namespace: proxy22.Namespace}:                       {Object: proxy22, Status: "valid", Description: "valid HTTPProxy", Vhost: "blog.containersteve.com"},
+				{Name: proxyBlogAdmitFqdn.Name, Namespace: proxyBlogAdmitFqdn.Namespace}: {Object: proxyBlogAdmitFqdn, Status: "valid", Description: "valid HTTPProxy"},
+			},
+		},
+		"include admitted by fqdn in another case": {
+			objs: []interface{}{proxy22, proxyBlogAdmitFqdnUpper, serviceGreenMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxy22.Name, Namespace: proxy22.Namespace}:                                 {Object: proxy22, Status: "valid", Description: "valid HTTPProxy", Vhost: "blog.containersteve.com"},
+				{Name: proxyBlogAdmitFqdnUpper.Name, Namespace: proxyBlogAdmitFqdnUpper.Namespace}: {Object: proxyBlogAdmitFqdnUpper, Status: "valid", Description: "valid HTTPProxy"},
+			},
+		},
+		"include not admitted by include admission policy": {
+			objs: []interface{}{proxy22, proxyBlogAdmitTeams, serviceGreenMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxy22.Name, Namespace: proxy22.Namespace}: {
+					Object:      proxy22,
+					Status:      "invalid",
+					Description: "include marketing/blog: not admitted by its include admission policy",
+					Vhost:       "blog.containersteve.com",
+				},
+				{Name: proxyBlogAdmitTeams.Name, Namespace: proxyBlogAdmitTeams.Namespace}: {Object: proxyBlogAdmitTeams, Status: "orphaned", Description: "this HTTPProxy is not part of a delegation chain from a root HTTPProxy"},
+			},
+		},
+		"include not admitted by include admission policy of namespace defaults": {
+			objs: []interface{}{proxy22, proxyBlogMarketing, proxyDefaultsAdmitTeams, serviceGreenMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxy22.Name, Namespace: proxy22.Namespace}: {
+					Object:      proxy22,
+					Status:      "invalid",
+					Description: "include marketing/blog: not admitted by its include admission policy",
+					Vhost:       "blog.containersteve.com",
+				},
+				{Name: proxyBlogMarketing.Name, Namespace: proxyBlogMarketing.Namespace}: {Object: proxyBlogMarketing, Status: "orphaned", Description: "this HTTPProxy is not part of a delegation chain from a root HTTPProxy"},
+			},
+		},
+		"tcpproxy include not admitted by include admission policy": {
+			objs: []interface{}{proxyTCPRootMarketing, proxyTCPChildAdmitTeams, serviceGreenMarketing, secretRootsNS},
+			want: map[k8s.FullName]Status{
+				{Name: proxyTCPRootMarketing.Name, Namespace: proxyTCPRootMarketing.Namespace}: {
+					Object:      proxyTCPRootMarketing,
+					Status:      "invalid",
+					Description: "tcpproxy: include marketing/tcpchild: not admitted by its include admission policy",
+					Vhost:       "tcpproxy.example.com",
+				},
+				{Name: proxyTCPChildAdmitTeams.Name, Namespace: proxyTCPChildAdmitTeams.Namespace}: {Object: proxyTCPChildAdmitTeams, Status: "orphaned", Description: "this HTTPProxy is not part of a delegation chain from a root HTTPProxy"},
+			},
+		},
+		"include admission policy of child overrides namespace defaults": {
+			objs: []interface{}{proxy22, proxyBlogAdmitRoots, proxyDefaultsAdmitTeams, serviceGreenMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxy22.Name, Namespace: proxy22.Namespace}:                         {Object: proxy22, Status: "valid", Description: "valid HTTPProxy", Vhost: "blog.containersteve.com"},
+				{Name: proxyBlogAdmitRoots.Name, Namespace: proxyBlogAdmitRoots.Namespace}: {Object: proxyBlogAdmitRoots, Status: "valid", Description: "valid HTTPProxy"},
+			},
+		},
+		"root permitted by fqdnpolicy": {
+			objs: []interface{}{fqdnPolicyTeams, proxyRootsTeamA, serviceKuard},
+			want: map[k8s.FullName]Status{
+				{Name: proxyRootsTeamA.Name, Namespace: proxyRootsTeamA.Namespace}: {Object: proxyRootsTeamA, Status: "valid", Description: "valid HTTPProxy", Vhost: "api.team-a.example.com"},
+			},
+		},
+		"root not permitted by fqdnpolicy does not conflict with permitted root": {
+			objs: []interface{}{fqdnPolicyTeams, proxyRootsTeamA, serviceKuard, proxyMarketingClaimsTeamA, serviceKuardMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxyRootsTeamA.Name, Namespace: proxyRootsTeamA.Namespace}: {Object: proxyRootsTeamA, Status: "valid", Description: "valid HTTPProxy", Vhost: "api.team-a.example.com"},
+				{Name: proxyMarketingClaimsTeamA.Name, Namespace: proxyMarketingClaimsTeamA.Namespace}: {
+					Object:      proxyMarketingClaimsTeamA,
+					Status:      "invalid",
+					Description: `fqdn "api.team-a.example.com" is not permitted in namespace "marketing" by FQDNPolicy teams`,
+					Vhost:       "api.team-a.example.com",
+					Reason:      ReasonFQDNPolicyViolation,
+				},
+			},
+		},
+		"root permitted by most specific fqdnpolicy rule": {
+			objs: []interface{}{fqdnPolicyTeams, proxyMarketingShared, serviceKuardMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxyMarketingShared.Name, Namespace: proxyMarketingShared.Namespace}: {Object: proxyMarketingShared, Status: "valid", Description: "valid HTTPProxy", Vhost: "shared.team-a.example.com"},
+			},
+		},
+		"wildcard root with names beneath not permitted by fqdnpolicy": {
+			objs: []interface{}{fqdnPolicyTeams, proxyMarketingWildcard, serviceKuardMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxyMarketingWildcard.Name, Namespace: proxyMarketingWildcard.Namespace}: {
+					Object:      proxyMarketingWildcard,
+					Status:      "invalid",
+					Description: `fqdn "*.example.com" includes names that are not permitted in namespace "marketing" by FQDNPolicy teams`,
+					Vhost:       "*.example.com",
+					Reason:      ReasonFQDNPolicyViolation,
+				},
+			},
+		},
+		"wildcard root without names beneath in fqdnpolicy": {
+			objs: []interface{}{fqdnPolicyTeams, proxyMarketingWildcardOther, serviceKuardMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxyMarketingWildcardOther.Name, Namespace: proxyMarketingWildcardOther.Namespace}: {Object: proxyMarketingWildcardOther, Status: "valid", Description: "valid HTTPProxy", Vhost: "*.other.example.com"},
+			},
+		},
+		"root with fqdn not matched by fqdnpolicy": {
+			objs: []interface{}{fqdnPolicyTeams, proxy22, proxyBlogMarketing, serviceGreenMarketing},
+			want: map[k8s.FullName]Status{
+				{Name: proxy22.Name, Namespace: proxy22.Namespace}:                       {Object: proxy22, Status: "valid", Description: "valid HTTPProxy", Vhost: "blog.containersteve.com"},
+				{Name: proxyBlogMarketing.Name, Namespace: proxyBlogMarketing.Namespace}: {Object: proxyBlogMarketing, Status: "valid", Description: "valid HTTPProxy"},
+			},
+		},
 		"fallback certificate requested and clientValidation also configured": {
 			objs: []interface{}{fallbackCertificateWithClientValidation, fallbackSecret, secretRootsNS, serviceHome},
 			want: map[k8s.FullName]Status{
@@ -25,29 +25,30 @@ import (
 //used for specifying fields for Envoy to log when JSON logging is enabled.
 //Only fields specified in this map may be used for JSON logging.
 var JSONFields = map[string]string{
-	"@timestamp":                "%START_TIME%",
-	"ts":                        "%START_TIME%",
-	"authority":                 "%REQ(:AUTHORITY)%",
-	"bytes_received":            "%BYTES_RECEIVED%",
-	"bytes_sent":                "%BYTES_SENT%",
-	"downstream_local_address":  "%DOWNSTREAM_LOCAL_ADDRESS%",
-	"downstream_remote_address": "%DOWNSTREAM_REMOTE_ADDRESS%",
-	"duration":                  "%DURATION%",
-	"method":                    "%REQ(:METHOD)%",
-	"path":                      "%REQ(X-ENVOY-ORIGINAL-PATH?:PATH)%",
-	"protocol":                  "%PROTOCOL%",
-	"request_id":                "%REQ(X-REQUEST-ID)%",
-	"requested_server_name":     "%REQUESTED_SERVER_NAME%",
-	"response_code":             "%RESPONSE_CODE%",
-	"response_flags":            "%RESPONSE_FLAGS%",
-	"uber_trace_id":             "%REQ(UBER-TRACE-ID)%",
-	"upstream_cluster":          "%UPSTREAM_CLUSTER%",
-	"upstream_host":             "%UPSTREAM_HOST%",
-	"upstream_local_address":    "%UPSTREAM_LOCAL_ADDRESS%",
-	"upstream_service_time":     "%RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)%",
-	"user_agent":                "%REQ(USER-AGENT)%",
-	"x_forwarded_for":           "%REQ(X-FORWARDED-FOR)%",
-	"x_trace_id":                "%REQ(X-TRACE-ID)%",
+	"@timestamp":                       "%START_TIME%",
+	"ts":                               "%START_TIME%",
+	"authority":                        "%REQ(:AUTHORITY)%",
+	"bytes_received":                   "%BYTES_RECEIVED%",
+	"bytes_sent":                       "%BYTES_SENT%",
+	"downstream_direct_remote_address": "%DOWNSTREAM_DIRECT_REMOTE_ADDRESS%",
+	"downstream_local_address":         "%DOWNSTREAM_LOCAL_ADDRESS%",
+	"downstream_remote_address":        "%DOWNSTREAM_REMOTE_ADDRESS%",
+	"duration":                         "%DURATION%",
+	"method":                           "%REQ(:METHOD)%",
+	"path":                             "%REQ(X-ENVOY-ORIGINAL-PATH?:PATH)%",
+	"protocol":                         "%PROTOCOL%",
+	"request_id":                       "%REQ(X-REQUEST-ID)%",
+	"requested_server_name":            "%REQUESTED_SERVER_NAME%",
+	"response_code":                    "%RESPONSE_CODE%",
+	"response_flags":                   "%RESPONSE_FLAGS%",
+	"uber_trace_id":                    "%REQ(UBER-TRACE-ID)%",
+	"upstream_cluster":                 "%UPSTREAM_CLUSTER%",
+	"upstream_host":                    "%UPSTREAM_HOST%",
+	"upstream_local_address":           "%UPSTREAM_LOCAL_ADDRESS%",
+	"upstream_service_time":            "%RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)%",
+	"user_agent":                       "%REQ(USER-AGENT)%",
+	"x_forwarded_for":                  "%REQ(X-FORWARDED-FOR)%",
+	"x_trace_id":                       "%REQ(X-TRACE-ID)%",
 }
 
 // DefaultFields are fields that will be included by default when JSON logging is enabled.
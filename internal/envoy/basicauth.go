@@ -0,0 +1,368 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	envoy_config_filter_http_lua_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/lua/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// basicAuthScript authenticates requests to the routes whose
+// metadata has a basic_auth entry, which holds the name of the
+// htpasswd Secret and the realm reported to clients that have not
+// authenticated. FilterBasicAuth prepends the credentials table,
+// which maps each Secret to the password hashes of its users.
+const basicAuthScript = `
+local bit = require("bit")
+local band, bor, bxor, bnot = bit.band, bit.bor, bit.bxor, bit.bnot
+local lshift, rshift, rol, tobit = bit.lshift, bit.rshift, bit.rol, bit.tobit
+
+local base64 = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+local function base64decode(s)
+  local out, acc, bits = {}, 0, 0
+  for i = 1, #s do
+    local v = base64:find(s:sub(i, i), 1, true)
+    if v == nil then
+      break
+    end
+    acc = bor(lshift(acc, 6), v - 1)
+    bits = bits + 6
+    if bits >= 8 then
+      bits = bits - 8
+      out[#out + 1] = string.char(band(rshift(acc, bits), 0xff))
+      acc = band(acc, lshift(1, bits) - 1)
+    end
+  end
+  return table.concat(out)
+end
+
+local function base64encode(s)
+  local out = {}
+  for i = 1, #s, 3 do
+    local a, b, c = s:byte(i, i + 2)
+    local n = bor(lshift(a, 16), lshift(b or 0, 8), c or 0)
+    local chars = math.min(#s - i + 1, 3) + 1
+    for j = 1, 4 do
+      if j <= chars then
+        local v = band(rshift(n, 6 * (4 - j)), 0x3f) + 1
+        out[#out + 1] = base64:sub(v, v)
+      else
+        out[#out + 1] = "="
+      end
+    end
+  end
+  return table.concat(out)
+end
+
+-- pad returns the supplied message padded to a multiple of 64 bytes,
+-- ending with its length in bits in the supplied byte order.
+local function pad(msg, bigendian)
+  local len = #msg
+  local bits = len * 8
+  local length = {
+    band(bits, 0xff), band(rshift(bits, 8), 0xff),
+    band(rshift(bits, 16), 0xff), band(rshift(bits, 24), 0xff),
+    0, 0, 0, 0,
+  }
+  if bigendian then
+    length = {0, 0, 0, 0, length[4], length[3], length[2], length[1]}
+  end
+  return msg .. "\128" .. string.rep("\0", (55 - len) % 64) .. string.char(unpack(length))
+end
+
+local function sha1(msg)
+  local h0, h1, h2, h3, h4 = 0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476, 0xc3d2e1f0
+  msg = pad(msg, true)
+
+  local w = {}
+  for chunk = 1, #msg, 64 do
+    for i = 0, 15 do
+      local a, b, c, d = msg:byte(chunk + i * 4, chunk + i * 4 + 3)
+      w[i] = bor(lshift(a, 24), lshift(b, 16), lshift(c, 8), d)
+    end
+    for i = 16, 79 do
+      w[i] = rol(bxor(w[i - 3], w[i - 8], w[i - 14], w[i - 16]), 1)
+    end
+
+    local a, b, c, d, e = h0, h1, h2, h3, h4
+    for i = 0, 79 do
+      local f, k
+      if i < 20 then
+        f, k = bor(band(b, c), band(bnot(b), d)), 0x5a827999
+      elseif i < 40 then
+        f, k = bxor(b, c, d), 0x6ed9eba1
+      elseif i < 60 then
+        f, k = bor(band(b, c), band(b, d), band(c, d)), 0x8f1bbcdc
+      else
+        f, k = bxor(b, c, d), 0xca62c1d6
+      end
+      local t = tobit(rol(a, 5) + f + e + k + w[i])
+      e, d, c, b, a = d, c, rol(b, 30), a, t
+    end
+
+    h0, h1, h2, h3, h4 = tobit(h0 + a), tobit(h1 + b), tobit(h2 + c), tobit(h3 + d), tobit(h4 + e)
+  end
+
+  local out = {}
+  for _, h in ipairs({h0, h1, h2, h3, h4}) do
+    out[#out + 1] = string.char(band(rshift(h, 24), 0xff), band(rshift(h, 16), 0xff), band(rshift(h, 8), 0xff), band(h, 0xff))
+  end
+  return table.concat(out)
+end
+
+local md5_s = {
+  7, 12, 17, 22, 7, 12, 17, 22, 7, 12, 17, 22, 7, 12, 17, 22,
+  5, 9, 14, 20, 5, 9, 14, 20, 5, 9, 14, 20, 5, 9, 14, 20,
+  4, 11, 16, 23, 4, 11, 16, 23, 4, 11, 16, 23, 4, 11, 16, 23,
+  6, 10, 15, 21, 6, 10, 15, 21, 6, 10, 15, 21, 6, 10, 15, 21,
+}
+
+local md5_k = {
+  0xd76aa478, 0xe8c7b756, 0x242070db, 0xc1bdceee, 0xf57c0faf, 0x4787c62a, 0xa8304613, 0xfd469501,
+  0x698098d8, 0x8b44f7af, 0xffff5bb1, 0x895cd7be, 0x6b901122, 0xfd987193, 0xa679438e, 0x49b40821,
+  0xf61e2562, 0xc040b340, 0x265e5a51, 0xe9b6c7aa, 0xd62f105d, 0x02441453, 0xd8a1e681, 0xe7d3fbc8,
+  0x21e1cde6, 0xc33707d6, 0xf4d50d87, 0x455a14ed, 0xa9e3e905, 0xfcefa3f8, 0x676f02d9, 0x8d2a4c8a,
+  0xfffa3942, 0x8771f681, 0x6d9d6122, 0xfde5380c, 0xa4beea44, 0x4bdecfa9, 0xf6bb4b60, 0xbebfbc70,
+  0x289b7ec6, 0xeaa127fa, 0xd4ef3085, 0x04881d05, 0xd9d4d039, 0xe6db99e5, 0x1fa27cf8, 0xc4ac5665,
+  0xf4292244, 0x432aff97, 0xab9423a7, 0xfc93a039, 0x655b59c3, 0x8f0ccc92, 0xffeff47d, 0x85845dd1,
+  0x6fa87e4f, 0xfe2ce6e0, 0xa3014314, 0x4e0811a1, 0xf7537e82, 0xbd3af235, 0x2ad7d2bb, 0xeb86d391,
+}
+
+local function md5(msg)
+  local a0, b0, c0, d0 = 0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476
+  msg = pad(msg, false)
+
+  local m = {}
+  for chunk = 1, #msg, 64 do
+    for i = 0, 15 do
+      local a, b, c, d = msg:byte(chunk + i * 4, chunk + i * 4 + 3)
+      m[i] = bor(a, lshift(b, 8), lshift(c, 16), lshift(d, 24))
+    end
+
+    local a, b, c, d = a0, b0, c0, d0
+    for i = 0, 63 do
+      local f, g
+      if i < 16 then
+        f, g = bor(band(b, c), band(bnot(b), d)), i
+      elseif i < 32 then
+        f, g = bor(band(d, b), band(bnot(d), c)), (5 * i + 1) % 16
+      elseif i < 48 then
+        f, g = bxor(b, c, d), (3 * i + 5) % 16
+      else
+        f, g = bxor(c, bor(b, bnot(d))), (7 * i) % 16
+      end
+      f = tobit(f + a + md5_k[i + 1] + m[g])
+      a, d, c = d, c, b
+      b = tobit(b + rol(f, md5_s[i + 1]))
+    end
+
+    a0, b0, c0, d0 = tobit(a0 + a), tobit(b0 + b), tobit(c0 + c), tobit(d0 + d)
+  end
+
+  local out = {}
+  for _, h in ipairs({a0, b0, c0, d0}) do
+    out[#out + 1] = string.char(band(h, 0xff), band(rshift(h, 8), 0xff), band(rshift(h, 16), 0xff), band(rshift(h, 24), 0xff))
+  end
+  return table.concat(out)
+end
+
+local itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+-- apr1 returns the Apache MD5 hash of the supplied password with
+-- the supplied salt, as computed by "htpasswd -m".
+local function apr1(password, salt)
+  local sum = md5(password .. salt .. password)
+
+  local t = {password, "$apr1$", salt}
+  for n = #password, 1, -16 do
+    t[#t + 1] = sum:sub(1, n)
+  end
+  local n = #password
+  while n > 0 do
+    if band(n, 1) ~= 0 then
+      t[#t + 1] = "\0"
+    else
+      t[#t + 1] = password:sub(1, 1)
+    end
+    n = rshift(n, 1)
+  end
+  sum = md5(table.concat(t))
+
+  for i = 0, 999 do
+    local odd = band(i, 1) ~= 0
+    t = {odd and password or sum}
+    if i % 3 ~= 0 then
+      t[#t + 1] = salt
+    end
+    if i % 7 ~= 0 then
+      t[#t + 1] = password
+    end
+    t[#t + 1] = odd and sum or password
+    sum = md5(table.concat(t))
+  end
+
+  local out = {"$apr1$", salt, "$"}
+  local function encode(v, n)
+    for _ = 1, n do
+      local c = band(v, 0x3f) + 1
+      out[#out + 1] = itoa64:sub(c, c)
+      v = rshift(v, 6)
+    end
+  end
+  for _, i in ipairs({{1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 16}, {5, 11, 6}}) do
+    encode(bor(lshift(sum:byte(i[1]), 16), lshift(sum:byte(i[2]), 8), sum:byte(i[3])), 4)
+  end
+  encode(sum:byte(12), 2)
+  return table.concat(out)
+end
+
+-- equal compares the supplied hashes in constant time.
+local function equal(a, b)
+  if #a ~= #b then
+    return false
+  end
+  local diff = 0
+  for i = 1, #a do
+    diff = bor(diff, bxor(a:byte(i), b:byte(i)))
+  end
+  return diff == 0
+end
+
+local function authenticate(users, user, password)
+  local hash = users[user]
+  if hash == nil then
+    return false
+  end
+  if hash:sub(1, 5) == "{SHA}" then
+    return equal(hash, "{SHA}" .. base64encode(sha1(password)))
+  end
+  local salt = hash:match("^%$apr1%$([^$]+)%$")
+  return salt ~= nil and equal(hash, apr1(password, salt))
+end
+
+-- verified holds the SHA-1 digests of the Secret names and
+-- Authorization headers that were authenticated, since Apache MD5
+-- hashes are deliberately slow to compute. The cache is bounded, and
+-- it is discarded with the script when the credentials change.
+local verified, verified_count = {}, 0
+
+function envoy_on_request(request_handle)
+  local auth = request_handle:metadata():get("basic_auth")
+  if auth == nil then
+    return
+  end
+
+  local users = credentials[auth.secret]
+  local header = request_handle:headers():get("authorization")
+  if users ~= nil and header ~= nil then
+    local key = sha1(auth.secret .. "\0" .. header)
+    if verified[key] then
+      return
+    end
+    local encoded = header:match("^[Bb][Aa][Ss][Ii][Cc]%s+(%S+)%s*$")
+    if encoded ~= nil then
+      local user, password = base64decode(encoded):match("^([^:]*):(.*)$")
+      if user ~= nil and authenticate(users, user, password) then
+        if verified_count >= 1024 then
+          verified, verified_count = {}, 0
+        end
+        verified[key], verified_count = true, verified_count + 1
+        return
+      end
+    end
+  end
+
+  request_handle:respond({
+    [":status"] = "401",
+    ["www-authenticate"] = 'Basic realm="' .. auth.realm .. '"',
+  }, "Unauthorized")
+end
+`
+
+// FilterBasicAuth returns the Lua HTTP filter that authenticates
+// requests for the routes of the supplied basic authentication
+// policies, which are configured with RouteLua. The password hashes
+// are part of the filter, so they reach Envoy with its listeners
+// rather than with its routes.
+func FilterBasicAuth(policies ...*dag.BasicAuthPolicy) *http.HttpFilter {
+	secrets := map[string]*dag.BasicAuthPolicy{}
+	for _, p := range policies {
+		secrets[p.Secret.String()] = p
+	}
+
+	var script strings.Builder
+	script.WriteString("local credentials = {\n")
+	for _, secret := range sortedKeys(secrets) {
+		fmt.Fprintf(&script, "  [%s] = {\n", luaString(secret))
+		users := secrets[secret].Credentials
+		names := make([]string, 0, len(users))
+		for user := range users {
+			names = append(names, user)
+		}
+		sort.Strings(names)
+		for _, user := range names {
+			fmt.Fprintf(&script, "    [%s] = %s,\n", luaString(user), luaString(users[user]))
+		}
+		script.WriteString("  },\n")
+	}
+	script.WriteString("}\n")
+	script.WriteString(basicAuthScript)
+
+	return &http.HttpFilter{
+		Name: LuaFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_lua_v2.Lua{
+				InlineCode: script.String(),
+			}),
+		},
+	}
+}
+
+// sortedKeys returns the keys of the supplied map in order.
+func sortedKeys(m map[string]*dag.BasicAuthPolicy) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// luaString returns the supplied string as a Lua string literal.
+// Quotes and backslashes are escaped, as are bytes other than
+// printable ASCII characters.
+func luaString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,82 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"strings"
+	"testing"
+
+	envoy_config_filter_http_lua_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/lua/v2"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/projectcontour/contour/internal/assert"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/htpasswd"
+	"github.com/projectcontour/contour/internal/k8s"
+)
+
+func TestFilterBasicAuth(t *testing.T) {
+	policy := func(name string, credentials htpasswd.Credentials) *dag.BasicAuthPolicy {
+		return &dag.BasicAuthPolicy{
+			Secret:      k8s.FullName{Namespace: "default", Name: name},
+			Credentials: credentials,
+		}
+	}
+	team := policy("team", htpasswd.Credentials{
+		"john": "$apr1$r31Mp9dl$NNB/l3RR5fgj8Mq5uKmjW.",
+		"jane": "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+	})
+	admins := policy("admins", htpasswd.Credentials{
+		`"quoted\"`: "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+		"zoë":       "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+	})
+
+	// Each Secret appears once, in order, and user names are
+	// escaped as Lua strings.
+	filter := FilterBasicAuth(team, admins, team)
+	assert.Equal(t, LuaFilterName, filter.Name)
+
+	var lua envoy_config_filter_http_lua_v2.Lua
+	if err := ptypes.UnmarshalAny(filter.GetTypedConfig(), &lua); err != nil {
+		t.Fatal(err)
+	}
+	want := `local credentials = {
+  ["default/admins"] = {
+    ["\"quoted\\\""] = "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+    ["zo\195\171"] = "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+  },
+  ["default/team"] = {
+    ["jane"] = "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+    ["john"] = "$apr1$r31Mp9dl$NNB/l3RR5fgj8Mq5uKmjW.",
+  },
+}
+`
+	assert.Equal(t, want, lua.InlineCode[:len(want)])
+	assert.Equal(t, true, strings.HasSuffix(lua.InlineCode, basicAuthScript))
+}
+
+func TestLuaString(t *testing.T) {
+	tests := map[string]string{
+		"jane":      `"jane"`,
+		"a\"b":      `"a\"b"`,
+		`a\b`:       `"a\\b"`,
+		"a\nb":      `"a\010b"`,
+		"\x001":     `"\0001"`,
+		"\xff":      `"\255"`,
+		"$apr1$a/.": `"$apr1$a/."`,
+	}
+
+	for s, want := range tests {
+		assert.Equal(t, want, luaString(s))
+	}
+}
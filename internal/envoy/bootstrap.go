@@ -76,6 +76,10 @@ type bootstrapf func(*BootstrapConfig) (string, proto.Message)
 func bootstrap(c *BootstrapConfig) ([]bootstrapf, error) {
 	steps := []bootstrapf{}
 
+	if c.Region != "" && c.Zone == "" {
+		return nil, fmt.Errorf("%q requires %q", "--region", "--zone")
+	}
+
 	if c.GrpcClientCert == "" && c.GrpcClientKey == "" && c.GrpcCABundle == "" {
 		steps = append(steps,
 			func(*BootstrapConfig) (string, proto.Message) {
@@ -157,7 +161,7 @@ func bootstrap(c *BootstrapConfig) ([]bootstrapf, error) {
 }
 
 func bootstrapConfig(c *BootstrapConfig) *envoy_api_bootstrap.Bootstrap {
-	return &envoy_api_bootstrap.Bootstrap{
+	b := &envoy_api_bootstrap.Bootstrap{
 		DynamicResources: &envoy_api_bootstrap.Bootstrap_DynamicResources{
 			LdsConfig: ConfigSource("contour"),
 			CdsConfig: ConfigSource("contour"),
@@ -217,6 +221,65 @@ func bootstrapConfig(c *BootstrapConfig) *envoy_api_bootstrap.Bootstrap {
 			Address:       SocketAddress(c.adminAddress(), c.adminPort()),
 		},
 	}
+
+	if c.ServiceCluster != "" {
+		b.Node = &envoy_api_v2_core.Node{
+			Cluster: c.ServiceCluster,
+		}
+	}
+
+	if t := c.Tracing; t != nil && t.CollectorAddress != "" {
+		b.StaticResources.Clusters = append(b.StaticResources.Clusters, tracingCollectorCluster(c.Namespace, t))
+		b.Tracing = tracingProvider(t)
+
+		// Envoy reports its service cluster as the service
+		// name of its spans, so the tracing service name
+		// replaces the configured service cluster. Note that
+		// Envoy's own --service-cluster command line flag takes
+		// precedence over this, so it must not be passed to Envoy.
+		if t.ServiceName != "" {
+			if b.Node == nil {
+				b.Node = &envoy_api_v2_core.Node{}
+			}
+			b.Node.Cluster = t.ServiceName
+		}
+	}
+
+	if c.Zone != "" {
+		// Envoy compares the localities of its own endpoints
+		// with those of each upstream cluster to decide how
+		// much traffic it can keep in its zone.
+		if b.Node == nil {
+			b.Node = &envoy_api_v2_core.Node{}
+		}
+		b.Node.Locality = &envoy_api_v2_core.Locality{
+			Region: c.Region,
+			Zone:   c.Zone,
+		}
+		b.StaticResources.Clusters = append(b.StaticResources.Clusters, localCluster(c))
+		b.ClusterManager = &envoy_api_bootstrap.ClusterManager{
+			LocalClusterName: localClusterName,
+		}
+	}
+
+	return b
+}
+
+// localClusterName is the name of the cluster of Envoy's own endpoints.
+const localClusterName = "envoy"
+
+// localCluster returns the cluster of the endpoints of the Service
+// that selects the Envoy pods, which Contour discovers with EDS.
+func localCluster(c *BootstrapConfig) *api.Cluster {
+	return &api.Cluster{
+		Name:                 localClusterName,
+		ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
+		ClusterDiscoveryType: ClusterDiscoveryType(api.Cluster_EDS),
+		EdsClusterConfig: &api.Cluster_EdsClusterConfig{
+			EdsConfig:   ConfigSource("contour"),
+			ServiceName: path.Join(c.Namespace, c.localService()),
+		},
+	}
 }
 
 func upstreamFileTLSContext(c *BootstrapConfig) *envoy_api_v2_auth.UpstreamTlsContext {
@@ -374,12 +437,35 @@ type BootstrapConfig struct {
 	// referenced in the configuration actually exist. This option is for
 	// testing only.
 	SkipFilePathCheck bool
+
+	// ServiceCluster is the service cluster name Envoy reports, in
+	// place of Envoy's --service-cluster command line flag. A tracing
+	// service name takes precedence over it.
+	ServiceCluster string
+
+	// Tracing configures the trace collector that Envoy sends spans to.
+	// If nil, or if no collector address is set, tracing is disabled.
+	Tracing *TracingConfig
+
+	// Region is the region Envoy runs in. It requires Zone.
+	Region string
+
+	// Zone is the zone Envoy runs in. If set, Envoy prefers the
+	// endpoints of upstream clusters that are in the same zone.
+	Zone string
+
+	// LocalService is the name and port name of the Service in
+	// Namespace that selects the Envoy pods, whose endpoints Envoy
+	// compares with those of upstream clusters for zone aware routing.
+	// Defaults to envoy/http.
+	LocalService string
 }
 
 func (c *BootstrapConfig) xdsAddress() string   { return stringOrDefault(c.XDSAddress, "127.0.0.1") }
 func (c *BootstrapConfig) xdsGRPCPort() int     { return intOrDefault(c.XDSGRPCPort, 8001) }
 func (c *BootstrapConfig) adminAddress() string { return stringOrDefault(c.AdminAddress, "127.0.0.1") }
 func (c *BootstrapConfig) adminPort() int       { return intOrDefault(c.AdminPort, 9001) }
+func (c *BootstrapConfig) localService() string { return stringOrDefault(c.LocalService, "envoy/http") }
 func (c *BootstrapConfig) adminAccessLogPath() string {
 	return stringOrDefault(c.AdminAccessLogPath, "/dev/null")
 }
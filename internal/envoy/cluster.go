@@ -53,10 +53,23 @@ func Cluster(c *dag.Cluster) *v2.Cluster {
 		// external name not set, cluster will be discovered via EDS
 		cluster.ClusterDiscoveryType = ClusterDiscoveryType(v2.Cluster_EDS)
 		cluster.EdsClusterConfig = edsconfig("contour", service)
+		if zr := service.ZoneAwareRouting; zr != nil {
+			cluster.CommonLbConfig.LocalityConfigSpecifier = zoneAwareLbConfig(zr)
+		}
 	default:
 		// external name set, use hard coded DNS name
 		cluster.ClusterDiscoveryType = ClusterDiscoveryType(v2.Cluster_STRICT_DNS)
 		cluster.LoadAssignment = StaticClusterLoadAssignment(service)
+		if dns := service.DNSPolicy; dns != nil {
+			if dns.LogicalDNS {
+				cluster.ClusterDiscoveryType = ClusterDiscoveryType(v2.Cluster_LOGICAL_DNS)
+			}
+			if dns.RefreshRate > 0 {
+				cluster.DnsRefreshRate = protobuf.Duration(dns.RefreshRate)
+			}
+			cluster.RespectDnsTtl = dns.RespectTTL
+			cluster.DnsLookupFamily = dnsLookupFamily(dns.LookupFamily)
+		}
 	}
 
 	// Drain connections immediately if using healthchecks and the endpoint is known to be removed
@@ -75,6 +88,12 @@ func Cluster(c *dag.Cluster) *v2.Cluster {
 		}
 	}
 
+	if c.MaxStreamDuration > 0 {
+		cluster.CommonHttpProtocolOptions = &envoy_api_v2_core.HttpProtocolOptions{
+			MaxStreamDuration: protobuf.Duration(c.MaxStreamDuration),
+		}
+	}
+
 	switch c.Protocol {
 	case "tls":
 		cluster.TransportSocket = UpstreamTLSTransportSocket(
@@ -181,6 +200,12 @@ func Clustername(cluster *dag.Cluster) string {
 		buf += uv.CACertificate.Object.ObjectMeta.Name
 		buf += uv.SubjectName
 	}
+	if cluster.MaxStreamDuration > 0 {
+		buf += cluster.MaxStreamDuration.String()
+	}
+	// distinguish the clusters of an ExternalBackend from
+	// those of a Service with the same name and port.
+	buf += service.Kind
 
 	// This isn't a crypto hash, we just want a unique name.
 	hash := sha1.Sum([]byte(buf)) // nolint:gosec
@@ -268,6 +293,31 @@ func u32nil(val uint32) *wrappers.UInt32Value {
 	}
 }
 
+// dnsLookupFamily returns the Envoy DNS lookup family
+// of the supplied dag.DNSPolicy lookup family.
+func dnsLookupFamily(family string) v2.Cluster_DnsLookupFamily {
+	switch family {
+	case "v4":
+		return v2.Cluster_V4_ONLY
+	case "v6":
+		return v2.Cluster_V6_ONLY
+	default:
+		return v2.Cluster_AUTO
+	}
+}
+
+// zoneAwareLbConfig returns the zone aware load balancing
+// configuration for the supplied policy.
+func zoneAwareLbConfig(zr *dag.ZoneAwareRoutingPolicy) *v2.Cluster_CommonLbConfig_ZoneAwareLbConfig_ {
+	config := &v2.Cluster_CommonLbConfig_ZoneAwareLbConfig{}
+	if zr.MinClusterSize > 0 {
+		config.MinClusterSize = protobuf.UInt64(zr.MinClusterSize)
+	}
+	return &v2.Cluster_CommonLbConfig_ZoneAwareLbConfig_{
+		ZoneAwareLbConfig: config,
+	}
+}
+
 // ClusterCommonLBConfig creates a *v2.Cluster_CommonLbConfig with HealthyPanicThreshold disabled.
 func ClusterCommonLBConfig() *v2.Cluster_CommonLbConfig {
 	return &v2.Cluster_CommonLbConfig{
@@ -125,6 +125,24 @@ func TestCluster(t *testing.T) {
 				Http2ProtocolOptions: &envoy_api_v2_core.Http2ProtocolOptions{},
 			},
 		},
+		"max stream duration": {
+			cluster: &dag.Cluster{
+				Upstream:          service(s1),
+				MaxStreamDuration: 5 * time.Minute,
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/b355580f40",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				CommonHttpProtocolOptions: &envoy_api_v2_core.HttpProtocolOptions{
+					MaxStreamDuration: protobuf.Duration(5 * time.Minute),
+				},
+			},
+		},
 		"h2 upstream": {
 			cluster: &dag.Cluster{
 				Upstream: service(s1, "h2"),
@@ -155,6 +173,31 @@ func TestCluster(t *testing.T) {
 				LoadAssignment:       StaticClusterLoadAssignment(service(s2)),
 			},
 		},
+		"externalName service with dns policy": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					Name:         s2.Name,
+					Namespace:    s2.Namespace,
+					ServicePort:  &s2.Spec.Ports[0],
+					ExternalName: s2.Spec.ExternalName,
+					DNSPolicy: &dag.DNSPolicy{
+						RefreshRate:  30 * time.Second,
+						RespectTTL:   true,
+						LookupFamily: "v4",
+						LogicalDNS:   true,
+					},
+				},
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/da39a3ee5e",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_LOGICAL_DNS),
+				LoadAssignment:       StaticClusterLoadAssignment(service(s2)),
+				DnsRefreshRate:       protobuf.Duration(30 * time.Second),
+				RespectDnsTtl:        true,
+				DnsLookupFamily:      v2.Cluster_V4_ONLY,
+			},
+		},
 		"tls upstream": {
 			cluster: &dag.Cluster{
 				Upstream: service(s1, "tls"),
@@ -324,6 +367,34 @@ func TestCluster(t *testing.T) {
 				LbPolicy: v2.Cluster_RANDOM,
 			},
 		},
+		"zone aware routing": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					Name:        s1.Name,
+					Namespace:   s1.Namespace,
+					ServicePort: &s1.Spec.Ports[0],
+					ZoneAwareRouting: &dag.ZoneAwareRoutingPolicy{
+						MinClusterSize: 3,
+					},
+				},
+			},
+			want: &v2.Cluster{
+				Name:                 "default/kuard/443/da39a3ee5e",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(v2.Cluster_EDS),
+				EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				CommonLbConfig: &v2.Cluster_CommonLbConfig{
+					LocalityConfigSpecifier: &v2.Cluster_CommonLbConfig_ZoneAwareLbConfig_{
+						ZoneAwareLbConfig: &v2.Cluster_CommonLbConfig_ZoneAwareLbConfig{
+							MinClusterSize: protobuf.UInt64(3),
+						},
+					},
+				},
+			},
+		},
 		"cluster with cookie policy": {
 			cluster: &dag.Cluster{
 				Upstream:           service(s1),
@@ -419,6 +490,21 @@ func TestClustername(t *testing.T) {
 			},
 			want: "default/backend/80/da39a3ee5e",
 		},
+		"externalbackend": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					Name:      "backend",
+					Namespace: "default",
+					ServicePort: &v1.ServicePort{
+						Name:     "80",
+						Protocol: "TCP",
+						Port:     80,
+					},
+					Kind: "ExternalBackend",
+				},
+			},
+			want: "default/backend/80/bfa274ebcc",
+		},
 		"far too long": {
 			cluster: &dag.Cluster{
 				Upstream: &dag.Service{
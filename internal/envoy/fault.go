@@ -0,0 +1,78 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"math"
+
+	envoy_config_filter_fault_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/fault/v2"
+	envoy_config_filter_http_fault_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/fault/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// FilterFault returns the fault injection HTTP filter. The filter
+// injects no faults of its own; routes configure their faults
+// with RouteFault.
+func FilterFault() *http.HttpFilter {
+	return &http.HttpFilter{
+		Name: wellknown.Fault,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_fault_v2.HTTPFault{}),
+		},
+	}
+}
+
+// RouteFault returns the fault filter configuration that injects
+// the faults of the supplied route, or nil if the route has none.
+func RouteFault(r *dag.Route) *envoy_config_filter_http_fault_v2.HTTPFault {
+	fp := r.FaultPolicy
+	if fp == nil {
+		return nil
+	}
+
+	fault := &envoy_config_filter_http_fault_v2.HTTPFault{
+		Headers: headerMatcher(fp.HeaderConditions),
+	}
+	if fp.Delay > 0 {
+		fault.Delay = &envoy_config_filter_fault_v2.FaultDelay{
+			FaultDelaySecifier: &envoy_config_filter_fault_v2.FaultDelay_FixedDelay{
+				FixedDelay: protobuf.Duration(fp.Delay),
+			},
+			Percentage: faultPercentage(fp.DelayPercentage),
+		}
+	}
+	if fp.AbortStatus > 0 {
+		fault.Abort = &envoy_config_filter_http_fault_v2.FaultAbort{
+			ErrorType: &envoy_config_filter_http_fault_v2.FaultAbort_HttpStatus{
+				HttpStatus: fp.AbortStatus,
+			},
+			Percentage: faultPercentage(fp.AbortPercentage),
+		}
+	}
+
+	return fault
+}
+
+// faultPercentage expresses the supplied percentage in millionths
+// so that fractional percentages are not truncated.
+func faultPercentage(p float64) *envoy_type.FractionalPercent {
+	return &envoy_type.FractionalPercent{
+		Numerator:   uint32(math.Round(p * 10000)),
+		Denominator: envoy_type.FractionalPercent_MILLION,
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	jwt "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/jwt_authn/v2alpha"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+	"github.com/projectcontour/contour/internal/sorter"
+)
+
+// JWTAuthnFilterName is the name of the JWT authentication HTTP filter.
+const JWTAuthnFilterName = "envoy.filters.http.jwt_authn"
+
+// FilterJWTAuthn returns the JWT authentication HTTP filter that
+// verifies the JWTs of requests to the routes of the supplied
+// virtual hosts, or nil if none of their routes verify JWTs. If
+// the virtual hosts are not secure, routes that require HTTPS are
+// redirected, and don't verify JWTs.
+//
+// Envoy cannot configure the filter per route, so the filter's
+// rules repeat the matches of each route with an additional match
+// on the :authority header of its virtual host. This also lets
+// one filter serve the virtual hosts of the fallback certificate.
+func FilterJWTAuthn(secure bool, vhosts ...*dag.VirtualHost) *http.HttpFilter {
+	config := &jwt.JwtAuthentication{
+		Providers: map[string]*jwt.JwtProvider{},
+	}
+
+	// Rules for exact names must precede rules for wildcard names,
+	// and the rules for the default virtual host come last.
+	vhosts = append([]*dag.VirtualHost{}, vhosts...)
+	sort.SliceStable(vhosts, func(i, j int) bool {
+		return authorityRank(vhosts[i].Name) < authorityRank(vhosts[j].Name)
+	})
+
+	for _, vh := range vhosts {
+		var rules []*jwt.RequirementRule
+		vh.Visit(func(v dag.Vertex) {
+			r, ok := v.(*dag.Route)
+			if !ok {
+				return
+			}
+
+			rule := &jwt.RequirementRule{
+				Match: RouteMatch(r),
+			}
+			if p := r.JWTProvider; p != nil && (secure || !r.HTTPSUpgrade) {
+				config.Providers[p.Name] = jwtProvider(p)
+				rule.Requires = jwtRequirement(p.Name, r.JWTAllowMissing)
+			}
+			sort.Stable(sorter.For(rule.Match.Headers))
+			rules = append(rules, rule)
+		})
+		sort.Stable(sorter.For(rules))
+
+		if authority := authorityMatcher(vh.Name); authority != nil {
+			for _, rule := range rules {
+				rule.Match.Headers = append(rule.Match.Headers, authority)
+			}
+		}
+		config.Rules = append(config.Rules, rules...)
+	}
+
+	if len(config.Providers) == 0 {
+		return nil
+	}
+
+	return &http.HttpFilter{
+		Name: JWTAuthnFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(config),
+		},
+	}
+}
+
+func jwtProvider(p *dag.JWTProvider) *jwt.JwtProvider {
+	provider := &jwt.JwtProvider{
+		Issuer:    p.Issuer,
+		Audiences: p.Audiences,
+		Forward:   p.ForwardJWT,
+	}
+
+	// The Lua filter forwards claims from the payload the
+	// provider stores in the request's dynamic metadata.
+	if len(p.ClaimsToHeaders) > 0 {
+		provider.PayloadInMetadata = p.Name
+	}
+
+	switch {
+	case p.RemoteJWKS != nil:
+		provider.JwksSourceSpecifier = &jwt.JwtProvider_RemoteJwks{
+			RemoteJwks: &jwt.RemoteJwks{
+				HttpUri: &envoy_api_v2_core.HttpUri{
+					Uri: p.RemoteJWKS.URI,
+					HttpUpstreamType: &envoy_api_v2_core.HttpUri_Cluster{
+						Cluster: Clustername(p.RemoteJWKS.Cluster),
+					},
+					Timeout: protobuf.Duration(p.RemoteJWKS.Timeout),
+				},
+				CacheDuration: protobuf.Duration(p.RemoteJWKS.CacheDuration),
+			},
+		}
+	default:
+		provider.JwksSourceSpecifier = &jwt.JwtProvider_LocalJwks{
+			LocalJwks: &envoy_api_v2_core.DataSource{
+				Specifier: &envoy_api_v2_core.DataSource_InlineString{
+					InlineString: p.LocalJWKS,
+				},
+			},
+		}
+	}
+
+	return provider
+}
+
+func jwtRequirement(provider string, allowMissing bool) *jwt.JwtRequirement {
+	requirement := &jwt.JwtRequirement{
+		RequiresType: &jwt.JwtRequirement_ProviderName{
+			ProviderName: provider,
+		},
+	}
+	if !allowMissing {
+		return requirement
+	}
+
+	// A request without a JWT satisfies the second requirement,
+	// but a request with an invalid JWT satisfies neither.
+	return &jwt.JwtRequirement{
+		RequiresType: &jwt.JwtRequirement_RequiresAny{
+			RequiresAny: &jwt.JwtRequirementOrList{
+				Requirements: []*jwt.JwtRequirement{
+					requirement,
+					{
+						RequiresType: &jwt.JwtRequirement_AllowMissing{
+							AllowMissing: &empty.Empty{},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// authorityRank orders virtual host names the way Envoy selects
+// virtual hosts: exact names, then wildcard names, then "*".
+func authorityRank(name string) int {
+	switch {
+	case name == "*":
+		return 2
+	case strings.HasPrefix(name, "*."):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// authorityMatcher returns a header matcher that matches requests
+// for the named virtual host, with or without a port, or nil if
+// the virtual host matches every request.
+func authorityMatcher(name string) *envoy_api_v2_route.HeaderMatcher {
+	var host string
+	switch {
+	case name == "*":
+		return nil
+	case strings.HasPrefix(name, "*."):
+		host = ".+" + regexp.QuoteMeta(name[1:])
+	default:
+		host = regexp.QuoteMeta(name)
+	}
+
+	return &envoy_api_v2_route.HeaderMatcher{
+		Name: ":authority",
+		HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+			SafeRegexMatch: SafeRegexMatch("(?i)" + host + "(:[0-9]+)?"),
+		},
+	}
+}
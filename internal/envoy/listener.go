@@ -16,6 +16,7 @@ package envoy
 import (
 	"fmt"
 	"log"
+	"net"
 	"sort"
 	"time"
 
@@ -134,6 +135,9 @@ type httpConnectionManagerBuilder struct {
 	maxConnectionDuration time.Duration
 	filters               []*http.HttpFilter
 	codec                 HTTPVersionType // Note the zero value is AUTO, which is the default we want.
+	tracing               *TracingConfig
+	numTrustedHops        uint32
+	skipXFFAppend         bool
 }
 
 // RouteConfigName sets the name of the RDS element that contains
@@ -193,6 +197,28 @@ func (b *httpConnectionManagerBuilder) MaxConnectionDuration(timeout time.Durati
 	return b
 }
 
+// Tracing sets the tracing configuration of the connection manager.
+// If nil, requests are not traced.
+func (b *httpConnectionManagerBuilder) Tracing(tracing *TracingConfig) *httpConnectionManagerBuilder {
+	b.tracing = tracing
+	return b
+}
+
+// NumTrustedHops sets the number of proxies in front of Envoy whose
+// X-Forwarded-For entries the connection manager trusts when it
+// derives the client address. The default is 0, which trusts none.
+func (b *httpConnectionManagerBuilder) NumTrustedHops(hops uint32) *httpConnectionManagerBuilder {
+	b.numTrustedHops = hops
+	return b
+}
+
+// SkipXFFAppend stops the connection manager appending the peer
+// address to the X-Forwarded-For header.
+func (b *httpConnectionManagerBuilder) SkipXFFAppend(skip bool) *httpConnectionManagerBuilder {
+	b.skipXFFAppend = skip
+	return b
+}
+
 func (b *httpConnectionManagerBuilder) DefaultFilters() *httpConnectionManagerBuilder {
 	b.filters = append(b.filters,
 		&http.HttpFilter{
@@ -209,7 +235,13 @@ func (b *httpConnectionManagerBuilder) DefaultFilters() *httpConnectionManagerBu
 	return b
 }
 
+// AddFilter adds a filter to the manager. The router filter must
+// be the last filter, so the filter is added ahead of it.
 func (b *httpConnectionManagerBuilder) AddFilter(f *http.HttpFilter) *httpConnectionManagerBuilder {
+	if n := len(b.filters); n > 0 && b.filters[n-1].Name == wellknown.Router {
+		b.filters = append(b.filters[:n-1], f, b.filters[n-1])
+		return b
+	}
 	b.filters = append(b.filters, f)
 	return b
 }
@@ -236,15 +268,19 @@ func (b *httpConnectionManagerBuilder) Get() *envoy_api_v2_listener.Filter {
 			// a Host: header. See #537.
 			AcceptHttp_10: true,
 		},
-		UseRemoteAddress: protobuf.Bool(true),
-		NormalizePath:    protobuf.Bool(true),
-		RequestTimeout:   protobuf.Duration(b.requestTimeout),
+		UseRemoteAddress:  protobuf.Bool(true),
+		XffNumTrustedHops: b.numTrustedHops,
+		SkipXffAppend:     b.skipXFFAppend,
+		NormalizePath:     protobuf.Bool(true),
+		RequestTimeout:    protobuf.Duration(b.requestTimeout),
 
 		// issue #1487 pass through X-Request-Id if provided.
 		PreserveExternalRequestId: true,
 		MergeSlashes:              true,
 
 		StreamIdleTimeout: protobuf.Duration(b.streamIdleTimeout),
+
+		Tracing: httpConnectionManagerTracing(b.tracing),
 	}
 
 	// This timeout is disabled in Envoy by NOT providing a value, rather than explicitly passing a 0.
@@ -404,20 +440,33 @@ func FilterMisdirectedRequests(fqdn string) *http.HttpFilter {
 	// so the Host header (authority) may contain a port that
 	// should be ignored. This means that if we don't have a match,
 	// we should try again after stripping the port specifier.
+	// A wildcard fqdn such as "*.example.com" matches any host
+	// that ends with ".example.com".
 
 	code := `
+local function matches(host, target)
+	if host == target then
+		return true
+	end
+	if string.sub(target, 1, 2) == "*." then
+		local suffix = string.sub(target, 2)
+		return string.len(host) > string.len(suffix) and string.sub(host, -string.len(suffix)) == suffix
+	end
+	return false
+end
+
 function envoy_on_request(request_handle)
 	local headers = request_handle:headers()
 	local host = headers:get(":authority")
 	local target = "%s"
 
-	if host ~= target then
+	if not matches(host, target) then
 		s, e = string.find(host, ":", 1, true)
 		if s ~= nil then
 			host = string.sub(host, 1, s - 1)
 		end
 
-		if host ~= target then
+		if not matches(host, target) then
 			request_handle:respond(
 				{[":status"] = "421"},
 				string.format("misdirected request to %%q", headers:get(":authority"))
@@ -470,16 +519,25 @@ func FilterChainTLSFallback(downstream *envoy_api_v2_auth.DownstreamTlsContext,
 	return fc
 }
 
-// ListenerFilters returns a []*envoy_api_v2_listener.ListenerFilter for the supplied listener filters.
-func ListenerFilters(filters ...*envoy_api_v2_listener.ListenerFilter) []*envoy_api_v2_listener.ListenerFilter {
-	return filters
+// SourcePrefixRanges returns the CIDR ranges of the supplied networks,
+// for filter chains that match the source address of connections.
+func SourcePrefixRanges(cidrs []net.IPNet) []*envoy_api_v2_core.CidrRange {
+	var ranges []*envoy_api_v2_core.CidrRange
+	for _, cidr := range cidrs {
+		ranges = append(ranges, cidrRange(cidr))
+	}
+	return ranges
 }
 
-func ContainsFallbackFilterChain(filterchains []*envoy_api_v2_listener.FilterChain) bool {
-	for _, fc := range filterchains {
-		if fc.Name == "fallback-certificate" {
-			return true
-		}
+func cidrRange(cidr net.IPNet) *envoy_api_v2_core.CidrRange {
+	ones, _ := cidr.Mask.Size()
+	return &envoy_api_v2_core.CidrRange{
+		AddressPrefix: cidr.IP.String(),
+		PrefixLen:     protobuf.UInt32(uint32(ones)),
 	}
-	return false
+}
+
+// ListenerFilters returns a []*envoy_api_v2_listener.ListenerFilter for the supplied listener filters.
+func ListenerFilters(filters ...*envoy_api_v2_listener.ListenerFilter) []*envoy_api_v2_listener.ListenerFilter {
+	return filters
 }
@@ -24,6 +24,8 @@ import (
 	envoy_api_v2_accesslog "github.com/envoyproxy/go-control-plane/envoy/config/filter/accesslog/v2"
 	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
 	envoy_config_v2_tcpproxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
+	envoy_type_tracing_v2 "github.com/envoyproxy/go-control-plane/envoy/type/tracing/v2"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/google/go-cmp/cmp"
 	"github.com/projectcontour/contour/internal/assert"
@@ -304,6 +306,7 @@ func TestHTTPConnectionManager(t *testing.T) {
 		connectionIdleTimeout time.Duration
 		streamIdleTimeout     time.Duration
 		maxConnectionDuration time.Duration
+		tracing               *TracingConfig
 		want                  *envoy_api_v2_listener.Filter
 	}{
 		"default": {
@@ -635,6 +638,79 @@ func TestHTTPConnectionManager(t *testing.T) {
 				},
 			},
 		},
+		"tracing": {
+			routename:    "default/kuard",
+			accesslogger: FileAccessLogEnvoy("/dev/stdout"),
+			tracing: &TracingConfig{
+				Sampling: 25,
+				CustomTags: []TracingCustomTag{{
+					Name:    "cluster",
+					Literal: "prod",
+				}},
+			},
+			want: &envoy_api_v2_listener.Filter{
+				Name: wellknown.HTTPConnectionManager,
+				ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&http.HttpConnectionManager{
+						StatPrefix: "default/kuard",
+						RouteSpecifier: &http.HttpConnectionManager_Rds{
+							Rds: &http.Rds{
+								RouteConfigName: "default/kuard",
+								ConfigSource: &envoy_api_v2_core.ConfigSource{
+									ConfigSourceSpecifier: &envoy_api_v2_core.ConfigSource_ApiConfigSource{
+										ApiConfigSource: &envoy_api_v2_core.ApiConfigSource{
+											ApiType: envoy_api_v2_core.ApiConfigSource_GRPC,
+											GrpcServices: []*envoy_api_v2_core.GrpcService{{
+												TargetSpecifier: &envoy_api_v2_core.GrpcService_EnvoyGrpc_{
+													EnvoyGrpc: &envoy_api_v2_core.GrpcService_EnvoyGrpc{
+														ClusterName: "contour",
+													},
+												},
+											}},
+										},
+									},
+								},
+							},
+						},
+						HttpFilters: []*http.HttpFilter{{
+							Name: wellknown.Gzip,
+						}, {
+							Name: wellknown.GRPCWeb,
+						}, {
+							Name: wellknown.Router,
+						}},
+						HttpProtocolOptions: &envoy_api_v2_core.Http1ProtocolOptions{
+							// Enable support for HTTP/1.0 requests that carry
+							// a Host: header. See #537.
+							AcceptHttp_10: true,
+						},
+						CommonHttpProtocolOptions: &envoy_api_v2_core.HttpProtocolOptions{
+							IdleTimeout: protobuf.Duration(0),
+						},
+						AccessLog:                 FileAccessLogEnvoy("/dev/stdout"),
+						UseRemoteAddress:          protobuf.Bool(true),
+						NormalizePath:             protobuf.Bool(true),
+						RequestTimeout:            protobuf.Duration(0),
+						PreserveExternalRequestId: true,
+						MergeSlashes:              true,
+						StreamIdleTimeout:         protobuf.Duration(0),
+						Tracing: &http.HttpConnectionManager_Tracing{
+							OverallSampling: &envoy_type.Percent{
+								Value: 25,
+							},
+							CustomTags: []*envoy_type_tracing_v2.CustomTag{{
+								Tag: "cluster",
+								Type: &envoy_type_tracing_v2.CustomTag_Literal_{
+									Literal: &envoy_type_tracing_v2.CustomTag_Literal{
+										Value: "prod",
+									},
+								},
+							}},
+						},
+					}),
+				},
+			},
+		},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -646,6 +722,7 @@ func TestHTTPConnectionManager(t *testing.T) {
 				ConnectionIdleTimeout(tc.connectionIdleTimeout).
 				StreamIdleTimeout(tc.streamIdleTimeout).
 				MaxConnectionDuration(tc.maxConnectionDuration).
+				Tracing(tc.tracing).
 				DefaultFilters().
 				Get()
 
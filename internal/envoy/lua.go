@@ -0,0 +1,106 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	envoy_config_filter_http_lua_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/lua/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// LuaFilterName is the name of the Lua HTTP filter. Routes pass
+// configuration to the filter with metadata keyed by this name.
+const LuaFilterName = "envoy.filters.http.lua"
+
+// luaScript forwards the JWT claims named by the jwt_claims entry
+// of the route metadata, which holds the key of the JWT payload in
+// the jwt_authn filter's dynamic metadata and the claim forwarded in
+// each header.
+const luaScript = `
+local function forward_claims(request_handle, claims)
+  local payloads = request_handle:streamInfo():dynamicMetadata():get("envoy.filters.http.jwt_authn")
+  local payload = payloads and payloads[claims.payload]
+  for header, claim in pairs(claims.headers) do
+    -- Clients cannot supply the headers claims are forwarded in.
+    request_handle:headers():remove(header)
+    local value = payload and payload[claim]
+    if value ~= nil and type(value) ~= "table" then
+      request_handle:headers():add(header, tostring(value))
+    end
+  end
+end
+
+function envoy_on_request(request_handle)
+  local claims = request_handle:metadata():get("jwt_claims")
+  if claims ~= nil then
+    forward_claims(request_handle, claims)
+  end
+end
+`
+
+// FilterLua returns the Lua HTTP filter that forwards JWT claims
+// for the routes configured with RouteLua.
+func FilterLua() *http.HttpFilter {
+	return &http.HttpFilter{
+		Name: LuaFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_lua_v2.Lua{
+				InlineCode: luaScript,
+			}),
+		},
+	}
+}
+
+// RouteLua returns the Lua filter metadata that authenticates
+// requests to the supplied route and forwards the claims of their
+// JWTs, or nil if the route needs neither.
+func RouteLua(r *dag.Route) *_struct.Struct {
+	fields := map[string]*_struct.Value{}
+	if ba := r.BasicAuthPolicy; ba != nil {
+		fields["basic_auth"] = structValue(map[string]*_struct.Value{
+			"secret": stringValue(ba.Secret.String()),
+			"realm":  stringValue(ba.Realm),
+		})
+	}
+	if p := r.JWTProvider; p != nil && len(p.ClaimsToHeaders) > 0 {
+		headers := make(map[string]*_struct.Value, len(p.ClaimsToHeaders))
+		for header, claim := range p.ClaimsToHeaders {
+			headers[header] = stringValue(claim)
+		}
+		fields["jwt_claims"] = structValue(map[string]*_struct.Value{
+			"payload": stringValue(p.Name),
+			"headers": structValue(headers),
+		})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &_struct.Struct{Fields: fields}
+}
+
+func structValue(fields map[string]*_struct.Value) *_struct.Value {
+	return &_struct.Value{
+		Kind: &_struct.Value_StructValue{
+			StructValue: &_struct.Struct{Fields: fields},
+		},
+	}
+}
+
+func stringValue(s string) *_struct.Value {
+	return &_struct.Value{
+		Kind: &_struct.Value_StringValue{StringValue: s},
+	}
+}
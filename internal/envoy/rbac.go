@@ -0,0 +1,292 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_config_filter_http_rbac_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rbac/v2"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	envoy_config_rbac_v2 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// RBACFilterName is the name of the RBAC HTTP filter.
+const RBACFilterName = "envoy.filters.http.rbac"
+
+// FilterRBAC returns the RBAC HTTP filter. The filter has no
+// rules of its own; routes configure their rules with RouteRBAC.
+func FilterRBAC() *http.HttpFilter {
+	return &http.HttpFilter{
+		Name: RBACFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_rbac_v2.RBAC{}),
+		},
+	}
+}
+
+// RouteRBAC returns the RBAC configuration that filters requests
+// to the supplied route by address, or nil if the route has no
+// IP filter rules.
+func RouteRBAC(r *dag.Route) *envoy_config_filter_http_rbac_v2.RBACPerRoute {
+	if len(r.IPFilterRules) == 0 {
+		return nil
+	}
+
+	action := envoy_config_rbac_v2.RBAC_DENY
+	if r.IPFilterAllow {
+		action = envoy_config_rbac_v2.RBAC_ALLOW
+	}
+
+	principals := ipFilterPrincipals(r.IPFilterRules)
+	if r.IPFilterAllow && len(r.IPFilterDenyRules) > 0 {
+		// Requests from the denied ranges are rejected even
+		// if they are also in one of the allowed ranges.
+		principals = []*envoy_config_rbac_v2.Principal{
+			principalAnd(
+				principalOr(principals...),
+				principalNot(principalOr(ipFilterPrincipals(r.IPFilterDenyRules)...)),
+			),
+		}
+	}
+
+	return &envoy_config_filter_http_rbac_v2.RBACPerRoute{
+		Rbac: &envoy_config_filter_http_rbac_v2.RBAC{
+			Rules: &envoy_config_rbac_v2.RBAC{
+				Action: action,
+				Policies: map[string]*envoy_config_rbac_v2.Policy{
+					"ip-filter": {
+						Permissions: []*envoy_config_rbac_v2.Permission{{
+							Rule: &envoy_config_rbac_v2.Permission_Any{Any: true},
+						}},
+						Principals: principals,
+					},
+				},
+			},
+		},
+	}
+}
+
+// ipFilterPrincipals returns the principals that match the
+// supplied IP filter rules.
+func ipFilterPrincipals(rules []dag.IPFilterRule) []*envoy_config_rbac_v2.Principal {
+	var principals []*envoy_config_rbac_v2.Principal
+	for _, rule := range rules {
+		// The remote address is the address of the peer unless
+		// Envoy is configured to trust X-Forwarded-For.
+		if rule.ClientAddress == nil {
+			principals = append(principals, sourceIPPrincipal(rule.CIDR))
+			continue
+		}
+		principals = append(principals, clientAddressPrincipal(rule.CIDR, rule.ClientAddress))
+	}
+	return principals
+}
+
+func sourceIPPrincipal(cidr net.IPNet) *envoy_config_rbac_v2.Principal {
+	return &envoy_config_rbac_v2.Principal{
+		Identifier: &envoy_config_rbac_v2.Principal_SourceIp{
+			SourceIp: cidrRange(cidr),
+		},
+	}
+}
+
+// clientAddressPrincipal returns a principal that matches the client
+// addresses in the supplied IPv4 range. Envoy's RBAC filter can only
+// match the peer address, so the principal matches the X-Forwarded-For
+// entry Envoy derives the client address from instead.
+func clientAddressPrincipal(cidr net.IPNet, ca *dag.ClientAddressPolicy) *envoy_config_rbac_v2.Principal {
+	// The client's entry is followed by the entries of the
+	// trusted hops, the last of which is the peer address
+	// Envoy appends.
+	following := ca.NumTrustedHops
+	if ca.SkipXFFAppend {
+		following--
+	}
+
+	entry := func(address string) string {
+		regex := `(.*,)?\s*` + address + `\s*`
+		if following > 0 {
+			regex += fmt.Sprintf("(,[^,]*){%d}", following)
+		}
+		return regex
+	}
+
+	// Envoy uses the peer address as the client address if
+	// X-Forwarded-For is missing, has fewer entries than there
+	// are trusted hops, or the client's entry is not an address.
+	client := principalOr(
+		xffPrincipal(entry(ipv4Regex(cidr))),
+		principalAnd(
+			principalNot(xffPrincipal(entry(ipAddressRegex))),
+			sourceIPPrincipal(cidr),
+		),
+	)
+
+	if len(ca.TrustedCIDRs) == 0 {
+		return client
+	}
+
+	// The X-Forwarded-For headers of untrusted peers are
+	// ignored, so their client address is the peer address.
+	var trusted []*envoy_config_rbac_v2.Principal
+	for _, c := range ca.TrustedCIDRs {
+		trusted = append(trusted, sourceIPPrincipal(c))
+	}
+	trustedPeer := principalOr(trusted...)
+
+	return principalOr(
+		principalAnd(trustedPeer, client),
+		principalAnd(principalNot(trustedPeer), sourceIPPrincipal(cidr)),
+	)
+}
+
+// ipAddressRegex matches the X-Forwarded-For entries that Envoy
+// may parse as an address: the dotted decimal IPv4 addresses, and
+// the entries made of the characters of IPv6 addresses that have
+// a colon.
+var ipAddressRegex = "(" + ipv4Regex(net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}) + `|[0-9A-Fa-f.:]*:[0-9A-Fa-f.:]*)`
+
+func xffPrincipal(regex string) *envoy_config_rbac_v2.Principal {
+	return &envoy_config_rbac_v2.Principal{
+		Identifier: &envoy_config_rbac_v2.Principal_Header{
+			Header: &envoy_api_v2_route.HeaderMatcher{
+				Name: "x-forwarded-for",
+				HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+					SafeRegexMatch: SafeRegexMatch(regex),
+				},
+			},
+		},
+	}
+}
+
+func principalOr(ids ...*envoy_config_rbac_v2.Principal) *envoy_config_rbac_v2.Principal {
+	return &envoy_config_rbac_v2.Principal{
+		Identifier: &envoy_config_rbac_v2.Principal_OrIds{
+			OrIds: &envoy_config_rbac_v2.Principal_Set{Ids: ids},
+		},
+	}
+}
+
+func principalAnd(ids ...*envoy_config_rbac_v2.Principal) *envoy_config_rbac_v2.Principal {
+	return &envoy_config_rbac_v2.Principal{
+		Identifier: &envoy_config_rbac_v2.Principal_AndIds{
+			AndIds: &envoy_config_rbac_v2.Principal_Set{Ids: ids},
+		},
+	}
+}
+
+func principalNot(id *envoy_config_rbac_v2.Principal) *envoy_config_rbac_v2.Principal {
+	return &envoy_config_rbac_v2.Principal{
+		Identifier: &envoy_config_rbac_v2.Principal_NotId{NotId: id},
+	}
+}
+
+// ipv4Regex returns a regular expression that matches the
+// dotted decimal form of the addresses in the supplied range.
+func ipv4Regex(cidr net.IPNet) string {
+	ip := cidr.IP.To4()
+	ones, bits := cidr.Mask.Size()
+	ones -= bits - 32
+
+	octets := make([]string, 4)
+	for i := range octets {
+		n := ones - 8*i
+		switch {
+		case n < 0:
+			n = 0
+		case n > 8:
+			n = 8
+		}
+		lo := int(ip[i]) & (0xff << (8 - n)) & 0xff
+		hi := lo | 0xff>>n
+		octets[i] = decimalRangeRegex(lo, hi)
+	}
+	return strings.Join(octets, `\.`)
+}
+
+// decimalRangeRegex returns a regular expression that matches the
+// decimal numbers from lo to hi, which are at most 999, written
+// without leading zeros.
+func decimalRangeRegex(lo, hi int) string {
+	var alternatives []string
+	for _, digits := range [][2]int{{0, 9}, {10, 99}, {100, 999}} {
+		from, to := lo, hi
+		if from < digits[0] {
+			from = digits[0]
+		}
+		if to > digits[1] {
+			to = digits[1]
+		}
+		if from <= to {
+			alternatives = append(alternatives, digitRangeRegex(strconv.Itoa(from), strconv.Itoa(to))...)
+		}
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+	return "(" + strings.Join(alternatives, "|") + ")"
+}
+
+// digitRangeRegex returns the alternatives of a regular expression
+// that matches the numbers from lo to hi, which have the same number
+// of digits.
+func digitRangeRegex(lo, hi string) []string {
+	digits := func(lo, hi byte) string {
+		if lo == hi {
+			return string(lo)
+		}
+		return "[" + string(lo) + "-" + string(hi) + "]"
+	}
+
+	if len(lo) == 1 {
+		return []string{digits(lo[0], hi[0])}
+	}
+
+	prefixed := func(prefix string, alternatives []string) []string {
+		for i := range alternatives {
+			alternatives[i] = prefix + alternatives[i]
+		}
+		return alternatives
+	}
+
+	if lo[0] == hi[0] {
+		return prefixed(lo[:1], digitRangeRegex(lo[1:], hi[1:]))
+	}
+
+	rest := len(lo) - 1
+	zeros, nines := strings.Repeat("0", rest), strings.Repeat("9", rest)
+
+	// Numbers between those that share the first digit of lo
+	// or hi can match any digits after their first.
+	first, last := lo[0], hi[0]
+	var head, tail []string
+	if lo[1:] != zeros {
+		head = prefixed(lo[:1], digitRangeRegex(lo[1:], nines))
+		first++
+	}
+	if hi[1:] != nines {
+		tail = prefixed(hi[:1], digitRangeRegex(zeros, hi[1:]))
+		last--
+	}
+	if first <= last {
+		head = append(head, digits(first, last)+strings.Repeat("[0-9]", rest))
+	}
+	return append(head, tail...)
+}
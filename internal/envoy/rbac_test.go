@@ -0,0 +1,231 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+
+	envoy_config_rbac_v2 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+	"github.com/projectcontour/contour/internal/assert"
+	"github.com/projectcontour/contour/internal/dag"
+)
+
+func TestIPv4Regex(t *testing.T) {
+	tests := map[string]string{
+		"single address": "203.0.113.7/32",
+		"octet boundary": "10.0.0.0/8",
+		"partial octet":  "192.168.1.128/25",
+		"short prefix":   "172.16.0.0/12",
+		"everything":     "0.0.0.0/0",
+	}
+
+	// Every value of every octet, with the other octets taken
+	// from the network address, covers the range boundaries.
+	addresses := func(network net.IP) []net.IP {
+		var ips []net.IP
+		for i := 0; i < 4; i++ {
+			for v := 0; v < 256; v++ {
+				ip := make(net.IP, 4)
+				copy(ip, network.To4())
+				ip[i] = byte(v)
+				ips = append(ips, ip)
+			}
+		}
+		return ips
+	}
+
+	for name, cidr := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, ipnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			re := regexp.MustCompile("^(?:" + ipv4Regex(*ipnet) + ")$")
+			for _, ip := range addresses(ipnet.IP) {
+				assert.Equal(t, ipnet.Contains(ip), re.MatchString(ip.String()))
+			}
+			assert.Equal(t, false, re.MatchString(fmt.Sprintf("0%s", ipnet.IP)))
+		})
+	}
+}
+
+func TestDecimalRangeRegex(t *testing.T) {
+	tests := map[string]struct {
+		lo, hi int
+		want   string
+	}{
+		"single":      {lo: 7, hi: 7, want: "7"},
+		"one digit":   {lo: 0, hi: 9, want: "[0-9]"},
+		"upper half":  {lo: 128, hi: 255, want: "(12[8-9]|1[3-9][0-9]|2[0-4][0-9]|25[0-5])"},
+		"whole octet": {lo: 0, hi: 255, want: "([0-9]|[1-9][0-9]|1[0-9][0-9]|2[0-4][0-9]|25[0-5])"},
+		"sixteen":     {lo: 16, hi: 31, want: "(1[6-9]|2[0-9]|3[0-1])"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, decimalRangeRegex(tc.lo, tc.hi))
+		})
+	}
+}
+
+func TestClientAddressPrincipal(t *testing.T) {
+	// match evaluates the principal for a request from the
+	// supplied peer with the supplied X-Forwarded-For header,
+	// which is missing if xff is empty.
+	var match func(p *envoy_config_rbac_v2.Principal, peer, xff string) bool
+	match = func(p *envoy_config_rbac_v2.Principal, peer, xff string) bool {
+		switch id := p.Identifier.(type) {
+		case *envoy_config_rbac_v2.Principal_OrIds:
+			for _, p := range id.OrIds.Ids {
+				if match(p, peer, xff) {
+					return true
+				}
+			}
+			return false
+		case *envoy_config_rbac_v2.Principal_AndIds:
+			for _, p := range id.AndIds.Ids {
+				if !match(p, peer, xff) {
+					return false
+				}
+			}
+			return true
+		case *envoy_config_rbac_v2.Principal_NotId:
+			return !match(id.NotId, peer, xff)
+		case *envoy_config_rbac_v2.Principal_SourceIp:
+			_, cidr, err := net.ParseCIDR(fmt.Sprintf("%s/%d", id.SourceIp.AddressPrefix, id.SourceIp.PrefixLen.GetValue()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return cidr.Contains(net.ParseIP(peer))
+		case *envoy_config_rbac_v2.Principal_Header:
+			assert.Equal(t, "x-forwarded-for", id.Header.Name)
+			re := regexp.MustCompile("^(?:" + id.Header.GetSafeRegexMatch().Regex + ")$")
+			return xff != "" && re.MatchString(xff)
+		default:
+			t.Fatalf("unexpected principal %v", p)
+			return false
+		}
+	}
+
+	cidr := func(s string) net.IPNet {
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *ipnet
+	}
+
+	// The X-Forwarded-For headers are those the RBAC filter
+	// sees, after Envoy has appended the peer address.
+	tests := map[string]struct {
+		policy dag.ClientAddressPolicy
+		peer   string
+		xff    string
+		want   bool
+	}{
+		"client entry in range": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 2},
+			peer:   "10.0.0.1",
+			xff:    "192.168.1.7, 10.1.1.1,10.0.0.1",
+			want:   true,
+		},
+		"client entry out of range": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 2},
+			peer:   "10.0.0.1",
+			xff:    "192.168.1.7, 203.0.113.1, 10.1.1.1,10.0.0.1",
+		},
+		"ipv6 client entry": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 2},
+			peer:   "192.168.1.9",
+			xff:    "2001:db8::1, 10.1.1.1,192.168.1.9",
+		},
+		"too few entries, peer in range": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 2},
+			peer:   "192.168.1.9",
+			xff:    "203.0.113.1,192.168.1.9",
+			want:   true,
+		},
+		"too few entries, peer out of range": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 2},
+			peer:   "10.0.0.1",
+			xff:    "192.168.1.7,10.0.0.1",
+		},
+		"only the peer entry": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 1},
+			peer:   "192.168.1.9",
+			xff:    "192.168.1.9",
+			want:   true,
+		},
+		"client entry not an address": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 2},
+			peer:   "192.168.1.9",
+			xff:    "unknown, 10.1.1.1,192.168.1.9",
+			want:   true,
+		},
+		"skip append": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 1, SkipXFFAppend: true},
+			peer:   "10.0.0.1",
+			xff:    "192.168.1.7",
+			want:   true,
+		},
+		"skip append, missing header": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 1, SkipXFFAppend: true},
+			peer:   "192.168.1.9",
+			want:   true,
+		},
+		"skip append, client entry out of range": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 1, SkipXFFAppend: true},
+			peer:   "192.168.1.9",
+			xff:    "203.0.113.1",
+		},
+		"trusted peer": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 1, TrustedCIDRs: []net.IPNet{cidr("10.0.0.0/8")}},
+			peer:   "10.0.0.1",
+			xff:    "192.168.1.7,10.0.0.1",
+			want:   true,
+		},
+		"untrusted peer in range": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 1, TrustedCIDRs: []net.IPNet{cidr("10.0.0.0/8")}},
+			peer:   "192.168.1.9",
+			xff:    "203.0.113.1,192.168.1.9",
+			want:   true,
+		},
+		"untrusted peer out of range": {
+			policy: dag.ClientAddressPolicy{NumTrustedHops: 1, TrustedCIDRs: []net.IPNet{cidr("10.0.0.0/8")}},
+			peer:   "203.0.113.1",
+			xff:    "192.168.1.7,203.0.113.1",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := clientAddressPrincipal(cidr("192.168.1.0/24"), &tc.policy)
+			assert.Equal(t, tc.want, match(p, tc.peer, tc.xff))
+		})
+	}
+}
+
+func TestIPAddressRegex(t *testing.T) {
+	re := regexp.MustCompile("^(?:" + ipAddressRegex + ")$")
+	for _, s := range []string{"192.168.1.7", "0.0.0.0", "2001:db8::1", "::ffff:10.0.0.1", "::"} {
+		assert.Equal(t, true, re.MatchString(s))
+	}
+	for _, s := range []string{"", "unknown", "1.2.3", "256.0.0.1", "01.2.3.4", strings.Repeat("1", 4)} {
+		assert.Equal(t, false, re.MatchString(s))
+	}
+}
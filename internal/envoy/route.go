@@ -15,14 +15,20 @@ package envoy
 
 import (
 	"fmt"
+	"net/http"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/golang/protobuf/ptypes/duration"
+	_struct "github.com/golang/protobuf/ptypes/struct"
 	wrappers "github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/protobuf"
@@ -53,6 +59,36 @@ func RouteMatch(route *dag.Route) *envoy_api_v2_route.RouteMatch {
 	}
 }
 
+// TypedPerFilterConfig returns the configuration of the HTTP filters
+// that the supplied route overrides, or nil if it overrides none.
+func TypedPerFilterConfig(r *dag.Route) map[string]*any.Any {
+	config := map[string]*any.Any{}
+	if rbac := RouteRBAC(r); rbac != nil {
+		config[RBACFilterName] = protobuf.MustMarshalAny(rbac)
+	}
+	if fault := RouteFault(r); fault != nil {
+		config[wellknown.Fault] = protobuf.MustMarshalAny(fault)
+	}
+	if len(config) == 0 {
+		return nil
+	}
+	return config
+}
+
+// RouteMetadata returns the metadata that configures the HTTP
+// filters for the supplied route, or nil if there is none.
+func RouteMetadata(r *dag.Route) *envoy_api_v2_core.Metadata {
+	lua := RouteLua(r)
+	if lua == nil {
+		return nil
+	}
+	return &envoy_api_v2_core.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			LuaFilterName: lua,
+		},
+	}
+}
+
 // RouteRoute creates a *envoy_api_v2_route.Route_Route for the services supplied.
 // If len(services) is greater than one, the route's action will be a
 // weighted cluster.
@@ -61,7 +97,9 @@ func RouteRoute(r *dag.Route) *envoy_api_v2_route.Route_Route {
 		RetryPolicy:           retryPolicy(r),
 		Timeout:               responseTimeout(r),
 		IdleTimeout:           idleTimeout(r),
+		MaxGrpcTimeout:        maxGrpcTimeout(r),
 		PrefixRewrite:         r.PrefixRewrite,
+		RegexRewrite:          regexRewrite(r),
 		HashPolicy:            hashPolicy(r),
 		RequestMirrorPolicies: mirrorPolicy(r),
 	}
@@ -114,6 +152,19 @@ func hashPolicy(r *dag.Route) []*envoy_api_v2_route.RouteAction_HashPolicy {
 	return nil
 }
 
+// regexRewrite returns a regex path rewrite for the route, or nil
+// if the route does not rewrite its path with a regular expression.
+func regexRewrite(r *dag.Route) *matcher.RegexMatchAndSubstitute {
+	if r.RegexRewrite == nil {
+		return nil
+	}
+
+	return &matcher.RegexMatchAndSubstitute{
+		Pattern:      SafeRegexMatch(r.RegexRewrite.Pattern),
+		Substitution: r.RegexRewrite.Substitution,
+	}
+}
+
 func mirrorPolicy(r *dag.Route) []*envoy_api_v2_route.RouteAction_RequestMirrorPolicy {
 	if r.MirrorPolicy == nil {
 		return nil
@@ -142,9 +193,19 @@ func idleTimeout(r *dag.Route) *duration.Duration {
 	if r.TimeoutPolicy == nil {
 		return nil
 	}
+	if r.Websocket && r.TimeoutPolicy.WebsocketIdleTimeout != 0 {
+		return timeout(r.TimeoutPolicy.WebsocketIdleTimeout)
+	}
 	return timeout(r.TimeoutPolicy.IdleTimeout)
 }
 
+func maxGrpcTimeout(r *dag.Route) *duration.Duration {
+	if r.TimeoutPolicy == nil {
+		return nil
+	}
+	return timeout(r.TimeoutPolicy.MaxGrpcTimeout)
+}
+
 // timeout interprets a time.Duration with respect to
 // Envoy's timeout logic. Zero durations are interpreted
 // as nil, therefore remaining unset. Negative durations
@@ -219,6 +280,36 @@ func HeaderValueList(hvm map[string]string, app bool) []*envoy_api_v2_core.Heade
 	return hvs
 }
 
+// SecurityHeaders returns the response headers of the supplied
+// *dag.SecurityHeadersPolicy, or nil if the policy adds none.
+func SecurityHeaders(sh *dag.SecurityHeadersPolicy) map[string]string {
+	if sh == nil {
+		return nil
+	}
+
+	headers := make(map[string]string, len(sh.Set)+1)
+	for k, v := range sh.Set {
+		// Envoy treats % as the start of a variable.
+		headers[k] = strings.Replace(v, "%", "%%", -1)
+	}
+
+	if sh.HSTS != nil {
+		value := fmt.Sprintf("max-age=%d", int64(sh.HSTS.MaxAge/time.Second))
+		if sh.HSTS.IncludeSubdomains {
+			value += "; includeSubDomains"
+		}
+		if sh.HSTS.Preload {
+			value += "; preload"
+		}
+		headers["Strict-Transport-Security"] = value
+	}
+
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
 // singleSimpleCluster determines whether we can use a RouteAction_Cluster
 // or must use a RouteAction_WeighedCluster to encode additional routing data.
 func singleSimpleCluster(clusters []*dag.Cluster) bool {
@@ -264,7 +355,8 @@ func weightedClusters(clusters []*dag.Cluster) *envoy_api_v2_route.WeightedClust
 			c.RequestHeadersToRemove = cluster.RequestHeadersPolicy.Remove
 		}
 		if cluster.ResponseHeadersPolicy != nil {
-			c.ResponseHeadersToAdd = HeaderValueList(cluster.ResponseHeadersPolicy.Set, false)
+			c.ResponseHeadersToAdd = append(HeaderValueList(cluster.ResponseHeadersPolicy.Set, false),
+				HeaderValueList(cluster.ResponseHeadersPolicy.Add, true)...)
 			c.ResponseHeadersToRemove = cluster.ResponseHeadersPolicy.Remove
 		}
 		wc.Clusters = append(wc.Clusters, c)
@@ -285,9 +377,20 @@ func weightedClusters(clusters []*dag.Cluster) *envoy_api_v2_route.WeightedClust
 // VirtualHost creates a new route.VirtualHost.
 func VirtualHost(hostname string, routes ...*envoy_api_v2_route.Route) *envoy_api_v2_route.VirtualHost {
 	domains := []string{hostname}
-	if hostname != "*" {
+	switch {
+	case hostname == "*":
+	case strings.HasPrefix(hostname, "*."):
+		// Envoy domains allow only a single wildcard, so a
+		// wildcard hostname cannot also match any port. Match
+		// the HTTP and HTTPS ports that clients add to the Host
+		// header instead.
+		domains = append(domains, hostname+":80", hostname+":443")
+	default:
 		// NOTE(jpeach) see also envoy.FilterMisdirectedRequests().
-		domains = append(domains, hostname+":*")
+		// Envoy prefers wildcard suffixes to wildcard prefixes,
+		// so the ports that wildcard hostnames match are also
+		// matched exactly, ahead of any wildcard hostname.
+		domains = append(domains, hostname+":80", hostname+":443", hostname+":*")
 	}
 
 	return &envoy_api_v2_route.VirtualHost{
@@ -297,6 +400,16 @@ func VirtualHost(hostname string, routes ...*envoy_api_v2_route.Route) *envoy_ap
 	}
 }
 
+// MisdirectedRequest returns a *envoy_api_v2_route.Route_DirectResponse
+// that responds with a 421 Misdirected Request status.
+func MisdirectedRequest() *envoy_api_v2_route.Route_DirectResponse {
+	return &envoy_api_v2_route.Route_DirectResponse{
+		DirectResponse: &envoy_api_v2_route.DirectResponseAction{
+			Status: http.StatusMisdirectedRequest,
+		},
+	}
+}
+
 // RouteConfiguration returns a *v2.RouteConfiguration.
 func RouteConfiguration(name string, virtualhosts ...*envoy_api_v2_route.VirtualHost) *v2.RouteConfiguration {
 	return &v2.RouteConfiguration{
@@ -336,6 +449,10 @@ func headerMatcher(headers []dag.HeaderCondition) []*envoy_api_v2_route.HeaderMa
 			header.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: h.Value}
 		case "contains":
 			header.HeaderMatchSpecifier = containsMatch(h.Value)
+		case "regex":
+			header.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+				SafeRegexMatch: SafeRegexMatch(h.Value),
+			}
 		case "present":
 			header.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_PresentMatch{PresentMatch: true}
 		}
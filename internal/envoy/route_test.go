@@ -20,6 +20,7 @@ import (
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/projectcontour/contour/internal/assert"
 	"github.com/projectcontour/contour/internal/dag"
@@ -76,6 +77,96 @@ func TestRouteRoute(t *testing.T) {
 				},
 			},
 		},
+		"websocket idle timeout": {
+			route: &dag.Route{
+				Websocket: true,
+				Clusters:  []*dag.Cluster{c1},
+				TimeoutPolicy: &dag.TimeoutPolicy{
+					IdleTimeout:          10 * time.Second,
+					WebsocketIdleTimeout: time.Hour,
+				},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					IdleTimeout: protobuf.Duration(time.Hour),
+					UpgradeConfigs: []*envoy_api_v2_route.RouteAction_UpgradeConfig{{
+						UpgradeType: "websocket",
+					}},
+				},
+			},
+		},
+		"websocket idle timeout without websockets": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c1},
+				TimeoutPolicy: &dag.TimeoutPolicy{
+					IdleTimeout:          10 * time.Second,
+					WebsocketIdleTimeout: time.Hour,
+				},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					IdleTimeout: protobuf.Duration(10 * time.Second),
+				},
+			},
+		},
+		"max grpc timeout": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c1},
+				TimeoutPolicy: &dag.TimeoutPolicy{
+					MaxGrpcTimeout: 30 * time.Second,
+				},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					MaxGrpcTimeout: protobuf.Duration(30 * time.Second),
+				},
+			},
+		},
+		"infinite max grpc timeout": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c1},
+				TimeoutPolicy: &dag.TimeoutPolicy{
+					MaxGrpcTimeout: -1,
+				},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					MaxGrpcTimeout: protobuf.Duration(0),
+				},
+			},
+		},
+		"regex rewrite": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c1},
+				RegexRewrite: &dag.RegexRewrite{
+					Pattern:      "^/v1/(.*)/detail$",
+					Substitution: "/api/\\1",
+				},
+			},
+			want: &envoy_api_v2_route.Route_Route{
+				Route: &envoy_api_v2_route.RouteAction{
+					ClusterSpecifier: &envoy_api_v2_route.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					RegexRewrite: &matcher.RegexMatchAndSubstitute{
+						Pattern:      SafeRegexMatch("^/v1/(.*)/detail$"),
+						Substitution: "/api/\\1",
+					},
+				},
+			},
+		},
 		"websocket": {
 			route: &dag.Route{
 				Websocket: true,
@@ -659,7 +750,15 @@ func TestVirtualHost(t *testing.T) {
 			port:     9999,
 			want: &envoy_api_v2_route.VirtualHost{
 				Name:    "www.example.com",
-				Domains: []string{"www.example.com", "www.example.com:*"},
+				Domains: []string{"www.example.com", "www.example.com:80", "www.example.com:443", "www.example.com:*"},
+			},
+		},
+		"wildcard hostname": {
+			hostname: "*.example.com",
+			port:     9999,
+			want: &envoy_api_v2_route.VirtualHost{
+				Name:    "*.example.com",
+				Domains: []string{"*.example.com", "*.example.com:80", "*.example.com:443"},
 			},
 		},
 	}
@@ -671,6 +770,61 @@ func TestVirtualHost(t *testing.T) {
 	}
 }
 
+func TestSecurityHeaders(t *testing.T) {
+	tests := map[string]struct {
+		sh   *dag.SecurityHeadersPolicy
+		want map[string]string
+	}{
+		"nil policy": {
+			sh:   nil,
+			want: nil,
+		},
+		"empty policy": {
+			sh:   &dag.SecurityHeadersPolicy{},
+			want: nil,
+		},
+		"hsts max age": {
+			sh: &dag.SecurityHeadersPolicy{
+				HSTS: &dag.HSTSPolicy{MaxAge: 90 * time.Minute},
+			},
+			want: map[string]string{
+				"Strict-Transport-Security": "max-age=5400",
+			},
+		},
+		"hsts preload": {
+			sh: &dag.SecurityHeadersPolicy{
+				HSTS: &dag.HSTSPolicy{
+					MaxAge:            8760 * time.Hour,
+					IncludeSubdomains: true,
+					Preload:           true,
+				},
+			},
+			want: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains; preload",
+			},
+		},
+		"additional headers are escaped": {
+			sh: &dag.SecurityHeadersPolicy{
+				Set: map[string]string{
+					"X-Frame-Options": "DENY",
+					"X-Literal":       "100%",
+				},
+			},
+			want: map[string]string{
+				"X-Frame-Options": "DENY",
+				"X-Literal":       "100%%",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := SecurityHeaders(tc.sh)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
 func TestUpgradeHTTPS(t *testing.T) {
 	got := UpgradeHTTPS()
 	want := &envoy_api_v2_route.Route_Redirect{
@@ -746,6 +900,23 @@ func TestRouteMatch(t *testing.T) {
 				}},
 			},
 		},
+		"regex match": {
+			route: &dag.Route{
+				HeaderConditions: []dag.HeaderCondition{{
+					Name:      "Cookie",
+					Value:     `.*(^|;\s*)canary=always(;|$).*`,
+					MatchType: "regex",
+				}},
+			},
+			want: &envoy_api_v2_route.RouteMatch{
+				Headers: []*envoy_api_v2_route.HeaderMatcher{{
+					Name: "Cookie",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+						SafeRegexMatch: SafeRegexMatch(`.*(^|;\s*)canary=always(;|$).*`),
+					},
+				}},
+			},
+		},
 		"path prefix": {
 			route: &dag.Route{
 				PathCondition: &dag.PrefixCondition{
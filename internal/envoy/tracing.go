@@ -0,0 +1,202 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	api "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	envoy_config_trace_v2 "github.com/envoyproxy/go-control-plane/envoy/config/trace/v2"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
+	envoy_type_tracing_v2 "github.com/envoyproxy/go-control-plane/envoy/type/tracing/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// tracingCollectorClusterName is the name of the static
+// bootstrap cluster that spans are sent to.
+const tracingCollectorClusterName = "tracing-collector"
+
+// zipkinCollectorEndpoint is the path on the collector that
+// spans are sent to. Zipkin compatible collectors, including
+// the OpenTelemetry collector's Zipkin receiver, accept spans
+// on this path.
+const zipkinCollectorEndpoint = "/api/v2/spans"
+
+// TracingConfig holds the distributed tracing configuration shared
+// by the Envoy bootstrap and the HTTP connection managers.
+type TracingConfig struct {
+	// CollectorAddress is the DNS name of the trace collector.
+	CollectorAddress string
+
+	// CollectorPort is the port of the trace collector.
+	CollectorPort int
+
+	// Protocol is the protocol used to send spans to the collector.
+	// Valid values: 'zipkin', 'zipkin-proto'
+	// If not set, defaults to 'zipkin'.
+	Protocol string
+
+	// ServiceName is the name of the service reported in spans.
+	// If not set, Envoy's service cluster name is reported.
+	ServiceName string
+
+	// Sampling is the percentage of requests to trace, from 0 to 100.
+	Sampling float64
+
+	// CustomTags are added to every span.
+	CustomTags []TracingCustomTag
+}
+
+// TracingCustomTag describes a tag added to every span. Exactly
+// one of Literal, RequestHeader or Environment should be set.
+type TracingCustomTag struct {
+	// Name is the name of the tag.
+	Name string
+
+	// Literal is a static value for the tag.
+	Literal string
+
+	// RequestHeader is the name of a request header to
+	// take the value of the tag from.
+	RequestHeader string
+
+	// Environment is the name of an environment variable
+	// in the Envoy process to take the value of the tag from.
+	Environment string
+}
+
+// ValidTracingProtocol returns true if the supplied protocol
+// is a known tracing protocol.
+func ValidTracingProtocol(protocol string) bool {
+	switch protocol {
+	case "", "zipkin", "zipkin-proto":
+		return true
+	default:
+		return false
+	}
+}
+
+// httpConnectionManagerTracing returns the tracing configuration
+// for a HTTP connection manager, or nil if tracing is not configured.
+func httpConnectionManagerTracing(t *TracingConfig) *http.HttpConnectionManager_Tracing {
+	if t == nil {
+		return nil
+	}
+
+	return &http.HttpConnectionManager_Tracing{
+		OverallSampling: &envoy_type.Percent{
+			Value: t.Sampling,
+		},
+		CustomTags: customTags(t.CustomTags),
+	}
+}
+
+func customTags(tags []TracingCustomTag) []*envoy_type_tracing_v2.CustomTag {
+	var ct []*envoy_type_tracing_v2.CustomTag
+	for _, tag := range tags {
+		switch {
+		case tag.Literal != "":
+			ct = append(ct, &envoy_type_tracing_v2.CustomTag{
+				Tag: tag.Name,
+				Type: &envoy_type_tracing_v2.CustomTag_Literal_{
+					Literal: &envoy_type_tracing_v2.CustomTag_Literal{
+						Value: tag.Literal,
+					},
+				},
+			})
+		case tag.RequestHeader != "":
+			ct = append(ct, &envoy_type_tracing_v2.CustomTag{
+				Tag: tag.Name,
+				Type: &envoy_type_tracing_v2.CustomTag_RequestHeader{
+					RequestHeader: &envoy_type_tracing_v2.CustomTag_Header{
+						Name: tag.RequestHeader,
+					},
+				},
+			})
+		case tag.Environment != "":
+			ct = append(ct, &envoy_type_tracing_v2.CustomTag{
+				Tag: tag.Name,
+				Type: &envoy_type_tracing_v2.CustomTag_Environment_{
+					Environment: &envoy_type_tracing_v2.CustomTag_Environment{
+						Name: tag.Environment,
+					},
+				},
+			})
+		}
+	}
+	return ct
+}
+
+// RouteTracing returns the tracing sampling override for
+// the supplied route, or nil if the route has none.
+func RouteTracing(r *dag.Route) *envoy_api_v2_route.Tracing {
+	if r.TracingPolicy == nil {
+		return nil
+	}
+
+	// Percentages are expressed in millionths so that
+	// fractional percentages are not truncated.
+	return &envoy_api_v2_route.Tracing{
+		OverallSampling: &envoy_type.FractionalPercent{
+			Numerator:   uint32(math.Round(r.TracingPolicy.Sampling * 10000)),
+			Denominator: envoy_type.FractionalPercent_MILLION,
+		},
+	}
+}
+
+// tracingProvider returns the HTTP tracer for the bootstrap.
+func tracingProvider(t *TracingConfig) *envoy_config_trace_v2.Tracing {
+	version := envoy_config_trace_v2.ZipkinConfig_HTTP_JSON
+	if t.Protocol == "zipkin-proto" {
+		version = envoy_config_trace_v2.ZipkinConfig_HTTP_PROTO
+	}
+
+	return &envoy_config_trace_v2.Tracing{
+		Http: &envoy_config_trace_v2.Tracing_Http{
+			Name: wellknown.Zipkin,
+			ConfigType: &envoy_config_trace_v2.Tracing_Http_TypedConfig{
+				TypedConfig: protobuf.MustMarshalAny(&envoy_config_trace_v2.ZipkinConfig{
+					CollectorCluster:         tracingCollectorClusterName,
+					CollectorEndpoint:        zipkinCollectorEndpoint,
+					CollectorEndpointVersion: version,
+					TraceId_128Bit:           true,
+				}),
+			},
+		},
+	}
+}
+
+// tracingCollectorCluster returns the static bootstrap
+// cluster for the trace collector.
+func tracingCollectorCluster(namespace string, t *TracingConfig) *api.Cluster {
+	return &api.Cluster{
+		Name:                 tracingCollectorClusterName,
+		AltStatName:          strings.Join([]string{namespace, tracingCollectorClusterName}, "_"),
+		ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
+		ClusterDiscoveryType: ClusterDiscoveryType(api.Cluster_STRICT_DNS),
+		LbPolicy:             api.Cluster_ROUND_ROBIN,
+		LoadAssignment: &api.ClusterLoadAssignment{
+			ClusterName: tracingCollectorClusterName,
+			Endpoints: Endpoints(
+				SocketAddress(t.CollectorAddress, t.CollectorPort),
+			),
+		},
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"testing"
+
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
+	envoy_type_tracing_v2 "github.com/envoyproxy/go-control-plane/envoy/type/tracing/v2"
+	"github.com/projectcontour/contour/internal/assert"
+	"github.com/projectcontour/contour/internal/dag"
+)
+
+func TestHTTPConnectionManagerTracing(t *testing.T) {
+	tests := map[string]struct {
+		tracing *TracingConfig
+		want    *http.HttpConnectionManager_Tracing
+	}{
+		"nil tracing config": {
+			tracing: nil,
+			want:    nil,
+		},
+		"sampling": {
+			tracing: &TracingConfig{
+				Sampling: 12.5,
+			},
+			want: &http.HttpConnectionManager_Tracing{
+				OverallSampling: &envoy_type.Percent{
+					Value: 12.5,
+				},
+			},
+		},
+		"custom tags": {
+			tracing: &TracingConfig{
+				Sampling: 100,
+				CustomTags: []TracingCustomTag{{
+					Name:    "cluster",
+					Literal: "prod",
+				}, {
+					Name:          "request-id",
+					RequestHeader: "x-request-id",
+				}, {
+					Name:        "node",
+					Environment: "ENVOY_POD_NAME",
+				}},
+			},
+			want: &http.HttpConnectionManager_Tracing{
+				OverallSampling: &envoy_type.Percent{
+					Value: 100,
+				},
+				CustomTags: []*envoy_type_tracing_v2.CustomTag{{
+					Tag: "cluster",
+					Type: &envoy_type_tracing_v2.CustomTag_Literal_{
+						Literal: &envoy_type_tracing_v2.CustomTag_Literal{
+							Value: "prod",
+						},
+					},
+				}, {
+					Tag: "request-id",
+					Type: &envoy_type_tracing_v2.CustomTag_RequestHeader{
+						RequestHeader: &envoy_type_tracing_v2.CustomTag_Header{
+							Name: "x-request-id",
+						},
+					},
+				}, {
+					Tag: "node",
+					Type: &envoy_type_tracing_v2.CustomTag_Environment_{
+						Environment: &envoy_type_tracing_v2.CustomTag_Environment{
+							Name: "ENVOY_POD_NAME",
+						},
+					},
+				}},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := httpConnectionManagerTracing(tc.tracing)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRouteTracing(t *testing.T) {
+	tests := map[string]struct {
+		route *dag.Route
+		want  *envoy_api_v2_route.Tracing
+	}{
+		"no tracing policy": {
+			route: &dag.Route{},
+			want:  nil,
+		},
+		"whole percentage": {
+			route: &dag.Route{
+				TracingPolicy: &dag.TracingPolicy{Sampling: 50},
+			},
+			want: &envoy_api_v2_route.Tracing{
+				OverallSampling: &envoy_type.FractionalPercent{
+					Numerator:   500000,
+					Denominator: envoy_type.FractionalPercent_MILLION,
+				},
+			},
+		},
+		"fractional percentage": {
+			route: &dag.Route{
+				TracingPolicy: &dag.TracingPolicy{Sampling: 0.01},
+			},
+			want: &envoy_api_v2_route.Tracing{
+				OverallSampling: &envoy_type.FractionalPercent{
+					Numerator:   100,
+					Denominator: envoy_type.FractionalPercent_MILLION,
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := RouteTracing(tc.route)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestVirtualHostAliases(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/wildcard"),
+		Type:       "kubernetes.io/tls",
+		Data:       secretdata(WILDCARD_CERTIFICATE, WILDCARD_RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	rh.OnAdd(&v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/other"),
+		Type:       "kubernetes.io/tls",
+		Data:       secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	})
+
+	for _, name := range []string{"default/tenants", "default/admin"} {
+		rh.OnAdd(&v1.Service{
+			ObjectMeta: fixture.ObjectMeta(name),
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol:   "TCP",
+					Port:       8080,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		})
+	}
+
+	// A wildcard fqdn with an alias shares its routes and TLS
+	// with the alias.
+	p1 := fixture.NewProxy("tenants").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn:    "*.example.com",
+				Aliases: []string{"example.com"},
+				TLS: &projcontour.TLS{
+					SecretName: "wildcard",
+				},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "tenants",
+					Port: 8080,
+				}},
+			}},
+		})
+	rh.OnAdd(p1)
+
+	// An exact fqdn covered by the wildcard.
+	p2 := fixture.NewProxy("admin").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "admin.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: "wildcard",
+				},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "admin",
+					Port: 8080,
+				}},
+			}},
+		})
+	rh.OnAdd(p2)
+
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		TypeUrl: listenerType,
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: appendFilterChains(
+					filterchaintls("*.example.com", sec1,
+						httpsFilterFor("*.example.com"),
+						nil, "h2", "http/1.1"),
+					filterchaintls("admin.example.com", sec1,
+						httpsFilterFor("admin.example.com"),
+						nil, "h2", "http/1.1"),
+					filterchaintls("example.com", sec1,
+						httpsFilterFor("example.com"),
+						nil, "h2", "http/1.1"),
+				),
+			},
+		),
+	})
+
+	tenants := &envoy_api_v2_route.Route{
+		Match:  routePrefix("/"),
+		Action: routeCluster("default/tenants/8080/da39a3ee5e"),
+	}
+	admin := &envoy_api_v2_route.Route{
+		Match:  routePrefix("/"),
+		Action: routeCluster("default/admin/8080/da39a3ee5e"),
+	}
+
+	// The exact name takes precedence over the wildcard, so
+	// the wildcard rejects it as misdirected.
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("https/*.example.com",
+				envoy.VirtualHost("*.example.com", tenants),
+				envoy.VirtualHost("admin.example.com", &envoy_api_v2_route.Route{
+					Match:  routePrefix("/"),
+					Action: envoy.MisdirectedRequest(),
+				}),
+			),
+			envoy.RouteConfiguration("https/admin.example.com",
+				envoy.VirtualHost("admin.example.com", admin),
+			),
+			envoy.RouteConfiguration("https/example.com",
+				envoy.VirtualHost("example.com", tenants),
+			),
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("*.example.com", upgradeHTTPS(routePrefix("/"))),
+				envoy.VirtualHost("admin.example.com", upgradeHTTPS(routePrefix("/"))),
+				envoy.VirtualHost("example.com", upgradeHTTPS(routePrefix("/"))),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// The certificate must be valid for every name.
+	p3 := update(rh, p1, func(p *projcontour.HTTPProxy) {
+		p.Spec.VirtualHost.Aliases = []string{"example.com", "example.org"}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("https/admin.example.com",
+				envoy.VirtualHost("admin.example.com", admin),
+			),
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("admin.example.com", upgradeHTTPS(routePrefix("/"))),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p3).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `Spec.VirtualHost.TLS Secret "wildcard" is invalid: certificate is not valid for "example.org"`,
+	})
+
+	// An alias may not be claimed by another HTTPProxy.
+	p4 := update(rh, p3, func(p *projcontour.HTTPProxy) {
+		p.Spec.VirtualHost.Aliases = []string{"admin.example.com"}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p4).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `fqdn "admin.example.com" is used in multiple HTTPProxies: default/admin, default/tenants`,
+	})
+
+	// Without aliases or wildcards the certificate names
+	// are not checked.
+	p5 := update(rh, p2, func(p *projcontour.HTTPProxy) {
+		p.Spec.VirtualHost.TLS.SecretName = "other"
+	})
+	rh.OnDelete(p4)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("https/admin.example.com",
+				envoy.VirtualHost("admin.example.com", admin),
+			),
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("admin.example.com", upgradeHTTPS(routePrefix("/"))),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p5).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+}
@@ -0,0 +1,354 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/htpasswd"
+	"github.com/projectcontour/contour/internal/k8s"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestBasicAuthPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	s1 := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/htpasswd"),
+		Type:       v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			dag.BasicAuthKey: []byte("jane:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"),
+		},
+	}
+	rh.OnAdd(s1)
+
+	// The virtual host policy applies to the routes that
+	// aren't skipped. The Lua filter that authenticates their
+	// requests holds the password hashes, and the routes name
+	// the Secret in their metadata.
+	p1 := fixture.NewProxy("kuard").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.projectcontour.io",
+				BasicAuth: &projcontour.BasicAuthPolicy{
+					SecretName:   "htpasswd",
+					Realm:        "kuard",
+					SkipPrefixes: []string{"/public"},
+				},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}, {
+				Conditions: conditions(prefixCondition("/public/assets")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		})
+	rh.OnAdd(p1)
+
+	rh.OnAdd(fixture.NewProxy("open").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "open.projectcontour.io",
+			},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		}),
+	)
+
+	listener := func(credentials htpasswd.Credentials) *v2.DiscoveryResponse {
+		return &v2.DiscoveryResponse{
+			Resources: resources(t,
+				&v2.Listener{
+					Name:    "ingress_http",
+					Address: envoy.SocketAddress("0.0.0.0", 8080),
+					FilterChains: envoy.FilterChains(
+						envoy.HTTPConnectionManagerBuilder().
+							DefaultFilters().
+							AddFilter(basicAuthFilter("default", "htpasswd", credentials)).
+							RouteConfigName("ingress_http").
+							MetricsPrefix("ingress_http").
+							AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+							Get(),
+					),
+				},
+			),
+			TypeUrl: listenerType,
+		}
+	}
+
+	c.Request(listenerType, "ingress_http").Equals(listener(htpasswd.Credentials{
+		"jane": "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+	}))
+
+	basicAuthRoutes := routeResources(t,
+		envoy.RouteConfiguration("ingress_http",
+			envoy.VirtualHost("kuard.projectcontour.io",
+				&envoy_api_v2_route.Route{
+					Match:  routePrefix("/public/assets"),
+					Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+				},
+				&envoy_api_v2_route.Route{
+					Match:    routePrefix("/"),
+					Action:   routeCluster("default/kuard/8080/da39a3ee5e"),
+					Metadata: basicAuthMetadata("default/htpasswd", "kuard"),
+				},
+			),
+			envoy.VirtualHost("open.projectcontour.io",
+				&envoy_api_v2_route.Route{
+					Match:  routePrefix("/"),
+					Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+				},
+			),
+		),
+	)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: basicAuthRoutes,
+		TypeUrl:   routeType,
+	}).Status(p1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// The password hashes are sent with the listener, so changes
+	// to the Secret update the listener but leave the routes
+	// unchanged.
+	s2 := s1.DeepCopy()
+	s2.Data[dag.BasicAuthKey] = []byte("john:$apr1$r31Mp9dl$NNB/l3RR5fgj8Mq5uKmjW.\n")
+	rh.OnUpdate(s1, s2)
+
+	c.Request(listenerType, "ingress_http").Equals(listener(htpasswd.Credentials{
+		"john": "$apr1$r31Mp9dl$NNB/l3RR5fgj8Mq5uKmjW.",
+	}))
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: basicAuthRoutes,
+		TypeUrl:   routeType,
+	}).Status(p1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Envoy can't verify bcrypt password hashes, so they are
+	// rejected.
+	s3 := s2.DeepCopy()
+	s3.Data[dag.BasicAuthKey] = []byte("john:$2y$04$CTS3s13RiO/88eq4v/Hes.OROZXNqowgO50CMByC6ot7cbqKs1BJW\n")
+	rh.OnUpdate(s2, s3)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("open.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p1).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `Spec.VirtualHost.BasicAuth: invalid htpasswd Secret "default/htpasswd": user "john": bcrypt password hashes are not supported`,
+	})
+}
+
+func TestBasicAuthPolicyFallbackCertificate(t *testing.T) {
+	rh, c, done := setupWithFallbackCert(t, "fallbacksecret", "admin")
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/secret"),
+		Type:       "kubernetes.io/tls",
+		Data:       secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	fallbackSecret := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("admin/fallbacksecret"),
+		Type:       "kubernetes.io/tls",
+		Data:       secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(fallbackSecret)
+
+	rh.OnAdd(&projcontour.TLSCertificateDelegation{
+		ObjectMeta: fixture.ObjectMeta("admin/fallbackcertdelegation"),
+		Spec: projcontour.TLSCertificateDelegationSpec{
+			Delegations: []projcontour.CertificateDelegation{{
+				SecretName:       "fallbacksecret",
+				TargetNamespaces: []string{"*"},
+			}},
+		},
+	})
+
+	rh.OnAdd(&v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/htpasswd"),
+		Type:       v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			dag.BasicAuthKey: []byte("jane:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"),
+		},
+	})
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	proxy := func(name, fqdn string, ba *projcontour.BasicAuthPolicy) *projcontour.HTTPProxy {
+		return fixture.NewProxy(name).WithSpec(
+			projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: fqdn,
+					TLS: &projcontour.TLS{
+						SecretName:                "secret",
+						EnableFallbackCertificate: true,
+					},
+					BasicAuth: ba,
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{{
+						Name: "kuard",
+						Port: 8080,
+					}},
+				}},
+			})
+	}
+
+	rh.OnAdd(proxy("open", "open.example.com", nil))
+	rh.OnAdd(proxy("restricted", "restricted.example.com", &projcontour.BasicAuthPolicy{
+		SecretName: "htpasswd",
+	}))
+
+	// Requests without SNI are served by the fallback certificate
+	// filter chain, which must authenticate requests to the routes
+	// of every virtual host that shares it.
+	filter := basicAuthFilter("default", "htpasswd", htpasswd.Credentials{
+		"jane": "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+	})
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		TypeUrl: listenerType,
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: appendFilterChains(
+					filterchaintls("open.example.com", sec1,
+						httpsFilterFor("open.example.com"),
+						nil, "h2", "http/1.1"),
+					filterchaintls("restricted.example.com", sec1,
+						envoy.HTTPConnectionManagerBuilder().
+							AddFilter(envoy.FilterMisdirectedRequests("restricted.example.com")).
+							DefaultFilters().
+							AddFilter(filter).
+							RouteConfigName("https/restricted.example.com").
+							MetricsPrefix("ingress_https").
+							AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+							Get(),
+						nil, "h2", "http/1.1"),
+					filterchaintlsfallbackWith(fallbackSecret,
+						fallbackFilter(filter),
+						nil, "h2", "http/1.1"),
+				),
+			},
+		),
+	})
+
+	c.Request(routeType, contour.ENVOY_FALLBACK_ROUTECONFIG).Equals(&v2.DiscoveryResponse{
+		TypeUrl: routeType,
+		Resources: routeResources(t,
+			envoy.RouteConfiguration(contour.ENVOY_FALLBACK_ROUTECONFIG,
+				envoy.VirtualHost("open.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+					},
+				),
+				envoy.VirtualHost("restricted.example.com",
+					&envoy_api_v2_route.Route{
+						Match:    routePrefix("/"),
+						Action:   routeCluster("default/kuard/8080/da39a3ee5e"),
+						Metadata: basicAuthMetadata("default/htpasswd", "Restricted"),
+					},
+				),
+			),
+		),
+	})
+}
+
+// basicAuthFilter returns the filter that authenticates requests
+// with the supplied credentials of the named htpasswd Secret.
+func basicAuthFilter(namespace, name string, credentials htpasswd.Credentials) *http.HttpFilter {
+	return envoy.FilterBasicAuth(&dag.BasicAuthPolicy{
+		Secret:      k8s.FullName{Namespace: namespace, Name: name},
+		Credentials: credentials,
+	})
+}
+
+// basicAuthMetadata returns the metadata of a route that
+// authenticates requests with the named htpasswd Secret.
+func basicAuthMetadata(secret, realm string) *envoy_api_v2_core.Metadata {
+	return &envoy_api_v2_core.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			envoy.LuaFilterName: {
+				Fields: map[string]*_struct.Value{
+					"basic_auth": structValue(map[string]*_struct.Value{
+						"secret": stringValue(secret),
+						"realm":  stringValue(realm),
+					}),
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestCanaryPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	for _, name := range []string{"default/stable", "default/canary"} {
+		rh.OnAdd(&v1.Service{
+			ObjectMeta: fixture.ObjectMeta(name),
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol:   "TCP",
+					Port:       8080,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		})
+	}
+
+	p1 := fixture.NewProxy("app").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "app.example.com",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "stable",
+					Port: 8080,
+				}},
+				CanaryPolicy: &projcontour.CanaryPolicy{
+					Percentage:   20,
+					CookieMaxAge: "1h",
+					Services: []projcontour.Service{{
+						Name: "canary",
+						Port: 8080,
+					}},
+				},
+			}},
+		})
+	rh.OnAdd(p1)
+
+	assigned := func(value string) []dag.HeaderCondition {
+		return []dag.HeaderCondition{{
+			Name:      "Cookie",
+			Value:     `.*(^|;\s*)contour-canary=` + value + `(;|$).*`,
+			MatchType: "regex",
+		}, {
+			Name:      "X-Canary",
+			Value:     "always",
+			MatchType: "exact",
+			Invert:    true,
+		}, {
+			Name:      "X-Canary",
+			Value:     "never",
+			MatchType: "exact",
+			Invert:    true,
+		}}
+	}
+
+	override := func(value string) dag.HeaderCondition {
+		return dag.HeaderCondition{Name: "X-Canary", Value: value, MatchType: "exact"}
+	}
+
+	assign := func(cluster string, weight uint32, value string) *envoy_api_v2_route.WeightedCluster_ClusterWeight {
+		return &envoy_api_v2_route.WeightedCluster_ClusterWeight{
+			Name:   cluster,
+			Weight: protobuf.UInt32(weight),
+			ResponseHeadersToAdd: envoy.HeaderValueList(map[string]string{
+				"Set-Cookie": "contour-canary=" + value + "; Path=/; Max-Age=3600",
+			}, true),
+		}
+	}
+
+	// Overrides and assignment cookies route to the services they
+	// ask for, and other requests are assigned by percentage.
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("app.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/", assigned("canary")...),
+						Action: routeCluster("default/canary/8080/da39a3ee5e"),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/", assigned("stable")...),
+						Action: routeCluster("default/stable/8080/da39a3ee5e"),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/", override("always")),
+						Action: routeCluster("default/canary/8080/da39a3ee5e"),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/", override("never")),
+						Action: routeCluster("default/stable/8080/da39a3ee5e"),
+					},
+					&envoy_api_v2_route.Route{
+						Match: routePrefix("/"),
+						Action: &envoy_api_v2_route.Route_Route{
+							Route: &envoy_api_v2_route.RouteAction{
+								ClusterSpecifier: &envoy_api_v2_route.RouteAction_WeightedClusters{
+									WeightedClusters: &envoy_api_v2_route.WeightedCluster{
+										Clusters: []*envoy_api_v2_route.WeightedCluster_ClusterWeight{
+											assign("default/canary/8080/da39a3ee5e", 2000, "canary"),
+											assign("default/stable/8080/da39a3ee5e", 8000, "stable"),
+										},
+										TotalWeight: protobuf.UInt32(10000),
+									},
+								},
+							},
+						},
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Canary services may not be mirrors.
+	p2 := update(rh, p1, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].CanaryPolicy.Services[0].Mirror = true
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p2).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `route.canaryPolicy: service "canary" cannot be a mirror`,
+	})
+
+	// The header and cookie names must be valid.
+	p3 := update(rh, p2, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].CanaryPolicy.Services[0].Mirror = false
+		p.Spec.Routes[0].CanaryPolicy.CookieName = "canary=1"
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p3).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `route.canaryPolicy: invalid cookie name "canary=1"`,
+	})
+}
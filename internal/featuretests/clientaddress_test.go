@@ -0,0 +1,234 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_config_filter_http_rbac_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rbac/v2"
+	envoy_config_rbac_v2 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+	"github.com/golang/protobuf/ptypes/any"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestClientAddress(t *testing.T) {
+	clientAddress := &dag.ClientAddressPolicy{
+		NumTrustedHops: 1,
+		TrustedCIDRs: []net.IPNet{{
+			IP:   net.IP{10, 0, 0, 0},
+			Mask: net.CIDRMask(8, 32),
+		}},
+	}
+
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.ClientAddress = clientAddress
+		eh.CacheHandler.ListenerVisitorConfig.ClientAddress = clientAddress
+	})
+	defer done()
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	p1 := fixture.NewProxy("kuard").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.projectcontour.io",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+				IPDenyFilterPolicy: []projcontour.IPFilterPolicy{{
+					Source: projcontour.IPFilterSourceRemote,
+					CIDR:   "192.168.1.10",
+				}},
+			}},
+		})
+	rh.OnAdd(p1)
+
+	connectionManager := func(hops uint32) *envoy_api_v2_listener.Filter {
+		return envoy.HTTPConnectionManagerBuilder().
+			DefaultFilters().
+			AddFilter(envoy.FilterRBAC()).
+			RouteConfigName("ingress_http").
+			MetricsPrefix("ingress_http").
+			AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+			NumTrustedHops(hops).
+			Get()
+	}
+
+	// Connections from trusted peers are matched by a filter
+	// chain that trusts X-Forwarded-For, and other connections
+	// are matched by a filter chain that doesn't.
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: []*envoy_api_v2_listener.FilterChain{{
+					FilterChainMatch: &envoy_api_v2_listener.FilterChainMatch{
+						SourcePrefixRanges: []*envoy_api_v2_core.CidrRange{{
+							AddressPrefix: "10.0.0.0",
+							PrefixLen:     protobuf.UInt32(8),
+						}},
+					},
+					Filters: envoy.Filters(connectionManager(1)),
+				}, {
+					Filters: envoy.Filters(connectionManager(0)),
+				}},
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	trustedPeer := &envoy_config_rbac_v2.Principal{
+		Identifier: &envoy_config_rbac_v2.Principal_OrIds{
+			OrIds: &envoy_config_rbac_v2.Principal_Set{
+				Ids: []*envoy_config_rbac_v2.Principal{{
+					Identifier: &envoy_config_rbac_v2.Principal_SourceIp{
+						SourceIp: &envoy_api_v2_core.CidrRange{
+							AddressPrefix: "10.0.0.0",
+							PrefixLen:     protobuf.UInt32(8),
+						},
+					},
+				}},
+			},
+		},
+	}
+	and := func(ids ...*envoy_config_rbac_v2.Principal) *envoy_config_rbac_v2.Principal {
+		return &envoy_config_rbac_v2.Principal{
+			Identifier: &envoy_config_rbac_v2.Principal_AndIds{
+				AndIds: &envoy_config_rbac_v2.Principal_Set{Ids: ids},
+			},
+		}
+	}
+	or := func(ids ...*envoy_config_rbac_v2.Principal) *envoy_config_rbac_v2.Principal {
+		return &envoy_config_rbac_v2.Principal{
+			Identifier: &envoy_config_rbac_v2.Principal_OrIds{
+				OrIds: &envoy_config_rbac_v2.Principal_Set{Ids: ids},
+			},
+		}
+	}
+	not := func(id *envoy_config_rbac_v2.Principal) *envoy_config_rbac_v2.Principal {
+		return &envoy_config_rbac_v2.Principal{
+			Identifier: &envoy_config_rbac_v2.Principal_NotId{NotId: id},
+		}
+	}
+	xff := func(regex string) *envoy_config_rbac_v2.Principal {
+		return &envoy_config_rbac_v2.Principal{
+			Identifier: &envoy_config_rbac_v2.Principal_Header{
+				Header: &envoy_api_v2_route.HeaderMatcher{
+					Name: "x-forwarded-for",
+					HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+						SafeRegexMatch: envoy.SafeRegexMatch(regex),
+					},
+				},
+			},
+		}
+	}
+	peer := &envoy_config_rbac_v2.Principal{
+		Identifier: &envoy_config_rbac_v2.Principal_SourceIp{
+			SourceIp: &envoy_api_v2_core.CidrRange{
+				AddressPrefix: "192.168.1.10",
+				PrefixLen:     protobuf.UInt32(32),
+			},
+		},
+	}
+	octet := `([0-9]|[1-9][0-9]|1[0-9][0-9]|2[0-4][0-9]|25[0-5])`
+	address := `(` + strings.Join([]string{octet, octet, octet, octet}, `\.`) + `|[0-9A-Fa-f.:]*:[0-9A-Fa-f.:]*)`
+
+	// Requests from trusted peers are matched against the
+	// client's X-Forwarded-For entry, or against the peer address
+	// if there is no address in that entry. Other requests are
+	// matched against the peer address.
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+						TypedPerFilterConfig: map[string]*any.Any{
+							envoy.RBACFilterName: protobuf.MustMarshalAny(&envoy_config_filter_http_rbac_v2.RBACPerRoute{
+								Rbac: &envoy_config_filter_http_rbac_v2.RBAC{
+									Rules: &envoy_config_rbac_v2.RBAC{
+										Action: envoy_config_rbac_v2.RBAC_DENY,
+										Policies: map[string]*envoy_config_rbac_v2.Policy{
+											"ip-filter": {
+												Permissions: []*envoy_config_rbac_v2.Permission{{
+													Rule: &envoy_config_rbac_v2.Permission_Any{Any: true},
+												}},
+												Principals: []*envoy_config_rbac_v2.Principal{
+													or(
+														and(trustedPeer, or(
+															xff(`(.*,)?\s*192\.168\.1\.10\s*(,[^,]*){1}`),
+															and(not(xff(`(.*,)?\s*`+address+`\s*(,[^,]*){1}`)), peer),
+														)),
+														and(not(trustedPeer), peer),
+													),
+												},
+											},
+										},
+									},
+								},
+							}),
+						},
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Envoy cannot match IPv6 ranges against X-Forwarded-For.
+	p2 := update(rh, p1, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].IPDenyFilterPolicy[0].CIDR = "2001:db8::/32"
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p2).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `route: IPv6 CIDR "2001:db8::/32" cannot match the Remote address when X-Forwarded-For is trusted`,
+	})
+}
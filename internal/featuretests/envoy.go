@@ -24,7 +24,9 @@ import (
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
 	envoy_config_v2_tcpproxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/proto"
 	"github.com/projectcontour/contour/internal/contour"
@@ -156,6 +158,14 @@ func withPrefixRewrite(route *envoy_api_v2_route.Route_Route, replacement string
 	return route
 }
 
+func withRegexRewrite(route *envoy_api_v2_route.Route_Route, pattern, substitution string) *envoy_api_v2_route.Route_Route {
+	route.Route.RegexRewrite = &matcher.RegexMatchAndSubstitute{
+		Pattern:      envoy.SafeRegexMatch(pattern),
+		Substitution: substitution,
+	}
+	return route
+}
+
 func withRetryPolicy(route *envoy_api_v2_route.Route_Route, retryOn string, numRetries uint32, perTryTimeout time.Duration) *envoy_api_v2_route.Route_Route {
 	route.Route.RetryPolicy = &envoy_api_v2_route.RetryPolicy{
 		RetryOn: retryOn,
@@ -240,23 +250,37 @@ func filterchaintls(domain string, secret *v1.Secret, filter *envoy_api_v2_liste
 
 // filterchaintlsfallback returns a FilterChain for the given TLS fallback certificate.
 func filterchaintlsfallback(fallbackSecret *v1.Secret, peerValidationContext *dag.PeerValidationContext, alpn ...string) *envoy_api_v2_listener.FilterChain {
+	return filterchaintlsfallbackWith(fallbackSecret, fallbackFilter(), peerValidationContext, alpn...)
+}
+
+// filterchaintlsfallbackWith returns a FilterChain for the given TLS fallback
+// certificate and HTTP connection manager filter.
+func filterchaintlsfallbackWith(fallbackSecret *v1.Secret, filter *envoy_api_v2_listener.Filter, peerValidationContext *dag.PeerValidationContext, alpn ...string) *envoy_api_v2_listener.FilterChain {
 	return envoy.FilterChainTLSFallback(
 		envoy.DownstreamTLSContext(
 			&dag.Secret{Object: fallbackSecret},
 			envoy_api_v2_auth.TlsParameters_TLSv1_1,
 			peerValidationContext,
 			alpn...),
-		envoy.Filters(
-			envoy.HTTPConnectionManagerBuilder().
-				RouteConfigName(contour.ENVOY_FALLBACK_ROUTECONFIG).
-				MetricsPrefix(contour.ENVOY_HTTPS_LISTENER).
-				AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
-				RequestTimeout(0).
-				Get(),
-		),
+		envoy.Filters(filter),
 	)
 }
 
+// fallbackFilter returns the HTTP connection manager filter of the fallback
+// certificate FilterChain, with the supplied HTTP filters.
+func fallbackFilter(filters ...*http.HttpFilter) *envoy_api_v2_listener.Filter {
+	cm := envoy.HTTPConnectionManagerBuilder().
+		DefaultFilters().
+		RouteConfigName(contour.ENVOY_FALLBACK_ROUTECONFIG).
+		MetricsPrefix(contour.ENVOY_HTTPS_LISTENER).
+		AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+		RequestTimeout(0)
+	for _, filter := range filters {
+		cm.AddFilter(filter)
+	}
+	return cm.Get()
+}
+
 func httpsFilterFor(vhost string) *envoy_api_v2_listener.Filter {
 	return envoy.HTTPConnectionManagerBuilder().
 		AddFilter(envoy.FilterMisdirectedRequests(vhost)).
@@ -0,0 +1,178 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_config_filter_fault_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/fault/v2"
+	envoy_config_filter_http_fault_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/fault/v2"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/any"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestFaultInjectionPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	// Without faults the connection manager has no fault filter.
+	p1 := fixture.NewProxy("kuard").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.projectcontour.io",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		})
+	rh.OnAdd(p1)
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy.FilterChains(
+					envoy.HTTPConnectionManager("ingress_http", envoy.FileAccessLogEnvoy("/dev/stdout"), 0),
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// A route's faults are configured on the route, and the
+	// fault filter is added ahead of the router filter.
+	p2 := update(rh, p1, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].FaultInjectionPolicy = &projcontour.FaultInjectionPolicy{
+			Delay: &projcontour.FaultDelay{
+				Duration:   "2s",
+				Percentage: "12.5",
+			},
+			Abort: &projcontour.FaultAbort{
+				GRPCStatus: 14,
+			},
+			Headers: []projcontour.HeaderCondition{{
+				Name:  "X-Chaos",
+				Exact: "on",
+			}},
+		}
+	})
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy.FilterChains(
+					envoy.HTTPConnectionManagerBuilder().
+						DefaultFilters().
+						AddFilter(envoy.FilterFault()).
+						RouteConfigName("ingress_http").
+						MetricsPrefix("ingress_http").
+						AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+						Get(),
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+						TypedPerFilterConfig: map[string]*any.Any{
+							wellknown.Fault: protobuf.MustMarshalAny(&envoy_config_filter_http_fault_v2.HTTPFault{
+								Delay: &envoy_config_filter_fault_v2.FaultDelay{
+									FaultDelaySecifier: &envoy_config_filter_fault_v2.FaultDelay_FixedDelay{
+										FixedDelay: protobuf.Duration(2 * time.Second),
+									},
+									Percentage: &envoy_type.FractionalPercent{
+										Numerator:   125000,
+										Denominator: envoy_type.FractionalPercent_MILLION,
+									},
+								},
+								Abort: &envoy_config_filter_http_fault_v2.FaultAbort{
+									ErrorType: &envoy_config_filter_http_fault_v2.FaultAbort_HttpStatus{
+										HttpStatus: 503,
+									},
+									Percentage: &envoy_type.FractionalPercent{
+										Numerator:   1000000,
+										Denominator: envoy_type.FractionalPercent_MILLION,
+									},
+								},
+								Headers: envoy.RouteMatch(&dag.Route{
+									HeaderConditions: []dag.HeaderCondition{{
+										Name:      "X-Chaos",
+										Value:     "on",
+										MatchType: "exact",
+									}},
+								}).Headers,
+							}),
+						},
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p2).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// gRPC statuses Envoy cannot derive from an HTTP
+	// status are rejected.
+	p3 := update(rh, p2, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].FaultInjectionPolicy.Abort.GRPCStatus = 4
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p3).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "route.faultInjectionPolicy: abort: unsupported grpcStatus 4",
+	})
+}
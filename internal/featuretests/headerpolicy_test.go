@@ -236,3 +236,89 @@ func TestHeaderPolicy_ReplaceHeader_HTTProxy(t *testing.T) {
 		TypeUrl: clusterType,
 	})
 }
+
+func TestHeaderPolicy_DynamicHeaderValues_HTTProxy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "svc1",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	rh.OnAdd(fixture.NewProxy("simple").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{Fqdn: "hello.world"},
+			Routes: []projcontour.Route{{
+				Services: []projcontour.Service{{
+					Name: "svc1",
+					Port: 80,
+				}},
+				RequestHeadersPolicy: &projcontour.HeadersPolicy{
+					Set: []projcontour.HeaderValue{{
+						Name:  "X-Client-IP",
+						Value: "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+					}},
+				},
+				ResponseHeadersPolicy: &projcontour.HeadersPolicy{
+					Set: []projcontour.HeaderValue{{
+						Name:  "X-Served-By",
+						Value: "%HOSTNAME%",
+					}, {
+						Name:  "X-Literal",
+						Value: "%NOT_A_VARIABLE%",
+					}},
+				},
+			}},
+		}),
+	)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("hello.world",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/svc1/80/da39a3ee5e"),
+						RequestHeadersToAdd: []*envoy_api_v2_core.HeaderValueOption{{
+							Header: &envoy_api_v2_core.HeaderValue{
+								Key:   "X-Client-Ip",
+								Value: "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+							},
+							Append: &wrappers.BoolValue{
+								Value: false,
+							},
+						}},
+						ResponseHeadersToAdd: []*envoy_api_v2_core.HeaderValueOption{{
+							Header: &envoy_api_v2_core.HeaderValue{
+								Key:   "X-Literal",
+								Value: "%%NOT_A_VARIABLE%%",
+							},
+							Append: &wrappers.BoolValue{
+								Value: false,
+							},
+						}, {
+							Header: &envoy_api_v2_core.HeaderValue{
+								Key:   "X-Served-By",
+								Value: "%HOSTNAME%",
+							},
+							Append: &wrappers.BoolValue{
+								Value: false,
+							},
+						}},
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
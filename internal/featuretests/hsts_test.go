@@ -0,0 +1,203 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestHSTS(t *testing.T) {
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.SecurityHeaders = &dag.SecurityHeadersPolicy{
+			HSTS: &dag.HSTSPolicy{
+				MaxAge: time.Hour,
+			},
+			Set: map[string]string{
+				"X-Frame-Options": "DENY",
+			},
+		}
+	})
+	defer done()
+
+	rh.OnAdd(&v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/secret"),
+		Type:       "kubernetes.io/tls",
+		Data:       secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	})
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	headers := func(kv ...string) []*envoy_api_v2_core.HeaderValueOption {
+		hvm := map[string]string{}
+		for i := 0; i < len(kv); i += 2 {
+			hvm[kv[i]] = kv[i+1]
+		}
+		return envoy.HeaderValueList(hvm, false)
+	}
+
+	// The global security headers apply to the secure
+	// vhost, but not to the insecure redirect.
+	p1 := fixture.NewProxy("kuard").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.projectcontour.io",
+				TLS: &projcontour.TLS{
+					SecretName: "secret",
+				},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		})
+	rh.OnAdd(p1)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					upgradeHTTPS(routePrefix("/")),
+				),
+			),
+			envoy.RouteConfiguration("https/kuard.projectcontour.io",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+						ResponseHeadersToAdd: headers(
+							"Strict-Transport-Security", "max-age=3600",
+							"X-Frame-Options", "DENY",
+						),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// The vhost's HSTS replaces the global HSTS, and the
+	// route's response headers take precedence.
+	p2 := update(rh, p1, func(p *projcontour.HTTPProxy) {
+		p.Spec.VirtualHost.TLS.HSTS = &projcontour.HSTSPolicy{
+			MaxAge:            "8760h",
+			IncludeSubdomains: true,
+			Preload:           true,
+		}
+		p.Spec.Routes[0].ResponseHeadersPolicy = &projcontour.HeadersPolicy{
+			Set: []projcontour.HeaderValue{{
+				Name:  "X-Frame-Options",
+				Value: "SAMEORIGIN",
+			}},
+		}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					upgradeHTTPS(routePrefix("/")),
+				),
+			),
+			envoy.RouteConfiguration("https/kuard.projectcontour.io",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+						ResponseHeadersToAdd: headers(
+							"Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload",
+							"X-Frame-Options", "SAMEORIGIN",
+						),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p2).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Security headers removed by the route are not added.
+	p3 := update(rh, p2, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].ResponseHeadersPolicy = &projcontour.HeadersPolicy{
+			Remove: []string{"x-frame-options"},
+		}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					upgradeHTTPS(routePrefix("/")),
+				),
+			),
+			envoy.RouteConfiguration("https/kuard.projectcontour.io",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+						ResponseHeadersToAdd: headers(
+							"Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload",
+						),
+						ResponseHeadersToRemove: []string{"X-Frame-Options"},
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p3).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Preload without includeSubdomains invalidates the proxy.
+	p4 := update(rh, p3, func(p *projcontour.HTTPProxy) {
+		p.Spec.VirtualHost.TLS.HSTS.IncludeSubdomains = false
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p4).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "Spec.VirtualHost.TLS.HSTS: preload requires includeSubdomains",
+	})
+}
@@ -0,0 +1,245 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestIncludeWeights(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	for _, name := range []string{"default/stable", "default/canary", "default/metrics"} {
+		rh.OnAdd(&v1.Service{
+			ObjectMeta: fixture.ObjectMeta(name),
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol:   "TCP",
+					Port:       8080,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		})
+	}
+
+	stable := fixture.NewProxy("stable").WithSpec(
+		projcontour.HTTPProxySpec{
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "stable",
+					Port: 8080,
+				}},
+			}, {
+				Conditions: conditions(prefixCondition("/admin")),
+				Services: []projcontour.Service{{
+					Name: "stable",
+					Port: 8080,
+				}},
+			}},
+		})
+	rh.OnAdd(stable)
+
+	canary := fixture.NewProxy("canary").WithSpec(
+		projcontour.HTTPProxySpec{
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name:   "canary",
+					Port:   8080,
+					Weight: 3,
+				}, {
+					Name:   "metrics",
+					Port:   8080,
+					Weight: 1,
+				}},
+			}},
+		})
+	rh.OnAdd(canary)
+
+	// Routes with identical conditions split traffic by the
+	// weights of their includes, and the weights of each
+	// route's services divide its include's share.
+	root := fixture.NewProxy("root").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Includes: []projcontour.Include{{
+				Name:   "stable",
+				Weight: 90,
+			}, {
+				Name:   "canary",
+				Weight: 10,
+			}},
+		})
+	rh.OnAdd(root)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("example.com",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/admin"),
+						Action: routeCluster("default/stable/8080/da39a3ee5e"),
+					},
+					&envoy_api_v2_route.Route{
+						Match: routePrefix("/"),
+						Action: routeWeightedCluster(
+							weightedCluster{"default/canary/8080/da39a3ee5e", 750},
+							weightedCluster{"default/metrics/8080/da39a3ee5e", 250},
+							weightedCluster{"default/stable/8080/da39a3ee5e", 9000},
+						),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(root).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Without weights, identical include conditions are invalid.
+	root2 := update(rh, root, func(p *projcontour.HTTPProxy) {
+		p.Spec.Includes = []projcontour.Include{{
+			Name:       "stable",
+			Conditions: conditions(prefixCondition("/app")),
+			Weight:     90,
+		}, {
+			Name:       "canary",
+			Conditions: conditions(prefixCondition("/app")),
+		}}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(root2).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "duplicate conditions defined on an include",
+	})
+
+	// Weights above 100 are invalid.
+	root3 := update(rh, root2, func(p *projcontour.HTTPProxy) {
+		p.Spec.Includes = []projcontour.Include{{
+			Name:   "stable",
+			Weight: 101,
+		}, {
+			Name:   "canary",
+			Weight: 10,
+		}}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(root3).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "include default/stable: weight must be between 0 and 100",
+	})
+
+	// Routes with identical conditions that would be merged
+	// must not have different policies.
+	update(rh, stable, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].TimeoutPolicy = &projcontour.TimeoutPolicy{
+			Response: "1s",
+		}
+	})
+	update(rh, canary, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].TimeoutPolicy = &projcontour.TimeoutPolicy{
+			Response: "5s",
+		}
+	})
+
+	root4 := update(rh, root3, func(p *projcontour.HTTPProxy) {
+		p.Spec.Includes = []projcontour.Include{{
+			Name:   "stable",
+			Weight: 90,
+		}, {
+			Name:   "canary",
+			Weight: 10,
+		}}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(root4).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `weighted includes define different policies for routes with conditions "prefix: /"`,
+	})
+
+	// Merged weighted routes must not share their conditions with
+	// the routes of unweighted includes or of the including proxy.
+	root5 := update(rh, root4, func(p *projcontour.HTTPProxy) {
+		p.Spec.Includes = []projcontour.Include{{
+			Name:   "stable",
+			Weight: 100,
+		}}
+		p.Spec.Routes = []projcontour.Route{{
+			Conditions: conditions(prefixCondition("/")),
+			Services: []projcontour.Service{{
+				Name: "metrics",
+				Port: 8080,
+			}},
+		}}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(root5).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `weighted includes define routes with conditions "prefix: /" that another route also defines`,
+	})
+
+	root6 := update(rh, root5, func(p *projcontour.HTTPProxy) {
+		p.Spec.Includes = []projcontour.Include{{
+			Name:   "stable",
+			Weight: 100,
+		}, {
+			Name:       "canary",
+			Conditions: conditions(prefixCondition("/")),
+		}}
+		p.Spec.Routes = nil
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(root6).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `weighted includes define routes with conditions "prefix: /" that another route also defines`,
+	})
+}
@@ -0,0 +1,350 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_config_filter_http_rbac_v2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rbac/v2"
+	envoy_config_rbac_v2 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+	"github.com/golang/protobuf/ptypes/any"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestIPFilterPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	sourceIP := func(prefix string, length uint32) *envoy_config_rbac_v2.Principal {
+		return &envoy_config_rbac_v2.Principal{
+			Identifier: &envoy_config_rbac_v2.Principal_SourceIp{
+				SourceIp: &envoy_api_v2_core.CidrRange{
+					AddressPrefix: prefix,
+					PrefixLen:     protobuf.UInt32(length),
+				},
+			},
+		}
+	}
+
+	rbacPrincipals := func(action envoy_config_rbac_v2.RBAC_Action, principals ...*envoy_config_rbac_v2.Principal) map[string]*any.Any {
+		return map[string]*any.Any{
+			envoy.RBACFilterName: protobuf.MustMarshalAny(&envoy_config_filter_http_rbac_v2.RBACPerRoute{
+				Rbac: &envoy_config_filter_http_rbac_v2.RBAC{
+					Rules: &envoy_config_rbac_v2.RBAC{
+						Action: action,
+						Policies: map[string]*envoy_config_rbac_v2.Policy{
+							"ip-filter": {
+								Permissions: []*envoy_config_rbac_v2.Permission{{
+									Rule: &envoy_config_rbac_v2.Permission_Any{Any: true},
+								}},
+								Principals: principals,
+							},
+						},
+					},
+				},
+			}),
+		}
+	}
+
+	rbac := func(action envoy_config_rbac_v2.RBAC_Action, prefix string, length uint32) map[string]*any.Any {
+		return rbacPrincipals(action, sourceIP(prefix, length))
+	}
+
+	// The virtual host policy applies to the routes that don't
+	// set their own, and the RBAC filter is added ahead of the
+	// router filter.
+	p1 := fixture.NewProxy("kuard").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.projectcontour.io",
+				IPAllowFilterPolicy: []projcontour.IPFilterPolicy{{
+					CIDR: "10.0.0.0/8",
+				}},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}, {
+				Conditions: conditions(prefixCondition("/admin")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+				IPDenyFilterPolicy: []projcontour.IPFilterPolicy{{
+					Source: projcontour.IPFilterSourceRemote,
+					CIDR:   "192.168.1.10",
+				}},
+			}},
+		})
+	rh.OnAdd(p1)
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy.FilterChains(
+					envoy.HTTPConnectionManagerBuilder().
+						DefaultFilters().
+						AddFilter(envoy.FilterRBAC()).
+						RouteConfigName("ingress_http").
+						MetricsPrefix("ingress_http").
+						AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+						Get(),
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:                routePrefix("/admin"),
+						Action:               routeCluster("default/kuard/8080/da39a3ee5e"),
+						TypedPerFilterConfig: rbac(envoy_config_rbac_v2.RBAC_DENY, "192.168.1.10", 32),
+					},
+					&envoy_api_v2_route.Route{
+						Match:                routePrefix("/"),
+						Action:               routeCluster("default/kuard/8080/da39a3ee5e"),
+						TypedPerFilterConfig: rbac(envoy_config_rbac_v2.RBAC_ALLOW, "10.0.0.0", 8),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// A route cannot both allow and deny addresses.
+	p2 := update(rh, p1, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[1].IPAllowFilterPolicy = []projcontour.IPFilterPolicy{{
+			CIDR: "192.168.0.0/16",
+		}}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p2).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "route: cannot specify both ipAllowPolicy and ipDenyPolicy",
+	})
+
+	// A virtual host deny policy also applies to the routes
+	// that set their own policy.
+	p3 := update(rh, p2, func(p *projcontour.HTTPProxy) {
+		p.Spec.VirtualHost.IPAllowFilterPolicy = nil
+		p.Spec.VirtualHost.IPDenyFilterPolicy = []projcontour.IPFilterPolicy{{
+			CIDR: "192.168.0.0/16",
+		}}
+		p.Spec.Routes[1].IPDenyFilterPolicy = nil
+		p.Spec.Routes[1].IPAllowFilterPolicy = []projcontour.IPFilterPolicy{{
+			CIDR: "10.0.0.0/8",
+		}}
+		p.Spec.Routes = append(p.Spec.Routes, projcontour.Route{
+			Conditions: conditions(prefixCondition("/metrics")),
+			Services: []projcontour.Service{{
+				Name: "kuard",
+				Port: 8080,
+			}},
+			IPDenyFilterPolicy: []projcontour.IPFilterPolicy{{
+				CIDR: "172.16.0.0/12",
+			}},
+		})
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/metrics"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+						TypedPerFilterConfig: rbacPrincipals(envoy_config_rbac_v2.RBAC_DENY,
+							sourceIP("172.16.0.0", 12),
+							sourceIP("192.168.0.0", 16),
+						),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/admin"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+						TypedPerFilterConfig: rbacPrincipals(envoy_config_rbac_v2.RBAC_ALLOW,
+							&envoy_config_rbac_v2.Principal{
+								Identifier: &envoy_config_rbac_v2.Principal_AndIds{
+									AndIds: &envoy_config_rbac_v2.Principal_Set{
+										Ids: []*envoy_config_rbac_v2.Principal{{
+											Identifier: &envoy_config_rbac_v2.Principal_OrIds{
+												OrIds: &envoy_config_rbac_v2.Principal_Set{
+													Ids: []*envoy_config_rbac_v2.Principal{sourceIP("10.0.0.0", 8)},
+												},
+											},
+										}, {
+											Identifier: &envoy_config_rbac_v2.Principal_NotId{
+												NotId: &envoy_config_rbac_v2.Principal{
+													Identifier: &envoy_config_rbac_v2.Principal_OrIds{
+														OrIds: &envoy_config_rbac_v2.Principal_Set{
+															Ids: []*envoy_config_rbac_v2.Principal{sourceIP("192.168.0.0", 16)},
+														},
+													},
+												},
+											},
+										}},
+									},
+								},
+							},
+						),
+					},
+					&envoy_api_v2_route.Route{
+						Match:                routePrefix("/"),
+						Action:               routeCluster("default/kuard/8080/da39a3ee5e"),
+						TypedPerFilterConfig: rbac(envoy_config_rbac_v2.RBAC_DENY, "192.168.0.0", 16),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p3).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+}
+
+func TestIPFilterPolicyFallbackCertificate(t *testing.T) {
+	rh, c, done := setupWithFallbackCert(t, "fallbacksecret", "admin")
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/secret"),
+		Type:       "kubernetes.io/tls",
+		Data:       secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	fallbackSecret := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("admin/fallbacksecret"),
+		Type:       "kubernetes.io/tls",
+		Data:       secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(fallbackSecret)
+
+	rh.OnAdd(&projcontour.TLSCertificateDelegation{
+		ObjectMeta: fixture.ObjectMeta("admin/fallbackcertdelegation"),
+		Spec: projcontour.TLSCertificateDelegationSpec{
+			Delegations: []projcontour.CertificateDelegation{{
+				SecretName:       "fallbacksecret",
+				TargetNamespaces: []string{"*"},
+			}},
+		},
+	})
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	proxy := func(name, fqdn string, allow []projcontour.IPFilterPolicy) *projcontour.HTTPProxy {
+		return fixture.NewProxy(name).WithSpec(
+			projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: fqdn,
+					TLS: &projcontour.TLS{
+						SecretName:                "secret",
+						EnableFallbackCertificate: true,
+					},
+					IPAllowFilterPolicy: allow,
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{{
+						Name: "kuard",
+						Port: 8080,
+					}},
+				}},
+			})
+	}
+
+	rh.OnAdd(proxy("open", "open.example.com", nil))
+	restricted := proxy("restricted", "restricted.example.com", []projcontour.IPFilterPolicy{{
+		CIDR: "10.0.0.0/8",
+	}})
+	rh.OnAdd(restricted)
+
+	// The routes of TLS virtual hosts require HTTPS, and the RBAC
+	// filter applies to them. Requests without SNI are served by the
+	// fallback certificate filter chain, which must apply the RBAC
+	// filter for the routes of every virtual host that shares it.
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		TypeUrl: listenerType,
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: appendFilterChains(
+					filterchaintls("open.example.com", sec1,
+						httpsFilterFor("open.example.com"),
+						nil, "h2", "http/1.1"),
+					filterchaintls("restricted.example.com", sec1,
+						envoy.HTTPConnectionManagerBuilder().
+							AddFilter(envoy.FilterMisdirectedRequests("restricted.example.com")).
+							DefaultFilters().
+							AddFilter(envoy.FilterRBAC()).
+							RouteConfigName("https/restricted.example.com").
+							MetricsPrefix("ingress_https").
+							AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+							Get(),
+						nil, "h2", "http/1.1"),
+					filterchaintlsfallbackWith(fallbackSecret,
+						fallbackFilter(envoy.FilterRBAC()),
+						nil, "h2", "http/1.1"),
+				),
+			},
+		),
+	})
+}
@@ -0,0 +1,496 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	jwt "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/jwt_authn/v2alpha"
+	http "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/golang/protobuf/ptypes/empty"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestJWTVerification(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	for _, name := range []string{"default/kuard", "default/keys"} {
+		rh.OnAdd(&v1.Service{
+			ObjectMeta: fixture.ObjectMeta(name),
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol:   "TCP",
+					Port:       8080,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		})
+	}
+
+	const jwks = `{"keys":[{"kty":"oct","alg":"HS256","k":"c2VjcmV0"}]}`
+	rh.OnAdd(&v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/jwks"),
+		Type:       v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			dag.JWKSKey: []byte(jwks),
+		},
+	})
+
+	authority := &envoy_api_v2_route.HeaderMatcher{
+		Name: ":authority",
+		HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+			SafeRegexMatch: envoy.SafeRegexMatch(`(?i)jwt\.example\.com(:[0-9]+)?`),
+		},
+	}
+
+	rule := func(prefix string, requires *jwt.JwtRequirement) *jwt.RequirementRule {
+		match := routePrefix(prefix)
+		match.Headers = append(match.Headers, authority)
+		return &jwt.RequirementRule{Match: match, Requires: requires}
+	}
+
+	requireProvider := &jwt.JwtRequirement{
+		RequiresType: &jwt.JwtRequirement_ProviderName{
+			ProviderName: "default/app/auth",
+		},
+	}
+
+	filterJWTAuthn := func(provider *jwt.JwtProvider, rules ...*jwt.RequirementRule) *http.HttpFilter {
+		return &http.HttpFilter{
+			Name: envoy.JWTAuthnFilterName,
+			ConfigType: &http.HttpFilter_TypedConfig{
+				TypedConfig: protobuf.MustMarshalAny(&jwt.JwtAuthentication{
+					Providers: map[string]*jwt.JwtProvider{
+						"default/app/auth": provider,
+					},
+					Rules: rules,
+				}),
+			},
+		}
+	}
+
+	// Routes verify JWTs from the default provider unless they
+	// opt out, and the provider's claims are forwarded by the
+	// Lua filter, which runs after the JWT filter.
+	p1 := fixture.NewProxy("app").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "jwt.example.com",
+				JWTProviders: []projcontour.JWTProvider{{
+					Name:      "auth",
+					Issuer:    "https://auth.example.com",
+					Audiences: []string{"app"},
+					Default:   true,
+					LocalJWKS: &projcontour.LocalJWKS{
+						SecretName: "jwks",
+					},
+					ClaimsToHeaders: []projcontour.ClaimToHeader{{
+						Claim:  "sub",
+						Header: "x-user",
+					}},
+				}},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}, {
+				Conditions: conditions(prefixCondition("/optional")),
+				JWTVerificationPolicy: &projcontour.JWTVerificationPolicy{
+					AllowMissing: true,
+				},
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}, {
+				Conditions: conditions(prefixCondition("/public")),
+				JWTVerificationPolicy: &projcontour.JWTVerificationPolicy{
+					Disabled: true,
+				},
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		})
+	rh.OnAdd(p1)
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy.FilterChains(
+					envoy.HTTPConnectionManagerBuilder().
+						DefaultFilters().
+						AddFilter(filterJWTAuthn(
+							&jwt.JwtProvider{
+								Issuer:            "https://auth.example.com",
+								Audiences:         []string{"app"},
+								PayloadInMetadata: "default/app/auth",
+								JwksSourceSpecifier: &jwt.JwtProvider_LocalJwks{
+									LocalJwks: &envoy_api_v2_core.DataSource{
+										Specifier: &envoy_api_v2_core.DataSource_InlineString{
+											InlineString: jwks,
+										},
+									},
+								},
+							},
+							rule("/public", nil),
+							rule("/optional", &jwt.JwtRequirement{
+								RequiresType: &jwt.JwtRequirement_RequiresAny{
+									RequiresAny: &jwt.JwtRequirementOrList{
+										Requirements: []*jwt.JwtRequirement{
+											requireProvider,
+											{
+												RequiresType: &jwt.JwtRequirement_AllowMissing{
+													AllowMissing: &empty.Empty{},
+												},
+											},
+										},
+									},
+								},
+							}),
+							rule("/", requireProvider),
+						)).
+						AddFilter(envoy.FilterLua()).
+						RouteConfigName("ingress_http").
+						MetricsPrefix("ingress_http").
+						AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+						Get(),
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	claims := &envoy_api_v2_core.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			envoy.LuaFilterName: {
+				Fields: map[string]*_struct.Value{
+					"jwt_claims": structValue(map[string]*_struct.Value{
+						"payload": stringValue("default/app/auth"),
+						"headers": structValue(map[string]*_struct.Value{
+							"X-User": stringValue("sub"),
+						}),
+					}),
+				},
+			},
+		},
+	}
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("jwt.example.com",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/public"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+					},
+					&envoy_api_v2_route.Route{
+						Match:    routePrefix("/optional"),
+						Action:   routeCluster("default/kuard/8080/da39a3ee5e"),
+						Metadata: claims,
+					},
+					&envoy_api_v2_route.Route{
+						Match:    routePrefix("/"),
+						Action:   routeCluster("default/kuard/8080/da39a3ee5e"),
+						Metadata: claims,
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// A remote JWKS is fetched from the cluster of its Service.
+	p2 := update(rh, p1, func(p *projcontour.HTTPProxy) {
+		p.Spec.VirtualHost.JWTProviders[0].LocalJWKS = nil
+		p.Spec.VirtualHost.JWTProviders[0].ClaimsToHeaders = nil
+		p.Spec.VirtualHost.JWTProviders[0].ForwardJWT = true
+		p.Spec.VirtualHost.JWTProviders[0].RemoteJWKS = &projcontour.RemoteJWKS{
+			ServiceName: "keys",
+			ServicePort: 8080,
+			Path:        "/.well-known/jwks.json",
+		}
+		p.Spec.Routes = p.Spec.Routes[:1]
+	})
+
+	c.Request(listenerType, "ingress_http").Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_http",
+				Address: envoy.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy.FilterChains(
+					envoy.HTTPConnectionManagerBuilder().
+						DefaultFilters().
+						AddFilter(filterJWTAuthn(
+							&jwt.JwtProvider{
+								Issuer:    "https://auth.example.com",
+								Audiences: []string{"app"},
+								Forward:   true,
+								JwksSourceSpecifier: &jwt.JwtProvider_RemoteJwks{
+									RemoteJwks: &jwt.RemoteJwks{
+										HttpUri: &envoy_api_v2_core.HttpUri{
+											Uri: "http://keys.default:8080/.well-known/jwks.json",
+											HttpUpstreamType: &envoy_api_v2_core.HttpUri_Cluster{
+												Cluster: "default/keys/8080/da39a3ee5e",
+											},
+											Timeout: protobuf.Duration(time.Second),
+										},
+										CacheDuration: protobuf.Duration(5 * time.Minute),
+									},
+								},
+							},
+							rule("/", requireProvider),
+						)).
+						RouteConfigName("ingress_http").
+						MetricsPrefix("ingress_http").
+						AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+						Get(),
+				),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	c.Request(clusterType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			cluster("default/keys/8080/da39a3ee5e", "default/keys", "default_keys_8080"),
+			cluster("default/kuard/8080/da39a3ee5e", "default/kuard", "default_kuard_8080"),
+		),
+		TypeUrl: clusterType,
+	}).Status(p2).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Routes may only require providers of their virtual host.
+	p3 := update(rh, p2, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].JWTVerificationPolicy = &projcontour.JWTVerificationPolicy{
+			Require: "partner",
+		}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p3).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `route.jwtVerificationPolicy: JWT provider "partner" not found`,
+	})
+
+	// A JWKS Secret must hold a key set.
+	p4 := update(rh, p3, func(p *projcontour.HTTPProxy) {
+		p.Spec.Routes[0].JWTVerificationPolicy = nil
+		p.Spec.VirtualHost.JWTProviders[0].RemoteJWKS = nil
+		p.Spec.VirtualHost.JWTProviders[0].LocalJWKS = &projcontour.LocalJWKS{
+			SecretName: "missing",
+		}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p4).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `Spec.VirtualHost.JWTProviders: provider "auth": invalid JWKS Secret "default/missing": Secret not found`,
+	})
+}
+
+func TestJWTVerificationFallbackCertificate(t *testing.T) {
+	rh, c, done := setupWithFallbackCert(t, "fallbacksecret", "admin")
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/secret"),
+		Type:       "kubernetes.io/tls",
+		Data:       secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	fallbackSecret := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("admin/fallbacksecret"),
+		Type:       "kubernetes.io/tls",
+		Data:       secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(fallbackSecret)
+
+	rh.OnAdd(&projcontour.TLSCertificateDelegation{
+		ObjectMeta: fixture.ObjectMeta("admin/fallbackcertdelegation"),
+		Spec: projcontour.TLSCertificateDelegationSpec{
+			Delegations: []projcontour.CertificateDelegation{{
+				SecretName:       "fallbacksecret",
+				TargetNamespaces: []string{"*"},
+			}},
+		},
+	})
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	const jwks = `{"keys":[{"kty":"oct","alg":"HS256","k":"c2VjcmV0"}]}`
+	rh.OnAdd(&v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("default/jwks"),
+		Type:       v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			dag.JWKSKey: []byte(jwks),
+		},
+	})
+
+	proxy := func(name, fqdn string, providers []projcontour.JWTProvider) *projcontour.HTTPProxy {
+		return fixture.NewProxy(name).WithSpec(
+			projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: fqdn,
+					TLS: &projcontour.TLS{
+						SecretName:                "secret",
+						EnableFallbackCertificate: true,
+					},
+					JWTProviders: providers,
+				},
+				Routes: []projcontour.Route{{
+					Conditions: conditions(prefixCondition("/")),
+					Services: []projcontour.Service{{
+						Name: "kuard",
+						Port: 8080,
+					}},
+				}},
+			})
+	}
+
+	rh.OnAdd(proxy("open", "open.example.com", nil))
+	rh.OnAdd(proxy("app", "jwt.example.com", []projcontour.JWTProvider{{
+		Name:    "auth",
+		Issuer:  "https://auth.example.com",
+		Default: true,
+		LocalJWKS: &projcontour.LocalJWKS{
+			SecretName: "jwks",
+		},
+	}}))
+
+	match := routePrefix("/")
+	match.Headers = append(match.Headers, &envoy_api_v2_route.HeaderMatcher{
+		Name: ":authority",
+		HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_SafeRegexMatch{
+			SafeRegexMatch: envoy.SafeRegexMatch(`(?i)jwt\.example\.com(:[0-9]+)?`),
+		},
+	})
+
+	filterJWTAuthn := &http.HttpFilter{
+		Name: envoy.JWTAuthnFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&jwt.JwtAuthentication{
+				Providers: map[string]*jwt.JwtProvider{
+					"default/app/auth": {
+						Issuer: "https://auth.example.com",
+						JwksSourceSpecifier: &jwt.JwtProvider_LocalJwks{
+							LocalJwks: &envoy_api_v2_core.DataSource{
+								Specifier: &envoy_api_v2_core.DataSource_InlineString{
+									InlineString: jwks,
+								},
+							},
+						},
+					},
+				},
+				Rules: []*jwt.RequirementRule{{
+					Match: match,
+					Requires: &jwt.JwtRequirement{
+						RequiresType: &jwt.JwtRequirement_ProviderName{
+							ProviderName: "default/app/auth",
+						},
+					},
+				}},
+			}),
+		},
+	}
+
+	// The routes of TLS virtual hosts require HTTPS, and verify
+	// JWTs. Requests without SNI are served by the fallback
+	// certificate filter chain, whose JWT filter has the rules of
+	// every virtual host that shares it, matched by :authority.
+	c.Request(listenerType, "ingress_https").Equals(&v2.DiscoveryResponse{
+		TypeUrl: listenerType,
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+				FilterChains: appendFilterChains(
+					filterchaintls("jwt.example.com", sec1,
+						envoy.HTTPConnectionManagerBuilder().
+							AddFilter(envoy.FilterMisdirectedRequests("jwt.example.com")).
+							DefaultFilters().
+							AddFilter(filterJWTAuthn).
+							RouteConfigName("https/jwt.example.com").
+							MetricsPrefix("ingress_https").
+							AccessLoggers(envoy.FileAccessLogEnvoy("/dev/stdout")).
+							Get(),
+						nil, "h2", "http/1.1"),
+					filterchaintls("open.example.com", sec1,
+						httpsFilterFor("open.example.com"),
+						nil, "h2", "http/1.1"),
+					filterchaintlsfallbackWith(fallbackSecret,
+						fallbackFilter(filterJWTAuthn),
+						nil, "h2", "http/1.1"),
+				),
+			},
+		),
+	})
+}
+
+func structValue(fields map[string]*_struct.Value) *_struct.Value {
+	return &_struct.Value{
+		Kind: &_struct.Value_StructValue{
+			StructValue: &_struct.Struct{Fields: fields},
+		},
+	}
+}
+
+func stringValue(s string) *_struct.Value {
+	return &_struct.Value{
+		Kind: &_struct.Value_StringValue{StringValue: s},
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestHTTPProxyRegexRewrite(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	vhost := fixture.NewProxy("kuard").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.projectcontour.io",
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/v1")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+				PathRewritePolicy: &projcontour.PathRewritePolicy{
+					RegexRewrite: &projcontour.RegexRewrite{
+						Pattern:      "^/v1/(.*)/detail$",
+						Substitution: `/api/\1`,
+					},
+				},
+			}},
+		})
+
+	rh.OnAdd(vhost)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/v1"),
+						Action: withRegexRewrite(routeCluster("default/kuard/8080/da39a3ee5e"), "^/v1/(.*)/detail$", `/api/\1`),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(vhost).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Combining a regex rewrite with prefix replacement is not allowed.
+	vhost = update(rh, vhost,
+		func(vhost *projcontour.HTTPProxy) {
+			vhost.Spec.Routes[0].PathRewritePolicy.ReplacePrefix =
+				[]projcontour.ReplacePrefix{
+					{Replacement: "/api"},
+				}
+		})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(vhost).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "cannot specify both prefix replacements and a regex rewrite",
+	})
+
+}
@@ -20,7 +20,10 @@ import (
 	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/k8s"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -383,6 +386,136 @@ func TestTCPProxyTLSBackend(t *testing.T) {
 	})
 }
 
+// Assert that a TCPProxy service with protocol tls re-encrypts the
+// connections Envoy terminates, validating the service's certificate.
+func TestTCPProxyTLSReencryption(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	s1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "postgres-tls",
+			Namespace: "default",
+		},
+		Type: "kubernetes.io/tls",
+		Data: secretdata(CERTIFICATE, RSA_PRIVATE_KEY),
+	}
+
+	ca := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			dag.CACertificateKey: []byte(CERTIFICATE),
+		},
+	}
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "postgres",
+			Namespace: s1.Namespace,
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       5432,
+				TargetPort: intstr.FromInt(5432),
+			}},
+		},
+	}
+
+	protocol := "tls"
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "postgres",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "postgres.example.com",
+				TLS: &projcontour.TLS{
+					SecretName: s1.Name,
+				},
+			},
+			TCPProxy: &projcontour.TCPProxy{
+				Services: []projcontour.Service{{
+					Name:     svc.Name,
+					Port:     5432,
+					Protocol: &protocol,
+					UpstreamValidation: &projcontour.UpstreamValidation{
+						CACertificate: ca.Name,
+						SubjectName:   "subjname",
+					},
+				}},
+			},
+		},
+	}
+
+	rh.OnAdd(s1)
+	rh.OnAdd(ca)
+	rh.OnAdd(svc)
+	rh.OnAdd(hp1)
+
+	c.Request(listenerType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			&v2.Listener{
+				Name:    "ingress_https",
+				Address: envoy.SocketAddress("0.0.0.0", 8443),
+				FilterChains: appendFilterChains(
+					filterchaintls("postgres.example.com", s1,
+						tcpproxy("ingress_https", "default/postgres/5432/98c0f31c72"), nil),
+				),
+				ListenerFilters: envoy.ListenerFilters(
+					envoy.TLSInspector(),
+				),
+			},
+			staticListener(),
+		),
+		TypeUrl: listenerType,
+	})
+
+	c.Request(clusterType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			tlsCluster(cluster(
+				"default/postgres/5432/98c0f31c72",
+				"default/postgres",
+				"default_postgres_5432",
+			), []byte(CERTIFICATE), "subjname", ""),
+		),
+		TypeUrl: clusterType,
+	}).Status(hp1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Envoy can't re-encrypt connections it doesn't terminate.
+	hp2 := hp1.DeepCopy()
+	hp2.Spec.VirtualHost.TLS = &projcontour.TLS{Passthrough: true}
+	rh.OnUpdate(hp1, hp2)
+
+	c.Request(clusterType).Equals(&v2.DiscoveryResponse{
+		Resources: nil,
+		TypeUrl:   clusterType,
+	}).Status(hp2).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "tcpproxy: service default/postgres/5432: protocol tls cannot be combined with tls.passthrough",
+	})
+
+	// TCP proxies can't speak HTTP/2.
+	hp3 := hp1.DeepCopy()
+	h2 := "h2"
+	hp3.Spec.TCPProxy.Services[0].Protocol = &h2
+	rh.OnUpdate(hp2, hp3)
+
+	c.Request(clusterType).Equals(&v2.DiscoveryResponse{
+		Resources: nil,
+		TypeUrl:   clusterType,
+	}).Status(hp3).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "tcpproxy: service default/postgres/5432: unsupported protocol: h2",
+	})
+}
+
 // Assert that TCPProxy + a http service can be used to expose a ingress_http
 // route on the same vhost that port ingress_https is tls passthrough + proxying.
 func TestTCPProxyAndHTTPService(t *testing.T) {
@@ -925,3 +1058,88 @@ func TestTCPProxyMissingTLS(t *testing.T) {
 		TypeUrl: routeType,
 	})
 }
+
+// Assert that an HTTPProxy without a virtual host can claim a
+// plain TCP listener port.
+func TestTCPProxyListenerPort(t *testing.T) {
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.TCPPorts = []int{6379}
+	})
+	defer done()
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "redis",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       6379,
+				TargetPort: intstr.FromInt(6379),
+			}},
+		},
+	}
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "redis",
+			Namespace: svc.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			TCPProxy: &projcontour.TCPProxy{
+				Port: 6379,
+				Services: []projcontour.Service{{
+					Name: svc.Name,
+					Port: 6379,
+				}},
+			},
+		},
+	}
+
+	rh.OnAdd(svc)
+	rh.OnAdd(hp1)
+
+	c.Request(listenerType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.Listener("ingress_tcp_6379", "0.0.0.0", 6379, nil,
+				tcpproxy("ingress_tcp_6379", "default/redis/6379/da39a3ee5e"),
+			),
+			staticListener(),
+		),
+		TypeUrl: listenerType,
+	}).Status(hp1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Only one HTTPProxy may claim a port.
+	hp2 := hp1.DeepCopy()
+	hp2.Name = "cache"
+	rh.OnAdd(hp2)
+
+	c.Request(listenerType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			staticListener(),
+		),
+		TypeUrl: listenerType,
+	}).Status(hp1).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "port 6379 is claimed by multiple HTTPProxies: default/cache, default/redis",
+	})
+
+	// The port must be a configured TCP listener port.
+	rh.OnDelete(hp2)
+	hp3 := hp1.DeepCopy()
+	hp3.Spec.TCPProxy.Port = 6380
+	rh.OnUpdate(hp1, hp3)
+
+	c.Request(listenerType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			staticListener(),
+		),
+		TypeUrl: listenerType,
+	}).Status(hp3).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   "tcpproxy: port 6380 is not a TCP listener port",
+	})
+}
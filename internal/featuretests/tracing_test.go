@@ -0,0 +1,148 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuretests
+
+import (
+	"testing"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
+	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestTracingPolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rh.OnAdd(&v1.Service{
+		ObjectMeta: fixture.ObjectMeta("default/kuard"),
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       8080,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	})
+
+	sampling := func(numerator uint32) *envoy_api_v2_route.Tracing {
+		return &envoy_api_v2_route.Tracing{
+			OverallSampling: &envoy_type.FractionalPercent{
+				Numerator:   numerator,
+				Denominator: envoy_type.FractionalPercent_MILLION,
+			},
+		}
+	}
+
+	// The virtual host tracing policy applies to routes that
+	// don't set their own.
+	p1 := fixture.NewProxy("kuard").WithSpec(
+		projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{
+				Fqdn: "kuard.projectcontour.io",
+				TracingPolicy: &projcontour.TracingPolicy{
+					Sampling: "10",
+				},
+			},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}, {
+				Conditions: conditions(prefixCondition("/debug")),
+				TracingPolicy: &projcontour.TracingPolicy{
+					Sampling: "100",
+				},
+				Services: []projcontour.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		})
+	rh.OnAdd(p1)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:   routePrefix("/debug"),
+						Action:  routeCluster("default/kuard/8080/da39a3ee5e"),
+						Tracing: sampling(1000000),
+					},
+					&envoy_api_v2_route.Route{
+						Match:   routePrefix("/"),
+						Action:  routeCluster("default/kuard/8080/da39a3ee5e"),
+						Tracing: sampling(100000),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p1).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// Without a virtual host tracing policy, only the route
+	// with a tracing policy overrides the sampling.
+	p2 := update(rh, p1, func(p *projcontour.HTTPProxy) {
+		p.Spec.VirtualHost.TracingPolicy = nil
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("kuard.projectcontour.io",
+					&envoy_api_v2_route.Route{
+						Match:   routePrefix("/debug"),
+						Action:  routeCluster("default/kuard/8080/da39a3ee5e"),
+						Tracing: sampling(1000000),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/8080/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	}).Status(p2).Like(
+		projcontour.Status{CurrentStatus: k8s.StatusValid},
+	)
+
+	// An invalid sampling percentage invalidates the proxy.
+	p3 := update(rh, p2, func(p *projcontour.HTTPProxy) {
+		p.Spec.VirtualHost.TracingPolicy = &projcontour.TracingPolicy{
+			Sampling: "200",
+		}
+	})
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http"),
+		),
+		TypeUrl: routeType,
+	}).Status(p3).Equals(projcontour.Status{
+		CurrentStatus: k8s.StatusInvalid,
+		Description:   `Spec.VirtualHost.TracingPolicy: invalid tracing sampling percentage "200"`,
+	})
+}
@@ -15,10 +15,12 @@ package featuretests
 
 import (
 	"testing"
+	"time"
 
 	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	projcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
 	"github.com/projectcontour/contour/internal/envoy"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
@@ -279,3 +281,84 @@ func TestWebsocketHTTPProxy(t *testing.T) {
 	})
 
 }
+
+func TestWebsocketIdleTimeoutHTTPProxy(t *testing.T) {
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.WebsocketIdleTimeout = time.Hour
+	})
+	defer done()
+
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ws",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+		},
+	}
+	rh.OnAdd(s1)
+
+	hp1 := &projcontour.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "simple",
+			Namespace: s1.Namespace,
+		},
+		Spec: projcontour.HTTPProxySpec{
+			VirtualHost: &projcontour.VirtualHost{Fqdn: "websocket.hello.world"},
+			Routes: []projcontour.Route{{
+				Conditions: conditions(prefixCondition("/")),
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}, {
+				// The default websocket idle timeout applies.
+				Conditions:       conditions(prefixCondition("/ws-1")),
+				EnableWebsockets: true,
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}, {
+				// The route's websocket idle timeout overrides the default.
+				Conditions:       conditions(prefixCondition("/ws-2")),
+				EnableWebsockets: true,
+				TimeoutPolicy: &projcontour.TimeoutPolicy{
+					WebsocketIdle: "infinity",
+				},
+				Services: []projcontour.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		},
+	}
+	rh.OnAdd(hp1)
+
+	c.Request(routeType).Equals(&v2.DiscoveryResponse{
+		Resources: resources(t,
+			envoy.RouteConfiguration("ingress_http",
+				envoy.VirtualHost("websocket.hello.world",
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/ws-2"),
+						Action: withIdleTimeout(withWebsocket(routeCluster("default/ws/80/da39a3ee5e")), 0),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/ws-1"),
+						Action: withIdleTimeout(withWebsocket(routeCluster("default/ws/80/da39a3ee5e")), time.Hour),
+					},
+					&envoy_api_v2_route.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/ws/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
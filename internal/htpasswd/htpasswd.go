@@ -0,0 +1,91 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package htpasswd parses Apache htpasswd files.
+package htpasswd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Credentials maps user names to their password hashes.
+type Credentials map[string]string
+
+// Parse returns the credentials of the users in the supplied
+// htpasswd data. Passwords must be hashed with Apache MD5 ("$apr1$")
+// or SHA1 ("{SHA}").
+func Parse(data []byte) (Credentials, error) {
+	credentials := make(Credentials)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sep := strings.Index(line, ":")
+		if sep < 1 {
+			return nil, errors.New("malformed htpasswd entry")
+		}
+		user, hash := line[:sep], line[sep+1:]
+
+		if err := validHash(hash); err != nil {
+			return nil, fmt.Errorf("user %q: %s", user, err)
+		}
+
+		// As with Apache, the first entry for a user is used.
+		if _, ok := credentials[user]; !ok {
+			credentials[user] = hash
+		}
+	}
+
+	if len(credentials) == 0 {
+		return nil, errors.New("no credentials")
+	}
+
+	return credentials, nil
+}
+
+const (
+	apr1Magic  = "$apr1$"
+	sha1Prefix = "{SHA}"
+)
+
+// validHash returns an error if the supplied password hash is
+// malformed or uses an unsupported algorithm.
+func validHash(hash string) error {
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		return errors.New("bcrypt password hashes are not supported")
+	case strings.HasPrefix(hash, apr1Magic):
+		parts := strings.Split(strings.TrimPrefix(hash, apr1Magic), "$")
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[0]) > 8 || len(parts[1]) != 22 {
+			return errors.New("invalid MD5 password hash")
+		}
+	case strings.HasPrefix(hash, sha1Prefix):
+		digest, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(hash, sha1Prefix))
+		if err != nil || len(digest) != sha1.Size {
+			return errors.New("invalid SHA1 password hash")
+		}
+	default:
+		return errors.New("unsupported password hash")
+	}
+	return nil
+}
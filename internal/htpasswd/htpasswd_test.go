@@ -0,0 +1,72 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package htpasswd
+
+import (
+	"testing"
+
+	"github.com/projectcontour/contour/internal/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		data    string
+		want    Credentials
+		wantErr bool
+	}{
+		"supported hashes": {
+			data: "# generated by htpasswd\n" +
+				"jane:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n" +
+				"\n" +
+				"john:$apr1$r31Mp9dl$NNB/l3RR5fgj8Mq5uKmjW.\n" +
+				"jane:{SHA}qUqP5cyxm6YcTAhz05Hph5gvu9M=\n",
+			want: Credentials{
+				"jane": "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+				"john": "$apr1$r31Mp9dl$NNB/l3RR5fgj8Mq5uKmjW.",
+			},
+		},
+		"crypt": {
+			data:    "jane:rqXexS6ZhobKA\n",
+			wantErr: true,
+		},
+		"invalid sha1": {
+			data:    "jane:{SHA}cGFzc3dvcmQ=\n",
+			wantErr: true,
+		},
+		"invalid md5": {
+			data:    "jane:$apr1$r31Mp9dl\n",
+			wantErr: true,
+		},
+		"bcrypt": {
+			data:    "jane:$2y$04$CTS3s13RiO/88eq4v/Hes.OROZXNqowgO50CMByC6ot7cbqKs1BJW\n",
+			wantErr: true,
+		},
+		"missing user": {
+			data:    ":{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n",
+			wantErr: true,
+		},
+		"no credentials": {
+			data:    "# empty\n",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Parse([]byte(tc.data))
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
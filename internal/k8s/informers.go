@@ -17,6 +17,7 @@ import (
 	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	serviceapis "sigs.k8s.io/service-apis/api/v1alpha1"
@@ -25,7 +26,7 @@ import (
 // +kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses,verbs=get;list;watch
 // +kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses/status,verbs=create;get;update
 
-// +kubebuilder:rbac:groups="projectcontour.io",resources=httpproxies;tlscertificatedelegations,verbs=get;list;watch
+// +kubebuilder:rbac:groups="projectcontour.io",resources=clusterproxydefaults;externalbackends;fqdnpolicies;httpproxies;proxydefaults;tlscertificatedelegations,verbs=get;list;watch
 // +kubebuilder:rbac:groups="projectcontour.io",resources=httpproxies/status,verbs=create;get;update
 
 // DefaultResources ...
@@ -33,6 +34,10 @@ func DefaultResources() []schema.GroupVersionResource {
 	return []schema.GroupVersionResource{
 		projectcontour.HTTPProxyGVR,
 		projectcontour.TLSCertificateDelegationGVR,
+		projectcontour.ExternalBackendGVR,
+		projectcontour.ProxyDefaultsGVR,
+		projectcontour.ClusterProxyDefaultsGVR,
+		projectcontour.FQDNPolicyGVR,
 		corev1.SchemeGroupVersion.WithResource("services"),
 		v1beta1.SchemeGroupVersion.WithResource("ingresses"),
 	}
@@ -68,6 +73,31 @@ func EndpointsResources() []schema.GroupVersionResource {
 	}
 }
 
+// +kubebuilder:rbac:groups="discovery.k8s.io",resources=endpointslices,verbs=get;list;watch
+
+// EndpointSliceResources ...
+func EndpointSliceResources() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{
+		discoveryv1beta1.SchemeGroupVersion.WithResource("endpointslices"),
+	}
+}
+
+// ExternalBackendResources ...
+func ExternalBackendResources() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{
+		projectcontour.ExternalBackendGVR,
+	}
+}
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+// NodesResources ...
+func NodesResources() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{
+		corev1.SchemeGroupVersion.WithResource("nodes"),
+	}
+}
+
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
 
 // ServicesResources ...
@@ -16,6 +16,7 @@ package k8s
 import (
 	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
@@ -33,12 +34,24 @@ func KindOf(obj interface{}) string {
 		return "Service"
 	case *v1.Endpoints:
 		return "Endpoints"
+	case *v1.Node:
+		return "Node"
+	case *discoveryv1beta1.EndpointSlice:
+		return "EndpointSlice"
 	case *v1beta1.Ingress:
 		return "Ingress"
 	case *projectcontour.HTTPProxy:
 		return "HTTPProxy"
 	case *projectcontour.TLSCertificateDelegation:
 		return "TLSCertificateDelegation"
+	case *projectcontour.ExternalBackend:
+		return "ExternalBackend"
+	case *projectcontour.ProxyDefaults:
+		return "ProxyDefaults"
+	case *projectcontour.ClusterProxyDefaults:
+		return "ClusterProxyDefaults"
+	case *projectcontour.FQDNPolicy:
+		return "FQDNPolicy"
 	case *unstructured.Unstructured:
 		return obj.GetKind()
 	default:
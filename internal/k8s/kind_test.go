@@ -5,6 +5,7 @@ import (
 
 	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
@@ -17,10 +18,16 @@ func TestKindOf(t *testing.T) {
 		{"Secret", &v1.Secret{}},
 		{"Service", &v1.Service{}},
 		{"Endpoints", &v1.Endpoints{}},
+		{"Node", &v1.Node{}},
+		{"EndpointSlice", &discoveryv1beta1.EndpointSlice{}},
 		{"", &v1.Pod{}},
 		{"Ingress", &v1beta1.Ingress{}},
 		{"HTTPProxy", &projectcontour.HTTPProxy{}},
 		{"TLSCertificateDelegation", &projectcontour.TLSCertificateDelegation{}},
+		{"ExternalBackend", &projectcontour.ExternalBackend{}},
+		{"ProxyDefaults", &projectcontour.ProxyDefaults{}},
+		{"ClusterProxyDefaults", &projectcontour.ClusterProxyDefaults{}},
+		{"FQDNPolicy", &projectcontour.FQDNPolicy{}},
 		{"Foo", &unstructured.Unstructured{
 			Object: map[string]interface{}{
 				"apiVersion": "test.projectcontour.io/v1",
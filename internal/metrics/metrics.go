@@ -33,6 +33,8 @@ type Metrics struct {
 	proxyValidGauge     *prometheus.GaugeVec
 	proxyOrphanedGauge  *prometheus.GaugeVec
 
+	proxyFQDNPolicyViolationGauge *prometheus.GaugeVec
+
 	dagRebuildGauge             *prometheus.GaugeVec
 	CacheHandlerOnUpdateSummary prometheus.Summary
 	EventHandlerOperations      *prometheus.CounterVec
@@ -48,6 +50,10 @@ type RouteMetric struct {
 	Invalid  map[Meta]int
 	Orphaned map[Meta]int
 	Root     map[Meta]int
+
+	// FQDNPolicyViolation counts the root HTTPProxies that
+	// use a name an FQDNPolicy doesn't permit them to.
+	FQDNPolicyViolation map[Meta]int
 }
 
 // Meta holds the vhost and namespace of a metric object
@@ -64,6 +70,8 @@ const (
 	HTTPProxyValidGauge     = "contour_httpproxy_valid_total"
 	HTTPProxyOrphanedGauge  = "contour_httpproxy_orphaned_total"
 
+	HTTPProxyFQDNPolicyViolationGauge = "contour_httpproxy_fqdn_policy_violations"
+
 	DAGRebuildGauge             = "contour_dagrebuild_timestamp"
 	cacheHandlerOnUpdateSummary = "contour_cachehandler_onupdate_duration_seconds"
 	eventHandlerOperations      = "contour_eventhandler_operation_total"
@@ -120,6 +128,13 @@ func NewMetrics(registry *prometheus.Registry) *Metrics {
 			},
 			[]string{"namespace"},
 		),
+		proxyFQDNPolicyViolationGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: HTTPProxyFQDNPolicyViolationGauge,
+				Help: "Total number of root HTTPProxies which use an fqdn that an FQDNPolicy does not permit their namespace to.",
+			},
+			[]string{"namespace", "vhost"},
+		),
 		dagRebuildGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: DAGRebuildGauge,
@@ -154,6 +169,7 @@ func (m *Metrics) register(registry *prometheus.Registry) {
 		m.proxyInvalidGauge,
 		m.proxyValidGauge,
 		m.proxyOrphanedGauge,
+		m.proxyFQDNPolicyViolationGauge,
 		m.dagRebuildGauge,
 		m.CacheHandlerOnUpdateSummary,
 		m.EventHandlerOperations,
@@ -175,6 +191,8 @@ func (m *Metrics) Zero() {
 		Invalid:  map[Meta]int{meta: 0},
 		Orphaned: map[Meta]int{meta: 0},
 		Root:     map[Meta]int{meta: 0},
+
+		FQDNPolicyViolation: map[Meta]int{meta: 0},
 	}
 
 	m.SetDAGLastRebuilt(time.Now())
@@ -213,6 +231,10 @@ func (m *Metrics) SetHTTPProxyMetric(metrics RouteMetric) {
 		m.proxyRootTotalGauge.WithLabelValues(meta.Namespace).Set(float64(value))
 		delete(m.proxyMetricCache.Root, meta)
 	}
+	for meta, value := range metrics.FQDNPolicyViolation {
+		m.proxyFQDNPolicyViolationGauge.WithLabelValues(meta.Namespace, meta.VHost).Set(float64(value))
+		delete(m.proxyMetricCache.FQDNPolicyViolation, meta)
+	}
 
 	// All metrics processed, now remove what's left as they are not needed
 	for meta := range m.proxyMetricCache.Total {
@@ -230,6 +252,9 @@ func (m *Metrics) SetHTTPProxyMetric(metrics RouteMetric) {
 	for meta := range m.proxyMetricCache.Root {
 		m.proxyRootTotalGauge.DeleteLabelValues(meta.Namespace)
 	}
+	for meta := range m.proxyMetricCache.FQDNPolicyViolation {
+		m.proxyFQDNPolicyViolationGauge.DeleteLabelValues(meta.Namespace, meta.VHost)
+	}
 
 	m.proxyMetricCache = &RouteMetric{
 		Total:    metrics.Total,
@@ -237,6 +262,8 @@ func (m *Metrics) SetHTTPProxyMetric(metrics RouteMetric) {
 		Valid:    metrics.Valid,
 		Orphaned: metrics.Orphaned,
 		Root:     metrics.Root,
+
+		FQDNPolicyViolation: metrics.FQDNPolicyViolation,
 	}
 }
 
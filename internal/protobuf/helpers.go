@@ -40,6 +40,13 @@ func UInt32(val uint32) *wrappers.UInt32Value {
 	}
 }
 
+// UInt64 converts a uint64 to a pointer to a wrappers.UInt64Value.
+func UInt64(val uint64) *wrappers.UInt64Value {
+	return &wrappers.UInt64Value{
+		Value: val,
+	}
+}
+
 // Bool converts a bool to a pointer to a wrappers.BoolValue.
 func Bool(val bool) *wrappers.BoolValue {
 	return &wrappers.BoolValue{
@@ -21,6 +21,7 @@ import (
 	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
 	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	jwt "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/jwt_authn/v2alpha"
 	tcp "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
 	"github.com/golang/protobuf/proto"
 )
@@ -123,6 +124,16 @@ func (s routeSorter) Less(i, j int) bool {
 	return false
 }
 
+// Sorts JWT requirement rules in the same order as the routes
+// they match.
+type requirementRuleSorter []*jwt.RequirementRule
+
+func (s requirementRuleSorter) Len() int      { return len(s) }
+func (s requirementRuleSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s requirementRuleSorter) Less(i, j int) bool {
+	return routeSorter{{Match: s[i].Match}, {Match: s[j].Match}}.Less(0, 1)
+}
+
 // Sorts clusters by name.
 type clusterSorter []*v2.Cluster
 
@@ -213,6 +224,8 @@ func For(v interface{}) sort.Interface {
 		return virtualHostSorter(v)
 	case []*envoy_api_v2_route.Route:
 		return routeSorter(v)
+	case []*jwt.RequirementRule:
+		return requirementRuleSorter(v)
 	case []*envoy_api_v2_route.HeaderMatcher:
 		return headerMatcherSorter(v)
 	case []*v2.Cluster:
@@ -21,6 +21,7 @@ import (
 	envoy_api_v2_auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
 	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	envoy_api_v2_route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	jwt "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/jwt_authn/v2alpha"
 	tcp "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
 	"github.com/projectcontour/contour/internal/assert"
@@ -178,6 +179,39 @@ func TestSortRoutesLongestHeaders(t *testing.T) {
 	assert.Equal(t, have, want)
 }
 
+func TestSortRequirementRules(t *testing.T) {
+	want := []*jwt.RequirementRule{{
+		Match: &envoy_api_v2_route.RouteMatch{
+			PathSpecifier: matchRegex("."),
+		},
+	}, {
+		Match: &envoy_api_v2_route.RouteMatch{
+			PathSpecifier: matchPrefix("/path"),
+			Headers: []*envoy_api_v2_route.HeaderMatcher{
+				presentHeader("header-name"),
+			},
+		},
+	}, {
+		Match: &envoy_api_v2_route.RouteMatch{
+			PathSpecifier: matchPrefix("/path"),
+		},
+	}, {
+		Match: &envoy_api_v2_route.RouteMatch{
+			PathSpecifier: matchPrefix("/"),
+		},
+	}}
+
+	have := []*jwt.RequirementRule{
+		want[3],
+		want[2],
+		want[0],
+		want[1],
+	}
+
+	sort.Stable(For(have))
+	assert.Equal(t, have, want)
+}
+
 func TestSortSecrets(t *testing.T) {
 	want := []*envoy_api_v2_auth.Secret{
 		&envoy_api_v2_auth.Secret{Name: "first"},